@@ -18,19 +18,24 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/go-logr/logr"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/inftyai/manta/agent/pkg/bandwidth"
 	"github.com/inftyai/manta/agent/pkg/controller"
 	"github.com/inftyai/manta/agent/pkg/server"
 	"github.com/inftyai/manta/agent/pkg/task"
@@ -42,9 +47,47 @@ var (
 )
 
 func main() {
+	var hubDownloadLimit, peerDownloadLimit, peerUploadLimit string
+	var maxAttempts int
+
+	flag.StringVar(&hubDownloadLimit, "hub-download-limit", "0",
+		"The bandwidth limit in bytes per second of downloading from the model hubs, object storages "+
+			"and image registries, e.g. 100Mi, 0 means unlimited. Could be overridden by the nodeTracker.")
+	flag.StringVar(&peerDownloadLimit, "peer-download-limit", "0",
+		"The bandwidth limit in bytes per second of syncing chunks from the peers, 0 means unlimited.")
+	flag.StringVar(&peerUploadLimit, "peer-upload-limit", "0",
+		"The bandwidth limit in bytes per second of serving chunks to the peers, 0 means unlimited.")
+	flag.IntVar(&maxAttempts, "replication-max-attempts", 5,
+		"The maximum attempts of one Replication, it will be marked as Failed once reached and "+
+			"the chunk will be rescheduled to another node, 0 means unlimited. Replications reclaiming "+
+			"the chunks are retried without limit.")
+	flag.Parse()
+
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 	setupLog = ctrl.Log.WithName("Setup")
 
+	for _, item := range []struct {
+		name    string
+		value   string
+		limiter *bandwidth.Limiter
+	}{
+		{name: "hub-download-limit", value: hubDownloadLimit, limiter: bandwidth.HubDownload},
+		{name: "peer-download-limit", value: peerDownloadLimit, limiter: bandwidth.PeerDownload},
+		{name: "peer-upload-limit", value: peerUploadLimit, limiter: bandwidth.PeerUpload},
+	} {
+		quantity, err := resource.ParseQuantity(item.value)
+		if err != nil || quantity.Sign() < 0 {
+			setupLog.Error(err, "bandwidth limit must be a non-negative quantity", item.name, item.value)
+			os.Exit(1)
+		}
+		item.limiter.SetDefault(quantity.Value())
+	}
+
+	if maxAttempts < 0 {
+		setupLog.Error(nil, "replication max attempts must be non-negative", "replication-max-attempts", maxAttempts)
+		os.Exit(1)
+	}
+
 	cfg, err := config.GetConfig()
 	if err != nil {
 		setupLog.Error(err, "failed to get config")
@@ -55,10 +98,15 @@ func main() {
 
 	scheme := runtime.NewScheme()
 	_ = corev1.AddToScheme(scheme)
+	_ = authenticationv1.AddToScheme(scheme)
 	_ = api.AddToScheme(scheme)
 
 	mgr, err := manager.New(cfg, manager.Options{
 		Scheme: scheme,
+		// Secrets are read on demand only, no need to list and watch all of them.
+		Client: client.Options{
+			Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}}},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "failed to initialize the manager")
@@ -66,7 +114,7 @@ func main() {
 	}
 
 	if err := controller.NewReplicationReconciler(
-		mgr.GetClient(), mgr.GetScheme(),
+		mgr.GetClient(), mgr.GetScheme(), int32(maxAttempts),
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Model")
 		os.Exit(1)
@@ -81,6 +129,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	authenticator, err := server.NewAuthenticator(mgr.GetClient())
+	if err != nil {
+		setupLog.Error(err, "failed to initialize the authenticator")
+		os.Exit(1)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -95,7 +149,7 @@ func main() {
 	task.BackgroundTasks(ctx, mgr.GetClient())
 
 	// Run http server to receive sync requests.
-	go server.Run(ctx)
+	go server.Run(ctx, authenticator)
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
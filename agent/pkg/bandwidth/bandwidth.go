@@ -0,0 +1,188 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bandwidth
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// minBurst is the minimal burst of the limiter, it bounds the size of each read or write.
+	minBurst = 64 * 1024
+)
+
+var (
+	// HubDownload limits downloading from the model hubs, object storages and image registries.
+	HubDownload = NewLimiter()
+	// PeerDownload limits syncing chunks from the peers.
+	PeerDownload = NewLimiter()
+	// PeerUpload limits serving chunks to the peers.
+	PeerUpload = NewLimiter()
+)
+
+// Limiter is a token bucket limiting the bandwidth in bytes per second, it measures
+// the throughput at the same time.
+type Limiter struct {
+	limiter *rate.Limiter
+
+	mu sync.Mutex
+	// defaultLimit is set by the agent flags, it could be overridden by the nodeTracker.
+	defaultLimit int64
+	limit        int64
+	// bytes is the number of bytes transferred since lastSampleTime.
+	bytes          int64
+	lastSampleTime time.Time
+}
+
+// NewLimiter returns an unlimited Limiter.
+func NewLimiter() *Limiter {
+	return &Limiter{
+		limiter:        rate.NewLimiter(rate.Inf, minBurst),
+		lastSampleTime: time.Now(),
+	}
+}
+
+// SetDefault sets the default limit in bytes per second, 0 means unlimited.
+func (l *Limiter) SetDefault(limit int64) {
+	l.mu.Lock()
+	l.defaultLimit = limit
+	l.mu.Unlock()
+	l.Override(nil)
+}
+
+// Override overrides the default limit, nil means falling back to the default one.
+func (l *Limiter) Override(limit *int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	newLimit := l.defaultLimit
+	if limit != nil {
+		newLimit = *limit
+	}
+	if newLimit == l.limit {
+		return
+	}
+	l.limit = newLimit
+
+	if newLimit <= 0 {
+		l.limiter.SetLimit(rate.Inf)
+		l.limiter.SetBurst(minBurst)
+		return
+	}
+	l.limiter.SetLimit(rate.Limit(newLimit))
+	// Allows transferring up to one second of data at once.
+	l.limiter.SetBurst(int(max(newLimit, minBurst)))
+}
+
+// Limit returns the limit in effect, 0 means unlimited.
+func (l *Limiter) Limit() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Throughput returns the average throughput in bytes per second since the last call.
+func (l *Limiter) Throughput() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(l.lastSampleTime).Seconds()
+	bytes := l.bytes
+	l.bytes, l.lastSampleTime = 0, now
+
+	if elapsed <= 0 {
+		return 0
+	}
+	return int64(math.Round(float64(bytes) / elapsed))
+}
+
+// wait blocks until n bytes are allowed to transfer.
+func (l *Limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	l.bytes += int64(n)
+	l.mu.Unlock()
+
+	// The burst may be changed concurrently, wait in batches or WaitN will fail.
+	for n > 0 {
+		batch := l.burst(n)
+		if err := l.limiter.WaitN(ctx, batch); err != nil {
+			return err
+		}
+		n -= batch
+	}
+	return nil
+}
+
+// burst bounds the size of each read or write, or WaitN will fail.
+func (l *Limiter) burst(n int) int {
+	return min(n, l.limiter.Burst())
+}
+
+// Reader wraps the reader with the bandwidth limited.
+func (l *Limiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	return &reader{ctx: ctx, reader: r, limiter: l}
+}
+
+// Writer wraps the writer with the bandwidth limited.
+func (l *Limiter) Writer(ctx context.Context, w io.Writer) io.Writer {
+	return &writer{ctx: ctx, writer: w, limiter: l}
+}
+
+type reader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *Limiter
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p[:r.limiter.burst(len(p))])
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+type writer struct {
+	ctx     context.Context
+	writer  io.Writer
+	limiter *Limiter
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		n := w.limiter.burst(len(p) - written)
+		if err := w.limiter.wait(w.ctx, n); err != nil {
+			return written, err
+		}
+		n, err := w.writer.Write(p[written : written+n])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
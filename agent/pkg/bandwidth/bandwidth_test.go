@@ -0,0 +1,104 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bandwidth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/utils/ptr"
+)
+
+func TestLimiter(t *testing.T) {
+	content := strings.Repeat("m", 192*1024)
+
+	testCases := []struct {
+		name         string
+		defaultLimit int64
+		override     *int64
+		wantLimit    int64
+		// Limit the writer rather than the reader.
+		writer bool
+		// The first 128Ki is served by the burst.
+		wantMinDuration time.Duration
+	}{
+		{
+			name:      "unlimited",
+			wantLimit: 0,
+		},
+		{
+			name:            "limited by default",
+			defaultLimit:    128 * 1024,
+			wantLimit:       128 * 1024,
+			wantMinDuration: 400 * time.Millisecond,
+		},
+		{
+			name:            "overridden",
+			defaultLimit:    0,
+			override:        ptr.To[int64](128 * 1024),
+			wantLimit:       128 * 1024,
+			writer:          true,
+			wantMinDuration: 400 * time.Millisecond,
+		},
+		{
+			name:         "overridden as unlimited",
+			defaultLimit: 64 * 1024,
+			override:     ptr.To[int64](0),
+			wantLimit:    0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := NewLimiter()
+			limiter.SetDefault(tc.defaultLimit)
+			limiter.Override(tc.override)
+			if limiter.Limit() != tc.wantLimit {
+				t.Errorf("unexpected limit, want %d, got %d", tc.wantLimit, limiter.Limit())
+			}
+
+			start := time.Now()
+			var buf bytes.Buffer
+			var writer io.Writer = &buf
+			var reader io.Reader = strings.NewReader(content)
+			if tc.writer {
+				writer = limiter.Writer(context.Background(), writer)
+			} else {
+				reader = limiter.Reader(context.Background(), reader)
+			}
+			if _, err := io.Copy(writer, reader); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != content {
+				t.Errorf("unexpected content transferred")
+			}
+			if elapsed := time.Since(start); elapsed < tc.wantMinDuration {
+				t.Errorf("transferred too fast, want at least %v, got %v", tc.wantMinDuration, elapsed)
+			}
+			if throughput := limiter.Throughput(); throughput <= 0 {
+				t.Errorf("unexpected throughput: %d", throughput)
+			}
+			if throughput := limiter.Throughput(); throughput != 0 {
+				t.Errorf("throughput should be reset, got %d", throughput)
+			}
+		})
+	}
+}
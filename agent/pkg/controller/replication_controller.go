@@ -18,7 +18,11 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"os"
+	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
@@ -33,6 +37,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/inftyai/manta/agent/pkg/handler"
+	"github.com/inftyai/manta/agent/pkg/util"
+	cons "github.com/inftyai/manta/api"
 	api "github.com/inftyai/manta/api/v1alpha1"
 )
 
@@ -40,16 +46,31 @@ var (
 	NODE_NAME = os.Getenv("NODE_NAME")
 )
 
+const (
+	// The failed Replications are retried with exponential backoff, starting from the
+	// initialBackoff and doubling each time until the maxBackoff.
+	initialBackoff = 10 * time.Second
+	maxBackoff     = 5 * time.Minute
+
+	// heartbeatInterval is the interval to report the Replication is in progress,
+	// the progress is reported together.
+	heartbeatInterval = 30 * time.Second
+)
+
 // ReplicationReconciler reconciles a Replication object
 type ReplicationReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// maxAttempts represents the maximum attempts of one Replication, it will be marked as
+	// Failed once reached, then the chunk will be rescheduled to another node.
+	maxAttempts int32
 }
 
-func NewReplicationReconciler(client client.Client, scheme *runtime.Scheme) *ReplicationReconciler {
+func NewReplicationReconciler(client client.Client, scheme *runtime.Scheme, maxAttempts int32) *ReplicationReconciler {
 	return &ReplicationReconciler{
-		Client: client,
-		Scheme: scheme,
+		Client:      client,
+		Scheme:      scheme,
+		maxAttempts: maxAttempts,
 	}
 }
 
@@ -76,33 +97,67 @@ func (r *ReplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Filter out unrelated events.
 	if replication.Spec.NodeName != NODE_NAME ||
 		replicationReady(replication) ||
+		replicationFailed(replication) ||
 		// Waiting for the control plane set the Pending status.
 		len(replication.Status.Conditions) == 0 {
 		logger.V(10).Info("Skip replication", "Replication", klog.KObj(replication))
 		return ctrl.Result{}, nil
 	}
 
+	// Status updates of the last failure will trigger the reconciliation immediately, wait for the backoff.
+	if remaining := retryAfter(replication, time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
 	logger.Info("Reconcile replication", "Replication", klog.KObj(replication))
 
 	conditionType := api.ReplicateConditionType
 	if replication.Spec.Destination == nil {
 		conditionType = api.ReclaimingConditionType
 	}
+	// Go on replicating in the same reconciliation rather than requeueing, or the Replication
+	// may wait in the queue behind the long running ones without heartbeats, and be regarded
+	// as stalled by the control plane.
 	if conditionChanged := setReplicationCondition(replication, conditionType); conditionChanged {
-		return ctrl.Result{}, r.Status().Update(ctx, replication)
+		replication.Status.LastHeartbeatTime = ptr.To(metav1.Now())
+		if err := r.Status().Update(ctx, replication); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
+	// The status is patched rather than updated below, because the heartbeats change the resourceVersion.
+	base := replication.DeepCopy()
+	stopHeartbeat := r.heartbeat(ctx, replication)
+
 	// This may take a long time, the concurrency is controlled by the MaxConcurrentReconciles.
 	// TODO: should we create a Job to handle this? See discussion: https://github.com/InftyAI/Manta/issues/25
-	if err := handler.HandleReplication(ctx, r.Client, replication); err != nil {
+	err := handler.HandleReplication(ctx, r.Client, replication)
+	stopHeartbeat()
+
+	if err != nil {
 		logger.Error(err, "error to handle replication", "Replication", klog.KObj(replication))
-		return ctrl.Result{}, err
+
+		// The content is corrupted, no need to retry.
+		var integrityErr *util.IntegrityError
+		if errors.As(err, &integrityErr) {
+			setReplicationFailed(replication, integrityErr.Reason, integrityErr.Message)
+			return ctrl.Result{}, r.Status().Patch(ctx, replication, client.MergeFrom(base))
+		}
+
+		if recordFailure(replication, err, r.maxAttempts, time.Now()) {
+			logger.Info("replication reached the maximum attempts", "Replication", klog.KObj(replication), "attempts", replication.Status.FailedAttempts)
+			return ctrl.Result{}, r.Status().Patch(ctx, replication, client.MergeFrom(base))
+		}
+		if err := r.Status().Patch(ctx, replication, client.MergeFrom(base)); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: backoff(replication.Status.FailedAttempts)}, nil
 	} else {
 		if err := r.updateNodeTracker(ctx, replication); err != nil {
 			return ctrl.Result{}, err
 		}
 		if conditionChanged := setReplicationCondition(replication, api.ReadyConditionType); conditionChanged {
-			if err := r.Status().Update(ctx, replication); err != nil {
+			if err := r.Status().Patch(ctx, replication, client.MergeFrom(base)); err != nil {
 				return ctrl.Result{}, err
 			}
 		}
@@ -111,6 +166,69 @@ func (r *ReplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, nil
 }
 
+// heartbeat reports the Replication is still in progress periodically until stopped,
+// or the control plane will regard the Replication as stalled.
+func (r *ReplicationReconciler) heartbeat(ctx context.Context, replication *api.Replication) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	logger := log.FromContext(ctx)
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		lastBytes, lastTime := transferredBytes(ctx, replication), time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				status := map[string]interface{}{
+					"lastHeartbeatTime": metav1.NewTime(now),
+				}
+				if replication.Spec.Destination != nil {
+					bytes := transferredBytes(ctx, replication)
+					status["progress"] = calculateProgress(replication.Spec.SizeBytes, lastBytes, lastTime, bytes, now)
+					lastBytes, lastTime = bytes, now
+				}
+				patch, err := json.Marshal(map[string]interface{}{"status": status})
+				if err != nil {
+					logger.Error(err, "failed to marshal the heartbeat", "Replication", klog.KObj(replication))
+					continue
+				}
+				// Patch a copy, the replication is still in use by the reconciler.
+				if err := r.Status().Patch(ctx, replication.DeepCopy(), client.RawPatch(types.MergePatchType, patch)); err != nil && ctx.Err() == nil {
+					logger.Error(err, "failed to report the heartbeat", "Replication", klog.KObj(replication))
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+func transferredBytes(ctx context.Context, replication *api.Replication) int64 {
+	bytes, err := handler.TransferredBytes(replication)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to get the transferred bytes", "Replication", klog.KObj(replication))
+	}
+	return bytes
+}
+
+// calculateProgress calculates the throughput since the last report and the estimated completion time
+// at the throughput, the size is regarded as unknown once it's 0.
+func calculateProgress(size, lastBytes int64, lastTime time.Time, bytes int64, now time.Time) *api.ReplicationProgress {
+	progress := &api.ReplicationProgress{TransferredBytes: bytes}
+
+	// The bytes could be decreased once the partial chunk is discarded.
+	if elapsed := now.Sub(lastTime); elapsed > 0 && bytes > lastBytes {
+		progress.Throughput = int64(float64(bytes-lastBytes) / elapsed.Seconds())
+	}
+	if progress.Throughput > 0 && size > bytes {
+		remaining := time.Duration(float64(size-bytes) / float64(progress.Throughput) * float64(time.Second))
+		progress.EstimatedCompletionTime = ptr.To(metav1.NewTime(now.Add(remaining)).Rfc3339Copy())
+	}
+	return progress
+}
+
 func (r *ReplicationReconciler) updateNodeTracker(ctx context.Context, replication *api.Replication) error {
 	nodeTracker := &api.NodeTracker{}
 	if err := r.Get(ctx, types.NamespacedName{Name: replication.Spec.NodeName}, nodeTracker); err != nil {
@@ -136,14 +254,23 @@ func (r *ReplicationReconciler) updateNodeTracker(ctx context.Context, replicati
 			}
 		}
 		nodeTracker.Spec.Chunks = append(nodeTracker.Spec.Chunks, api.ChunkTracker{
-			ChunkName: chunkName,
-			SizeBytes: replication.Spec.SizeBytes,
+			ChunkName:      chunkName,
+			SizeBytes:      replication.Spec.SizeBytes,
+			Repo:           repoName(*replication.Spec.Destination.URI),
+			LastAccessTime: ptr.To(metav1.Now()),
 		})
 	}
 
 	return r.Client.Update(ctx, nodeTracker)
 }
 
+// repoName returns the repo folder of the uri, e.g. Qwen--Qwen2-0.5B-Instruct-GGUF of
+// localhost:///workspace/models/Qwen--Qwen2-0.5B-Instruct-GGUF/blobs/<chunk>.
+func repoName(uri string) string {
+	path := uri[strings.Index(uri, "://")+len("://"):]
+	return strings.Split(strings.TrimPrefix(path, cons.DefaultWorkspace), "/")[0]
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ReplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Pod{}, "spec.nodeName", func(rawObj client.Object) []string {
@@ -185,6 +312,73 @@ func setReplicationCondition(replication *api.Replication, conditionType string)
 	return false
 }
 
+// setReplicationFailed marks the Replication as Failed with the reason, the Replicating
+// condition will be set to false as well.
+func setReplicationFailed(replication *api.Replication, reason, message string) {
+	apimeta.SetStatusCondition(&replication.Status.Conditions, metav1.Condition{
+		Type:    api.ReplicateConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: "Replication failed",
+	})
+	apimeta.SetStatusCondition(&replication.Status.Conditions, metav1.Condition{
+		Type:    api.FailedConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	replication.Status.Phase = ptr.To[string](api.FailedConditionType)
+}
+
+// recordFailure records the failed attempt, the Replication will be marked as Failed once
+// reaching the maxAttempts, 0 means unlimited. Reclaim Replications are never marked as Failed,
+// because the files only live on this node, they can't be rescheduled to another node, and
+// the Torrent deletion and the node eviction are waiting for them.
+func recordFailure(replication *api.Replication, err error, maxAttempts int32, now time.Time) (failed bool) {
+	replication.Status.FailedAttempts += 1
+	replication.Status.LastFailureTime = ptr.To(metav1.NewTime(now))
+
+	conditionType := api.ReplicateConditionType
+	if replication.Spec.Destination == nil {
+		conditionType = api.ReclaimingConditionType
+	} else if maxAttempts > 0 && replication.Status.FailedAttempts >= maxAttempts {
+		setReplicationFailed(replication, "MaxAttemptsReached", err.Error())
+		return true
+	}
+
+	apimeta.SetStatusCondition(&replication.Status.Conditions, metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Retrying",
+		Message: err.Error(),
+	})
+	return false
+}
+
+// backoff returns the waiting time before the next attempt.
+func backoff(failedAttempts int32) time.Duration {
+	duration := initialBackoff
+	for i := int32(1); i < failedAttempts; i++ {
+		duration *= 2
+		if duration >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return duration
+}
+
+// retryAfter returns the remaining time of the backoff since the last failure.
+func retryAfter(replication *api.Replication, now time.Time) time.Duration {
+	if replication.Status.FailedAttempts == 0 || replication.Status.LastFailureTime == nil {
+		return 0
+	}
+	return replication.Status.LastFailureTime.Add(backoff(replication.Status.FailedAttempts)).Sub(now)
+}
+
+func replicationFailed(replication *api.Replication) bool {
+	return apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.FailedConditionType)
+}
+
 func replicationReady(replication *api.Replication) bool {
 	return apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.ReadyConditionType)
 }
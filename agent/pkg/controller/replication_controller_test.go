@@ -0,0 +1,198 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/test/util/wrapper"
+)
+
+func TestBackoff(t *testing.T) {
+	testCases := []struct {
+		failedAttempts int32
+		want           time.Duration
+	}{
+		{failedAttempts: 1, want: 10 * time.Second},
+		{failedAttempts: 2, want: 20 * time.Second},
+		{failedAttempts: 4, want: 80 * time.Second},
+		{failedAttempts: 6, want: maxBackoff},
+		{failedAttempts: 100, want: maxBackoff},
+	}
+
+	for _, tc := range testCases {
+		if got := backoff(tc.failedAttempts); got != tc.want {
+			t.Errorf("unexpected backoff of %d attempts, want %v, got %v", tc.failedAttempts, tc.want, got)
+		}
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	now := time.Now()
+	replication := wrapper.MakeReplication("replication").DestinationOfURI("localhost:///workspace/models/Qwen--Qwen2-7B-Instruct/snapshots/main/config.json").Obj()
+
+	for i := 1; i < 3; i++ {
+		if recordFailure(replication, errors.New("connection reset"), 3, now) {
+			t.Fatalf("replication should not be failed after %d attempts", i)
+		}
+		if !apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.ReplicateConditionType) {
+			t.Errorf("replication should be still replicating")
+		}
+	}
+
+	if remaining := retryAfter(replication, now); remaining != backoff(2) {
+		t.Errorf("unexpected remaining backoff: %v", remaining)
+	}
+	if remaining := retryAfter(replication, now.Add(backoff(2))); remaining > 0 {
+		t.Errorf("backoff should be expired, remaining: %v", remaining)
+	}
+
+	if !recordFailure(replication, errors.New("connection reset"), 3, now) {
+		t.Fatal("replication should be failed once reaching the maximum attempts")
+	}
+	if !replicationFailed(replication) || replication.Status.FailedAttempts != 3 {
+		t.Errorf("unexpected replication status: %v", replication.Status)
+	}
+
+	// Reclaim Replications keep retrying with the backoff.
+	reclaim := wrapper.MakeReplication("reclaim").SourceOfURI("localhost:///workspace/models/Qwen--Qwen2-7B-Instruct/snapshots/main/config.json").Obj()
+	for i := 1; i <= 5; i++ {
+		if recordFailure(reclaim, errors.New("device or resource busy"), 3, now) {
+			t.Fatalf("reclaim replication should not be failed after %d attempts", i)
+		}
+	}
+	if replicationFailed(reclaim) || !apimeta.IsStatusConditionTrue(reclaim.Status.Conditions, api.ReclaimingConditionType) {
+		t.Errorf("reclaim replication should be still reclaiming: %v", reclaim.Status)
+	}
+	if remaining := retryAfter(reclaim, now); remaining != backoff(5) {
+		t.Errorf("unexpected remaining backoff: %v", remaining)
+	}
+}
+
+func TestCalculateProgress(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	lastTime := now.Add(-10 * time.Second)
+
+	testCases := []struct {
+		name      string
+		size      int64
+		lastBytes int64
+		bytes     int64
+		want      *api.ReplicationProgress
+	}{
+		{
+			name:      "in progress",
+			size:      1000,
+			lastBytes: 100,
+			bytes:     300,
+			want: &api.ReplicationProgress{
+				TransferredBytes:        300,
+				Throughput:              20,
+				EstimatedCompletionTime: ptr.To(metav1.NewTime(now.Add(35 * time.Second))),
+			},
+		},
+		{
+			name:      "no bytes transferred",
+			size:      1000,
+			lastBytes: 300,
+			bytes:     300,
+			want:      &api.ReplicationProgress{TransferredBytes: 300},
+		},
+		{
+			name:      "partial chunk discarded",
+			size:      1000,
+			lastBytes: 300,
+			bytes:     0,
+			want:      &api.ReplicationProgress{TransferredBytes: 0},
+		},
+		{
+			name:      "unknown size",
+			lastBytes: 100,
+			bytes:     300,
+			want:      &api.ReplicationProgress{TransferredBytes: 300, Throughput: 20},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := calculateProgress(tc.size, tc.lastBytes, lastTime, tc.bytes, now)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected progress (-want +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestReconcileReplicatesRightAfterPending(t *testing.T) {
+	nodeName := NODE_NAME
+	NODE_NAME = "node1"
+	defer func() { NODE_NAME = nodeName }()
+
+	scheme := runtime.NewScheme()
+	if err := api.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "Apache License Version 2.0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "LICENSE", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+	t.Setenv("HF_ENDPOINT", server.URL)
+
+	replication := wrapper.MakeReplication("replication").NodeName("node1").ChunkName("chunk1--0001").
+		SizeBytes(int64(len(content))).
+		SourceOfHub("Huggingface", "Qwen/Qwen2-7B-Instruct", "main", "LICENSE").
+		DestinationOfURI("localhost://" + t.TempDir() + "/models/Qwen--Qwen2-7B-Instruct/snapshots/main/LICENSE").Obj()
+	replication.Status.Conditions = []metav1.Condition{{Type: api.PendingConditionType, Status: metav1.ConditionTrue, Reason: "Pending", LastTransitionTime: metav1.Now()}}
+	nodeTracker := &api.NodeTracker{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(replication, nodeTracker).WithStatusSubresource(replication).Build()
+	r := NewReplicationReconciler(c, scheme, 3)
+
+	// The replicating should not wait for another reconciliation once the condition is set.
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "replication"}}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	got := &api.Replication{}
+	if err := c.Get(context.Background(), types.NamespacedName{Name: "replication"}, got); err != nil {
+		t.Fatal(err)
+	}
+	if !replicationReady(got) {
+		t.Errorf("replication should be ready in one reconciliation, conditions: %v", got.Status.Conditions)
+	}
+	if got.Status.LastHeartbeatTime == nil {
+		t.Error("heartbeat should be reported once replicating")
+	}
+}
@@ -20,14 +20,38 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/inftyai/manta/agent/pkg/bandwidth"
+	"github.com/inftyai/manta/agent/pkg/util"
 	"github.com/inftyai/manta/api"
+	"github.com/inftyai/manta/api/v1alpha1"
 )
 
 const (
-	buffSize = 10 * 1024 * 1024 // 10MB buffer
+	// incompleteSuffix is appended to the blobs still under downloading or assembling.
+	incompleteSuffix = ".incomplete"
+)
+
+var (
+	// syncBackoff is the backoff of syncing chunks from peers, about 8 minutes in total.
+	syncBackoff = wait.Backoff{Duration: time.Second, Factor: 2, Jitter: 0.1, Steps: maxAttempts, Cap: 5 * time.Minute}
+
+	// workspace is the root of the served chunks, only the blobs under it are allowed
+	// to be synced to the peers.
+	workspace = api.DefaultWorkspace
+
+	// assembleLock avoids assembling the same object concurrently when the last
+	// two chunks are ready at the same time.
+	assembleLock sync.Mutex
 )
 
 // SendChunk will send the chunk content via http request.
@@ -39,7 +63,12 @@ func SendChunk(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	file, err := os.Open(path)
+	if err := validateChunkPath(path); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	reader, file, err := openChunk(path)
 	if err != nil {
 		http.Error(w, "File not found", http.StatusNotFound)
 		return
@@ -48,62 +77,257 @@ func SendChunk(w http.ResponseWriter, r *http.Request) {
 		_ = file.Close()
 	}()
 
-	buffer := make([]byte, buffSize)
-	for {
-		n, err := file.Read(buffer)
-		if err != nil {
-			if err == io.EOF {
-				break
-			} else {
-				fmt.Println("Error reading file")
-				http.Error(w, "Error reading file", http.StatusInternalServerError)
-				return
-			}
-		}
+	etag, err := chunkETag(file, filepath.Base(path))
+	if err != nil {
+		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		return
+	}
 
-		if n > 0 {
-			_, writeErr := w.Write(buffer[:n])
-			if writeErr != nil {
-				fmt.Println("Error writing to response:", writeErr)
-				http.Error(w, "Error writing to response", http.StatusInternalServerError)
-				return
-			}
+	// ServeContent handles the Range and If-Range headers, so peers can resume
+	// the partial chunks as long as the ETag is not changed.
+	w.Header().Set("ETag", etag)
+	http.ServeContent(&limitedResponseWriter{
+		ResponseWriter: w,
+		writer:         bandwidth.PeerUpload.Writer(r.Context(), w),
+	}, r, filepath.Base(path), time.Time{}, reader)
+}
+
+// limitedResponseWriter limits the bandwidth of the response body.
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	writer io.Writer
+}
+
+func (w *limitedResponseWriter) Write(p []byte) (int, error) {
+	return w.writer.Write(p)
+}
+
+// chunkETag identifies the content of the chunk, the modification time is not
+// involved because it's refreshed once accessed, see touch. Blobs are never modified
+// in place, so the inode changes once the blob is replaced.
+func chunkETag(file *os.File, chunkName string) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	var ino uint64
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		ino = stat.Ino
+	}
+	return fmt.Sprintf(`"%s-%x-%x"`, chunkName, ino, info.Size()), nil
+}
+
+// validateChunkPath makes sure the path refers to a blob under the workspace, which looks
+// like <workspace>/<repo>/blobs/<chunk>, paths with traversal or symlinks are rejected.
+func validateChunkPath(path string) error {
+	if path != filepath.Clean(path) || !strings.HasPrefix(path, workspace) {
+		return fmt.Errorf("path %s is not allowed", path)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(path, workspace), "/")
+	if len(parts) != 3 || parts[1] != "blobs" {
+		return fmt.Errorf("path %s is not a blob", path)
+	}
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("path %s is not allowed", path)
 		}
 	}
+
+	// The blob may not exist once assembled into the object, see openChunk.
+	if info, err := os.Lstat(path); err == nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("path %s is not a regular file", path)
+	}
+	return nil
 }
 
-func recvChunk(blobPath, snapshotPath, addr string) error {
-	url := fmt.Sprintf("http://%s:%s/sync?path=%s", addr, api.HttpPort, blobPath)
+// recvChunk receives the chunk from the peers, the chunk will be verified before committed.
+// Once there're several peers with the chunk size known, the chunk will be pulled from all
+// of them in parallel, otherwise, it's pulled from one peer and switched to another on failure.
+func recvChunk(blobPath, snapshotPath string, addrs []string, replication *v1alpha1.Replication) error {
+	endpoints := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		endpoints = append(endpoints, fmt.Sprintf("http://%s:%s/sync?path=%s", addr, api.HttpPort, url.QueryEscape(blobPath)))
+	}
+
+	// Use the same path for different peers, the partial chunk will be resumed
+	// once the chunk of the peer is not changed.
+	var lastErr error
+	attempts := 0
+	err := wait.ExponentialBackoff(syncBackoff, func() (bool, error) {
+		// The token is rotated by kubelet, read it for every attempt.
+		token, err := util.PeerToken()
+		if err != nil {
+			return false, fmt.Errorf("failed to read the peer token: %v", err)
+		}
+		authorization := "Bearer " + token
 
-	resp, err := http.Get(url)
+		if len(endpoints) > 1 && replication.Spec.SizeBytes > 0 {
+			lastErr = util.DownloadFileFromPeers(endpoints, blobPath+incompleteSuffix, replication.Spec.SizeBytes, authorization)
+		} else {
+			lastErr = util.DownloadFileWithETag(endpoints[attempts%len(endpoints)], blobPath+incompleteSuffix, authorization)
+		}
+		attempts += 1
+		return lastErr == nil, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("reach maximum sync attempts for %s, err: %v", blobPath, lastErr)
+	}
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
 
-	if err := os.MkdirAll(filepath.Dir(blobPath), os.ModePerm); err != nil {
+	if err := verifyChunk(blobPath+incompleteSuffix, replication); err != nil {
+		_ = os.Remove(blobPath + incompleteSuffix)
+		return err
+	}
+
+	if err := os.Rename(blobPath+incompleteSuffix, blobPath); err != nil {
 		return err
 	}
 
-	// Use the same path for different peers.
-	file, err := os.Create(blobPath)
+	return commitChunk(blobPath, snapshotPath, replication.Spec.ObjectDigest)
+}
+
+// openChunk opens the chunk of the path, once the chunk is already assembled into
+// the object, the byte range of the chunk in the object will be returned.
+func openChunk(path string) (io.ReadSeeker, *os.File, error) {
+	if file, err := os.Open(path); err == nil {
+		touch(path)
+		return file, file, nil
+	}
+
+	hash, _, _, err := api.ParseChunkName(filepath.Base(path))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	objectPath := filepath.Join(filepath.Dir(path), hash)
+	chunks, err := util.ReadManifest(objectPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var offset int64
+	for _, chunk := range chunks {
+		if chunk.ChunkName == filepath.Base(path) {
+			file, err := os.Open(objectPath)
+			if err != nil {
+				return nil, nil, err
+			}
+			touch(objectPath)
+			return io.NewSectionReader(file, offset, chunk.SizeBytes), file, nil
+		}
+		offset += chunk.SizeBytes
+	}
+	return nil, nil, fmt.Errorf("chunk %s not found in manifest", path)
+}
+
+// touch refreshes the modification time of the blob, which is regarded as the last
+// access time of the chunks when evicting, errors are ignored because it's not critical.
+// It only happens once served to peers, the reads of the local workloads are not tracked,
+// so the chunks only used locally look cold.
+func touch(path string) {
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+}
+
+// commitChunk makes the chunk visible in the snapshot. For object with only one chunk,
+// the snapshot links to the chunk directly. For object split into several chunks,
+// the chunks will be assembled into one blob named with the object hash once all
+// of them are ready, then the snapshot links to the assembled blob, otherwise, nothing
+// happens and the last committed chunk will finish the job. The assembled object will
+// be verified with the digest if not empty.
+func commitChunk(blobPath, targetPath, digest string) error {
+	hash, _, total, err := api.ParseChunkName(filepath.Base(blobPath))
+	if err != nil || total <= 1 {
+		return createSymlink(blobPath, targetPath)
+	}
+
+	assembleLock.Lock()
+	defer assembleLock.Unlock()
+
+	objectPath := filepath.Join(filepath.Dir(blobPath), hash)
+	if _, err := os.Stat(objectPath); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		chunkPaths := make([]string, 0, total)
+		for i := 0; i < total; i++ {
+			chunkPath := filepath.Join(filepath.Dir(blobPath), api.ChunkName(hash, i, total))
+			if _, err := os.Stat(chunkPath); err != nil {
+				if os.IsNotExist(err) {
+					// Wait for the rest chunks.
+					return nil
+				}
+				return err
+			}
+			chunkPaths = append(chunkPaths, chunkPath)
+		}
+
+		if err := assembleChunks(chunkPaths, objectPath, digest); err != nil {
+			return err
+		}
+	}
+
+	return createSymlink(objectPath, targetPath)
+}
+
+// assembleChunks concatenates the chunks in order into the object, records the chunks
+// in the manifest and removes the chunks at last.
+func assembleChunks(chunkPaths []string, objectPath string, digest string) error {
+	out, err := os.Create(objectPath + incompleteSuffix)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		_ = file.Close()
+		_ = out.Close()
 	}()
 
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
-		return err
+	chunks := make([]v1alpha1.ChunkTracker, 0, len(chunkPaths))
+	for _, chunkPath := range chunkPaths {
+		size, err := appendFile(out, chunkPath)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, v1alpha1.ChunkTracker{ChunkName: filepath.Base(chunkPath), SizeBytes: size})
+	}
+
+	if digest != "" {
+		if err := util.VerifyDigest(objectPath+incompleteSuffix, digest); err != nil {
+			// We couldn't tell which chunk is corrupted, discard them all.
+			_ = os.Remove(objectPath + incompleteSuffix)
+			for _, chunkPath := range chunkPaths {
+				_ = os.Remove(chunkPath)
+			}
+			return err
+		}
 	}
 
-	if err := createSymlink(blobPath, snapshotPath); err != nil {
+	if err := util.WriteManifest(objectPath, chunks); err != nil {
+		return err
+	}
+	if err := os.Rename(objectPath+incompleteSuffix, objectPath); err != nil {
 		return err
 	}
 
+	for _, chunkPath := range chunkPaths {
+		if err := os.Remove(chunkPath); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func appendFile(out io.Writer, path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	return io.Copy(out, file)
+}
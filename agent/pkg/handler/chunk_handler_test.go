@@ -0,0 +1,286 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/inftyai/manta/agent/pkg/util"
+	"github.com/inftyai/manta/api"
+)
+
+func Test_commitChunk(t *testing.T) {
+	repoPath := "../../../tmp/chunks/models/Qwen--Qwen2.5-72B-Instruct"
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/chunks")
+	}()
+
+	blobPath := repoPath + "/blobs/"
+	targetPath := repoPath + "/snapshots/main/model.safetensors"
+	if err := os.MkdirAll(blobPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := []string{"hello ", "manta"}
+	for i, content := range contents {
+		chunkPath := blobPath + api.ChunkName("hash", i, len(contents))
+		if err := os.WriteFile(chunkPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := commitChunk(chunkPath, targetPath, ""); err != nil {
+			t.Fatalf("failed to commit chunk: %v", err)
+		}
+
+		_, err := os.Lstat(targetPath)
+		if i < len(contents)-1 && err == nil {
+			t.Errorf("symlink should not exist before all chunks ready")
+		}
+		if i == len(contents)-1 && err != nil {
+			t.Errorf("symlink should exist once all chunks ready")
+		}
+	}
+
+	data, err := os.ReadFile(targetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello manta" {
+		t.Errorf("unexpected object content: %s", string(data))
+	}
+
+	for i := range contents {
+		if _, err := os.Stat(blobPath + api.ChunkName("hash", i, len(contents))); !os.IsNotExist(err) {
+			t.Errorf("chunk should be removed after assembled")
+		}
+	}
+
+	chunks, err := util.ReadManifest(blobPath + "hash")
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if len(chunks) != len(contents) {
+		t.Errorf("unexpected chunks in manifest: %v", chunks)
+	}
+
+	// The assembled chunk can still be served to peers.
+	reader, closer, err := openChunk(blobPath + api.ChunkName("hash", 1, len(contents)))
+	if err != nil {
+		t.Fatalf("failed to open chunk: %v", err)
+	}
+	defer func() {
+		_ = closer.Close()
+	}()
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "manta" {
+		t.Errorf("unexpected chunk content: %s", string(data))
+	}
+}
+
+func Test_commitChunkWithCorruptedObject(t *testing.T) {
+	repoPath := "../../../tmp/corrupted/models/Qwen--Qwen2.5-72B-Instruct"
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/corrupted")
+	}()
+
+	blobPath := repoPath + "/blobs/"
+	targetPath := repoPath + "/snapshots/main/model.safetensors"
+	if err := os.MkdirAll(blobPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The digest of "hello manta", but the second chunk is corrupted.
+	digest := "sha256:121564b6c4233a0c5418e6ac4af67c7b5d588e76ae74b5fabdfc6a39b4c4ad4b"
+	contents := []string{"hello ", "mantA"}
+	var err error
+	for i, content := range contents {
+		chunkPath := blobPath + api.ChunkName("hash", i, len(contents))
+		if err := os.WriteFile(chunkPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err = commitChunk(chunkPath, targetPath, digest)
+	}
+
+	var integrityErr *util.IntegrityError
+	if !errors.As(err, &integrityErr) || integrityErr.Reason != util.DigestMismatchReason {
+		t.Fatalf("expected digest mismatch error, got: %v", err)
+	}
+
+	paths := []string{targetPath, blobPath + "hash", blobPath + "hash" + incompleteSuffix, util.ManifestPath(blobPath + "hash")}
+	for i := range contents {
+		paths = append(paths, blobPath+api.ChunkName("hash", i, len(contents)))
+	}
+	for _, path := range paths {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should be discarded", path)
+		}
+	}
+}
+
+func TestSendChunk(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/send")
+	}()
+
+	oldWorkspace := workspace
+	workspace = "../../../tmp/send/models/"
+	defer func() {
+		workspace = oldWorkspace
+	}()
+
+	if err := util.MockRepo(workspace, "model", "main", []string{"file"}, []string{"blob--0001"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("../../../tmp/send/secret", []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../secret", workspace+"model/blobs/link--0001"); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{
+			name:       "blob under the workspace",
+			path:       workspace + "model/blobs/blob--0001",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "empty path",
+			path:       "",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "path outside the workspace",
+			path:       "/etc/passwd",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "path with traversal",
+			path:       workspace + "model/blobs/../../../secret",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "snapshot rather than blob",
+			path:       workspace + "model/snapshots/main/file",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "symlink blob",
+			path:       workspace + "model/blobs/link--0001",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "blob not found",
+			path:       workspace + "model/blobs/unknown--0001",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sync?path="+url.QueryEscape(tc.path), nil)
+			recorder := httptest.NewRecorder()
+			SendChunk(recorder, req)
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("unexpected status code, want %d, got %d", tc.wantStatus, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestSendChunkWithRange(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/range")
+	}()
+
+	oldWorkspace := workspace
+	workspace = "../../../tmp/range/models/"
+	defer func() {
+		workspace = oldWorkspace
+	}()
+
+	blobPath := workspace + "model/blobs/blob--0001"
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(blobPath, []byte("hello manta"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/sync?path="+url.QueryEscape(blobPath), nil)
+	recorder := httptest.NewRecorder()
+	SendChunk(recorder, req)
+	etag := recorder.Header().Get("ETag")
+	if recorder.Code != http.StatusOK || etag == "" {
+		t.Fatalf("unexpected response, status: %d, etag: %s", recorder.Code, etag)
+	}
+
+	testCases := []struct {
+		name        string
+		ifRange     string
+		wantStatus  int
+		wantContent string
+	}{
+		{
+			name:        "etag matched",
+			ifRange:     etag,
+			wantStatus:  http.StatusPartialContent,
+			wantContent: "manta",
+		},
+		{
+			name:        "etag changed",
+			ifRange:     `"changed"`,
+			wantStatus:  http.StatusOK,
+			wantContent: "hello manta",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sync?path="+url.QueryEscape(blobPath), nil)
+			req.Header.Set("Range", "bytes=6-")
+			req.Header.Set("If-Range", tc.ifRange)
+			recorder := httptest.NewRecorder()
+			SendChunk(recorder, req)
+
+			if recorder.Code != tc.wantStatus {
+				t.Errorf("unexpected status code, want %d, got %d", tc.wantStatus, recorder.Code)
+			}
+			if recorder.Body.String() != tc.wantContent {
+				t.Errorf("unexpected content: %s", recorder.Body.String())
+			}
+			if recorder.Header().Get("Content-Length") != strconv.Itoa(len(tc.wantContent)) {
+				t.Errorf("unexpected content length: %s", recorder.Header().Get("Content-Length"))
+			}
+		})
+	}
+}
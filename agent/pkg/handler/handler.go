@@ -30,7 +30,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/inftyai/manta/agent/pkg/util"
+	cons "github.com/inftyai/manta/api"
 	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/hub"
+	"github.com/inftyai/manta/pkg/objectstore"
+	"github.com/inftyai/manta/pkg/registry"
+	pkgutil "github.com/inftyai/manta/pkg/util"
 )
 
 // This only happens when replication not ready.
@@ -41,61 +47,210 @@ func HandleReplication(ctx context.Context, client client.Client, replication *a
 	}
 
 	if replication.Spec.Source.Hub != nil {
-		return downloadChunk(ctx, replication)
+		return downloadChunk(ctx, client, replication)
 	}
 
 	if replication.Spec.Source.URI != nil {
+		if objectstore.IsObjectStoreURI(*replication.Spec.Source.URI) || registry.IsImageURI(*replication.Spec.Source.URI) {
+			return downloadChunk(ctx, client, replication)
+		}
+
 		host, _ := parseURI(*replication.Spec.Source.URI)
 		// TODO: handel other uris.
 		if host != api.URI_LOCALHOST {
 			return syncChunk(ctx, client, replication)
 		}
-		// TODO: handle uri with object store.
 	}
 	return nil
 }
 
-func downloadChunk(ctx context.Context, replication *api.Replication) error {
-	logger := log.FromContext(ctx)
+// TransferredBytes returns the bytes of the chunk transferred to the node so far, the partial
+// downloads of the previous attempts are counted as well, because they'll be resumed.
+func TransferredBytes(replication *api.Replication) (int64, error) {
+	blobPath := localBlobPath(replication)
+	if blobPath == "" {
+		return 0, nil
+	}
+	if _, err := os.Stat(blobPath); err == nil {
+		return replication.Spec.SizeBytes, nil
+	}
+	return util.DownloadedBytes(blobPath + incompleteSuffix)
+}
+
+// localBlobPath returns the blob path of the chunk in the node, empty means no chunk will be
+// transferred, e.g. deleting the chunk.
+func localBlobPath(replication *api.Replication) string {
+	if replication.Spec.Destination == nil {
+		return ""
+	}
+	if replication.Spec.Source.Hub != nil {
+		blobPath, _ := chunkPaths(replication)
+		return blobPath
+	}
+	if replication.Spec.Source.URI == nil {
+		return ""
+	}
+	if objectstore.IsObjectStoreURI(*replication.Spec.Source.URI) || registry.IsImageURI(*replication.Spec.Source.URI) {
+		blobPath, _ := chunkPaths(replication)
+		return blobPath
+	}
+	// The chunk is synced to the same path of the peer, like remote://node@<path-to-your-file>.
+	_, address := parseURI(*replication.Spec.Source.URI)
+	if _, blobPath, found := strings.Cut(address, "@"); found {
+		return blobPath
+	}
+	return ""
+}
+
+// chunkPaths returns the blob path and the snapshot path of the chunk downloaded from the
+// model hub, the object storage or the image registry. The destination refers to the snapshot
+// path, except the hub Replications created by the older versions, which refer to the blob path
+// with the snapshot named after the revision.
+func chunkPaths(replication *api.Replication) (blobPath, targetPath string) {
+	_, destPath := parseURI(*replication.Spec.Destination.URI)
+	if replication.Spec.Source.Hub != nil && strings.Contains(destPath, "/blobs/") {
+		blobPath = destPath
+		targetPath = strings.Split(blobPath, "/blobs/")[0] + "/snapshots/" + *replication.Spec.Source.Hub.Revision + "/" + *replication.Spec.Source.Hub.Filename
+		return blobPath, targetPath
+	}
+	targetPath = destPath
+	blobPath = strings.Split(targetPath, "/snapshots/")[0] + "/blobs/" + replication.Spec.ChunkName
+	return blobPath, targetPath
+}
+
+// committed returns whether the snapshot links to the chunk, or the object assembled from the chunk.
+func committed(targetPath, chunkName string) bool {
+	realPath, err := filepath.EvalSymlinks(targetPath)
+	if err != nil {
+		return false
+	}
+	name := filepath.Base(realPath)
+	if name == chunkName {
+		return true
+	}
+	hash, _, total, err := cons.ParseChunkName(chunkName)
+	return err == nil && total > 1 && name == hash
+}
 
-	var blobPath, revision, filename, targetPath string
+// downloadChunk downloads the chunk from the model hub, the object storage or the image registry.
+func downloadChunk(ctx context.Context, c client.Client, replication *api.Replication) error {
+	logger := log.FromContext(ctx)
 
-	// If hub != nil, it must be download to the localhost.
+	var filename string
 	if replication.Spec.Source.Hub != nil {
-		_, blobPath = parseURI(*replication.Spec.Destination.URI)
-		revision = *replication.Spec.Source.Hub.Revision
 		filename = *replication.Spec.Source.Hub.Filename
-		splits := strings.Split(blobPath, "/blobs/")
-		targetPath = splits[0] + "/snapshots/" + revision + "/" + filename
+	} else {
+		filename = *replication.Spec.Source.URI
+	}
+	blobPath, targetPath := chunkPaths(replication)
 
-		// symlink exists means already downloaded.
-		if _, err := os.Stat(targetPath); err == nil {
-			logger.Info("file already downloaded", "file", filename)
-			return nil
+	// The snapshot may link to the chunk of the former commit, which is regarded as
+	// downloaded only when linked to the same chunk.
+	if committed(targetPath, filepath.Base(blobPath)) {
+		logger.Info("file already downloaded", "file", filename)
+		return nil
+	}
+
+	// The chunk is downloaded but the other chunks of the object are not ready yet.
+	if _, err := os.Stat(blobPath); err == nil {
+		logger.Info("chunk already downloaded", "chunk", replication.Spec.ChunkName)
+		return commitChunk(blobPath, targetPath, replication.Spec.ObjectDigest)
+	}
+
+	// Only download the byte range of the chunk once the object is split into several chunks.
+	var offset, length int64
+	if _, _, total, err := cons.ParseChunkName(filepath.Base(blobPath)); err == nil && total > 1 {
+		offset, length = replication.Spec.OffsetBytes, replication.Spec.SizeBytes
+	}
+
+	// Download to a temporary file first to avoid half-downloaded chunks being regarded as ready.
+	if replication.Spec.Source.Hub != nil {
+		credentials, err := pkgutil.SecretData(ctx, c, replication.Spec.Source.Hub.TokenSecretRef)
+		if err != nil {
+			return err
+		}
+
+		modelHub, err := hub.NewHub(*replication.Spec.Source.Hub.Name, credentials)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Start to download file from model hub", "hub", modelHub.Name(), "file", filename, "chunk", replication.Spec.ChunkName)
+		if err := downloadFromHub(modelHub, replication.Spec.Source.Hub.RepoID, *replication.Spec.Source.Hub.Revision, filename, blobPath+incompleteSuffix, offset, length); err != nil {
+			return err
 		}
+	} else {
+		credentials, err := pkgutil.SecretData(ctx, c, replication.Spec.Source.CredentialSecretRef)
+		if err != nil {
+			return err
+		}
+
+		if registry.IsImageURI(*replication.Spec.Source.URI) {
+			reg, err := registry.NewRegistry(*replication.Spec.Source.URI, credentials)
+			if err != nil {
+				return err
+			}
+
+			logger.Info("Start to download blob from image registry", "file", filename, "chunk", replication.Spec.ChunkName)
+			if err := downloadFromRegistry(reg, reg.Reference().Digest, blobPath+incompleteSuffix); err != nil {
+				return err
+			}
+			// The layer is addressed by the digest, verify with it anyway.
+			if replication.Spec.ObjectDigest == "" {
+				replication.Spec.ObjectDigest = reg.Reference().Digest
+			}
+		} else {
+			store, err := objectstore.NewObjectStore(*replication.Spec.Source.URI, credentials)
+			if err != nil {
+				return err
+			}
 
-		if *replication.Spec.Source.Hub.Name == api.HUGGINGFACE_MODEL_HUB {
-			logger.Info("Start to download file from Huggingface Hub", "file", filename)
-			if err := downloadFromHF(replication.Spec.Source.Hub.RepoID, revision, filename, blobPath); err != nil {
+			logger.Info("Start to download file from object storage", "file", filename, "chunk", replication.Spec.ChunkName)
+			if err := downloadFromObjectStore(store, blobPath+incompleteSuffix, offset, length); err != nil {
 				return err
 			}
-			// TODO: handle modelScope
 		}
-		// TODO: Handle address
+	}
+
+	if err := verifyChunk(blobPath+incompleteSuffix, replication); err != nil {
+		// The content is broken, download again from scratch next time.
+		_ = os.Remove(blobPath + incompleteSuffix)
+		return err
+	}
+
+	if err := os.Rename(blobPath+incompleteSuffix, blobPath); err != nil {
+		return err
 	}
 
 	// symlink can help to validate the file is downloaded successfully.
-	// TODO: once we support split a file to several chunks, the targetPath should be
-	// changed here, such as targetPath-0001.
-	if err := createSymlink(blobPath, targetPath); err != nil {
-		logger.Error(err, "failed to create symlink")
+	if err := commitChunk(blobPath, targetPath, replication.Spec.ObjectDigest); err != nil {
+		logger.Error(err, "failed to commit chunk")
 		return err
 	}
 
-	logger.Info("download chunk successfully", "file", filename)
+	logger.Info("download chunk successfully", "file", filename, "chunk", replication.Spec.ChunkName)
 	return nil
 }
 
+// verifyChunk verifies the chunk size, and the digest once the chunk is the whole object,
+// chunks of the object split into several chunks will be verified once assembled.
+// The size is regarded as unknown once it's 0.
+func verifyChunk(path string, replication *api.Replication) error {
+	if replication.Spec.SizeBytes > 0 {
+		if err := util.VerifySize(path, replication.Spec.SizeBytes); err != nil {
+			return err
+		}
+	}
+
+	if _, _, total, err := cons.ParseChunkName(replication.Spec.ChunkName); err == nil && total > 1 {
+		return nil
+	}
+	if replication.Spec.ObjectDigest == "" {
+		return nil
+	}
+	return util.VerifyDigest(path, replication.Spec.ObjectDigest)
+}
+
 func syncChunk(ctx context.Context, client client.Client, replication *api.Replication) error {
 	logger := log.FromContext(ctx)
 
@@ -105,15 +260,25 @@ func syncChunk(ctx context.Context, client client.Client, replication *api.Repli
 	sourceSplits := strings.Split(*replication.Spec.Source.URI, "://")
 	addresses := strings.Split(sourceSplits[1], "@")
 	nodeName, blobPath := addresses[0], addresses[1]
-	addr, err := peerAddr(ctx, client, nodeName)
-	if err != nil {
-		return err
+
+	// The chunk will be pulled from all the available peers.
+	var addrs []string
+	for _, name := range append([]string{nodeName}, replication.Spec.Source.PeerNodeNames...) {
+		addr, err := peerAddr(ctx, client, name)
+		if err != nil {
+			logger.Error(err, "failed to get the peer address", "node", name)
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("no peer available for chunk %s", replication.Spec.ChunkName)
 	}
 
 	// The destination URI looks like localhost://<path-to-your-file>
 	destSplits := strings.Split(*replication.Spec.Destination.URI, "://")
 
-	if err := recvChunk(blobPath, destSplits[1], addr); err != nil {
+	if err := recvChunk(blobPath, destSplits[1], addrs, replication); err != nil {
 		logger.Error(err, "failed to sync chunk")
 		return err
 	}
@@ -126,15 +291,85 @@ func deleteChunk(ctx context.Context, replication *api.Replication) error {
 	logger := log.FromContext(ctx)
 	logger.Info("try to delete chunk", "Replication", replication.Name, "chunk", replication.Spec.ChunkName)
 	splits := strings.Split(*replication.Spec.Source.URI, "://")
+
+	// The source refers to the blob once the chunk is evicted.
+	if strings.Contains(splits[1], "/blobs/") {
+		if err := evictChunk(splits[1]); err != nil {
+			logger.Error(err, "failed to evict chunk", "Replication", klog.KObj(replication), "chunk", replication.Spec.ChunkName)
+			return err
+		}
+		return nil
+	}
+
 	if err := deleteSymlinkAndTarget(splits[1]); err != nil {
 		logger.Error(err, "failed to delete chunk", "Replication", klog.KObj(replication), "chunk", replication.Spec.ChunkName)
 	}
+
+	// The chunk may not be assembled into the object yet, remove the chunk blob as well.
+	repoPath := strings.Split(splits[1], "/snapshots/")[0]
+	blobPath := repoPath + "/blobs/" + replication.Spec.ChunkName
+	for _, path := range []string{blobPath, blobPath + incompleteSuffix, util.ETagPath(blobPath + incompleteSuffix), util.PiecesPath(blobPath + incompleteSuffix)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Error(err, "failed to delete chunk blob", "Replication", klog.KObj(replication), "path", path)
+		}
+	}
+	return nil
+}
+
+// evictChunk removes the chunk blob, the object assembled from the chunk and all the
+// snapshot files linking to them, because one blob could be referenced by several revisions.
+func evictChunk(blobPath string) error {
+	repoPath := strings.Split(blobPath, "/blobs/")[0]
+
+	blobPaths := []string{blobPath}
+	toRemove := []string{blobPath, blobPath + incompleteSuffix, util.ETagPath(blobPath + incompleteSuffix), util.PiecesPath(blobPath + incompleteSuffix)}
+	if hash, _, total, err := cons.ParseChunkName(filepath.Base(blobPath)); err == nil && total > 1 {
+		objectPath := filepath.Join(filepath.Dir(blobPath), hash)
+		blobPaths = append(blobPaths, objectPath)
+		toRemove = append(toRemove, objectPath, util.ManifestPath(objectPath))
+	}
+
+	err := filepath.WalkDir(repoPath+"/snapshots/", func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type()&os.ModeSymlink == 0 {
+			return nil
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		for _, p := range blobPaths {
+			if filepath.Clean(target) == filepath.Clean(p) {
+				return os.Remove(path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range toRemove {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return nil
 }
 
 // local(real) file looks like: /workspace/models/Qwen--Qwen2-0.5B-Instruct-GGUF/blobs/8b08b8632419bd6d7369362945b5976c7f47b1c1--0001
 // target file looks like /workspace/models/Qwen--Qwen2-0.5B-Instruct-GGUF/snapshots/main/qwen2-0_5b-instruct-q5_k_m.gguf
-// the symlink of target file looks like ../../blobs/8b08b8632419bd6d7369362945b5976c7f47b1c1--0001
+// the symlink of target file looks like ../../blobs/8b08b8632419bd6d7369362945b5976c7f47b1c1--0001,
+// for files in the subfolders, e.g. snapshots/main/onnx/model.onnx, it looks like ../../../blobs/<chunk>.
 func createSymlink(localPath, targetPath string) error {
 	// This could happen like force delete a Torrent but downloading is still on the way,
 	// then the blob file is deleted, in this situation, we should not create the symlink.
@@ -159,12 +394,15 @@ func createSymlink(localPath, targetPath string) error {
 	if len(splits) != 2 {
 		return fmt.Errorf("unexpected localPath: %s", localPath)
 	}
+	targetSplits := strings.Split(targetPath, "/snapshots/")
+	if len(targetSplits) != 2 {
+		return fmt.Errorf("unexpected targetPath: %s", targetPath)
+	}
 
-	// TODO: once we support file-chunks, we may need to refactor the file name,
-	// like merges.txt.chunks--0002, merges.txt.chunks--0102.
 	// Use relative link to avoid the host folder is different with the container folder,
-	// one is /mnt/models, another is /workspace/models.
-	sourcePath := "../.." + "/blobs/" + splits[1]
+	// one is /mnt/models, another is /workspace/models. The link climbs up from the
+	// revision and the subfolders of the file to the repo folder.
+	sourcePath := strings.Repeat("../", strings.Count(targetSplits[1], "/")+1) + "blobs/" + splits[1]
 	return os.Symlink(sourcePath, targetPath)
 }
 
@@ -182,6 +420,9 @@ func deleteSymlinkAndTarget(symlinkPath string) error {
 		if err := os.Remove(targetPath); err != nil {
 			return fmt.Errorf("failed to remove target file: %v", err)
 		}
+		if err := os.Remove(util.ManifestPath(targetPath)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove manifest file: %v", err)
+		}
 		fmt.Printf("Target file %s removed.\n", targetPath)
 	} else if os.IsNotExist(err) {
 		fmt.Printf("Target file %s does not exist.\n", targetPath)
@@ -18,9 +18,24 @@ package handler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/inftyai/manta/agent/pkg/util"
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/hub"
+	"github.com/inftyai/manta/pkg/objectstore"
+	"github.com/inftyai/manta/pkg/registry"
 	"github.com/inftyai/manta/test/util/wrapper"
 )
 
@@ -72,3 +87,405 @@ func TestHandleReplication(t *testing.T) {
 		t.Errorf("file should not exists")
 	}
 }
+
+func TestHandleReplicationFromObjectStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/objectstore")
+	}()
+
+	// A minimal S3-compatible server with range support.
+	content := "Apache License Version 2.0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/qwen/LICENSE" || r.URL.Query().Get("X-Amz-Signature") == "" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, "LICENSE", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "s3-credentials"},
+		Data: map[string][]byte{
+			objectstore.AccessKeyIDKey:     []byte("access-key"),
+			objectstore.SecretAccessKeyKey: []byte("secret-key"),
+			objectstore.EndpointKey:        []byte(server.URL),
+		},
+	}
+	client := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	replication := wrapper.MakeReplication("replication").
+		ChunkName("9b2cf535f27731c974343645a3985328--0001").
+		SizeBytes(int64(len(content))).
+		SourceOfURI("s3://models/qwen/LICENSE").
+		DestinationOfURI("localhost://../../../tmp/objectstore/models/s3--models--qwen/snapshots/main/LICENSE").
+		Obj()
+	replication.Spec.Source.CredentialSecretRef = &corev1.SecretReference{Namespace: "default", Name: "s3-credentials"}
+
+	if err := HandleReplication(ctx, client, replication); err != nil {
+		t.Fatalf("failed to handle Replication: %v", err)
+	}
+
+	data, err := os.ReadFile("../../../tmp/objectstore/models/s3--models--qwen/snapshots/main/LICENSE")
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(data) != content {
+		t.Errorf("unexpected file content: %s", string(data))
+	}
+
+	if _, err := os.Stat("../../../tmp/objectstore/models/s3--models--qwen/blobs/9b2cf535f27731c974343645a3985328--0001"); err != nil {
+		t.Errorf("blob should exist: %v", err)
+	}
+}
+
+func TestHandleReplicationFromHub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/hub")
+	}()
+
+	// The revision main is moved from commit1 to commit2, only commit2 is served.
+	content := "Apache License Version 2.0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Qwen/Qwen2-7B-Instruct/resolve/commit2/LICENSE" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, "LICENSE", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+	t.Setenv("HF_ENDPOINT", server.URL)
+
+	rootPath := "../../../tmp/hub/models/"
+	// The snapshot links to the chunk of commit1.
+	if err := util.MockRepo(rootPath, "Qwen--Qwen2-7B-Instruct", "main", []string{"LICENSE"}, []string{"chunk1--0001"}); err != nil {
+		t.Fatal(err)
+	}
+
+	replication := wrapper.MakeReplication("replication").
+		ChunkName("chunk2--0001").
+		SizeBytes(int64(len(content))).
+		SourceOfHub("Huggingface", "Qwen/Qwen2-7B-Instruct", "commit2", "LICENSE").
+		DestinationOfURI("localhost://" + rootPath + "Qwen--Qwen2-7B-Instruct/snapshots/main/LICENSE").
+		Obj()
+	if err := HandleReplication(ctx, nil, replication); err != nil {
+		t.Fatalf("failed to handle Replication: %v", err)
+	}
+
+	targetPath := rootPath + "Qwen--Qwen2-7B-Instruct/snapshots/main/LICENSE"
+	data, err := os.ReadFile(targetPath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(data) != content {
+		t.Errorf("unexpected file content: %s", string(data))
+	}
+	if !committed(targetPath, "chunk2--0001") {
+		t.Errorf("snapshot should link to the chunk of commit2")
+	}
+}
+
+func TestHandleReplicationFromPrivateHub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/private-hub")
+	}()
+
+	// Only the token in the Secret is accepted, not the one of the agent envs.
+	content := "Apache License Version 2.0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.ServeContent(w, r, "LICENSE", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+	t.Setenv("HF_ENDPOINT", server.URL)
+	t.Setenv("HF_TOKEN", "env-token")
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hf-token"},
+		Data:       map[string][]byte{hub.TokenKey: []byte("secret-token")},
+	}
+	client := fake.NewClientBuilder().WithObjects(secret).Build()
+
+	targetPath := "../../../tmp/private-hub/models/Qwen--Qwen2-7B-Instruct/snapshots/main/LICENSE"
+	newReplication := func() *api.Replication {
+		return wrapper.MakeReplication("replication").
+			ChunkName("chunk1--0001").
+			SizeBytes(int64(len(content))).
+			SourceOfHub("Huggingface", "Qwen/Qwen2-7B-Instruct", "commit1", "LICENSE").
+			DestinationOfURI("localhost://" + targetPath).
+			Obj()
+	}
+
+	if err := HandleReplication(ctx, client, newReplication()); err == nil {
+		t.Fatal("expected error without the token in the Secret")
+	}
+
+	replication := newReplication()
+	replication.Spec.Source.Hub.TokenSecretRef = &corev1.SecretReference{Namespace: "default", Name: "hf-token"}
+	if err := HandleReplication(ctx, client, replication); err != nil {
+		t.Fatalf("failed to handle Replication: %v", err)
+	}
+
+	data, err := os.ReadFile(targetPath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(data) != content {
+		t.Errorf("unexpected file content: %s", string(data))
+	}
+}
+
+func TestHandleReplicationFromRegistry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A minimal anonymous registry serving one blob.
+	content := "Apache License Version 2.0"
+	hash := sha256.Sum256([]byte(content))
+	digest := "sha256:" + hex.EncodeToString(hash[:])
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/models/qwen2/blobs/" + digest:
+			http.ServeContent(w, r, "blob", time.Now(), strings.NewReader(content))
+		case "/v2/models/qwen2/blobs/sha256:broken":
+			http.ServeContent(w, r, "blob", time.Now(), strings.NewReader(content))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	testCases := []struct {
+		name      string
+		digest    string
+		wantError bool
+	}{
+		{
+			name:   "digest matched",
+			digest: digest,
+		},
+		{
+			name:      "digest mismatched",
+			digest:    "sha256:broken",
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				_ = os.RemoveAll("../../../tmp/registry")
+			}()
+
+			chunkName := registry.ChunkHash(tc.digest) + "--0001"
+			targetPath := "../../../tmp/registry/models/img--models--qwen2/snapshots/v1/LICENSE"
+			blobPath := "../../../tmp/registry/models/img--models--qwen2/blobs/" + chunkName
+
+			replication := wrapper.MakeReplication("replication").
+				ChunkName(chunkName).
+				SizeBytes(int64(len(content))).
+				SourceOfURI("img://" + host + "/models/qwen2@" + tc.digest).
+				DestinationOfURI("localhost://" + targetPath).
+				Obj()
+
+			err := HandleReplication(ctx, nil, replication)
+			if tc.wantError {
+				if err == nil {
+					t.Error("expected error here")
+				}
+				for _, path := range []string{targetPath, blobPath, blobPath + incompleteSuffix} {
+					if _, err := os.Lstat(path); !os.IsNotExist(err) {
+						t.Errorf("file %s should not exist", path)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to handle Replication: %v", err)
+			}
+			data, err := os.ReadFile(targetPath)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if string(data) != content {
+				t.Errorf("unexpected file content: %s", string(data))
+			}
+		})
+	}
+}
+
+func TestHandleEvictionReplication(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rootPath := "../../../tmp/eviction/models/"
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/eviction")
+	}()
+
+	// The same blob is referenced by two revisions.
+	if err := util.MockRepo(rootPath, "model", "main", []string{"file1", "file2"}, []string{"blob1--0001", "blob2--0001"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := util.MockRepo(rootPath, "model", "v1", []string{"file1"}, []string{"blob1--0001"}); err != nil {
+		t.Fatal(err)
+	}
+
+	replication := wrapper.MakeReplication("replication").
+		ChunkName("blob1--0001").
+		SourceOfURI("localhost://" + rootPath + "model/blobs/blob1--0001").
+		Obj()
+	if err := HandleReplication(ctx, nil, replication); err != nil {
+		t.Fatalf("failed to handle Replication: %v", err)
+	}
+
+	for _, path := range []string{"model/blobs/blob1--0001", "model/snapshots/main/file1", "model/snapshots/v1/file1"} {
+		if _, err := os.Lstat(rootPath + path); !os.IsNotExist(err) {
+			t.Errorf("%s should not exist", path)
+		}
+	}
+	for _, path := range []string{"model/blobs/blob2--0001", "model/snapshots/main/file2"} {
+		if _, err := os.Stat(rootPath + path); err != nil {
+			t.Errorf("%s should exist: %v", path, err)
+		}
+	}
+}
+
+func TestTransferredBytes(t *testing.T) {
+	rootPath := "../../../tmp/transferred/models/"
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/transferred")
+	}()
+
+	if err := os.MkdirAll(rootPath+"model/blobs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rootPath+"model/blobs/chunk1--0001", []byte("hello manta"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rootPath+"model/blobs/chunk2--0001"+incompleteSuffix, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name        string
+		replication *api.Replication
+		want        int64
+	}{
+		{
+			name: "downloaded from hub",
+			replication: wrapper.MakeReplication("replication").ChunkName("chunk1--0001").SizeBytes(11).
+				SourceOfHub("Huggingface", "model", "main", "LICENSE").
+				DestinationOfURI("localhost://" + rootPath + "model/blobs/chunk1--0001").Obj(),
+			want: 11,
+		},
+		{
+			name: "downloading from object storage",
+			replication: wrapper.MakeReplication("replication").ChunkName("chunk2--0001").SizeBytes(11).
+				SourceOfURI("s3://models/LICENSE").
+				DestinationOfURI("localhost://" + rootPath + "model/snapshots/main/LICENSE").Obj(),
+			want: 5,
+		},
+		{
+			name: "syncing from peers",
+			replication: wrapper.MakeReplication("replication").ChunkName("chunk2--0001").SizeBytes(11).
+				SourceOfURI("remote://node1@" + rootPath + "model/blobs/chunk2--0001").
+				DestinationOfURI("localhost://" + rootPath + "model/snapshots/main/LICENSE").Obj(),
+			want: 5,
+		},
+		{
+			name: "not started",
+			replication: wrapper.MakeReplication("replication").ChunkName("chunk3--0001").SizeBytes(11).
+				SourceOfURI("remote://node1@" + rootPath + "model/blobs/chunk3--0001").
+				DestinationOfURI("localhost://" + rootPath + "model/snapshots/main/LICENSE").Obj(),
+			want: 0,
+		},
+		{
+			name: "deleting",
+			replication: wrapper.MakeReplication("replication").ChunkName("chunk1--0001").SizeBytes(11).
+				SourceOfURI("localhost://" + rootPath + "model/blobs/chunk1--0001").Obj(),
+			want: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := TransferredBytes(tc.replication)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("unexpected transferred bytes, want %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCreateSymlink(t *testing.T) {
+	repoPath := "../../../tmp/symlink/models/model/"
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/symlink")
+	}()
+
+	if err := os.MkdirAll(repoPath+"blobs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(repoPath+"blobs/chunk--0001", []byte("hello manta"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name       string
+		targetPath string
+		wantLink   string
+	}{
+		{
+			name:       "file in the root folder",
+			targetPath: repoPath + "snapshots/main/model.onnx",
+			wantLink:   "../../blobs/chunk--0001",
+		},
+		{
+			name:       "file in the subfolder",
+			targetPath: repoPath + "snapshots/main/onnx/model.onnx",
+			wantLink:   "../../../blobs/chunk--0001",
+		},
+		{
+			name:       "file in the nested subfolders",
+			targetPath: repoPath + "snapshots/main/onnx/fp16/model.onnx",
+			wantLink:   "../../../../blobs/chunk--0001",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := createSymlink(repoPath+"blobs/chunk--0001", tc.targetPath); err != nil {
+				t.Fatalf("failed to create symlink: %v", err)
+			}
+			link, err := os.Readlink(tc.targetPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if link != tc.wantLink {
+				t.Errorf("unexpected link, want %s, got %s", tc.wantLink, link)
+			}
+			data, err := os.ReadFile(tc.targetPath)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if string(data) != "hello manta" {
+				t.Errorf("unexpected file content: %s", string(data))
+			}
+		})
+	}
+}
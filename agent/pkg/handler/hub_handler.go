@@ -0,0 +1,86 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/inftyai/manta/agent/pkg/util"
+	"github.com/inftyai/manta/pkg/hub"
+	"github.com/inftyai/manta/pkg/objectstore"
+	"github.com/inftyai/manta/pkg/registry"
+)
+
+const (
+	maxAttempts = 10
+)
+
+// The downloadPath is the full path, like: /workspace/models/Qwen--Qwen2-7B-Instruct/blobs/20024bfe7c83998e9aeaf98a0cd6a2ce6306c2f0--0001
+// Once length is not 0, only the byte range [offset, offset+length) of the file will be downloaded.
+func downloadFromHub(modelHub hub.Hub, modelID, revision, path string, downloadPath string, offset, length int64) error {
+	resolveURL := func() string { return modelHub.ResolveURL(modelID, revision, path) }
+	return downloadWithRetry(resolveURL, modelHub.Token(), downloadPath, offset, length)
+}
+
+// downloadFromObjectStore downloads the object referred by the store uri, the url is presigned
+// so no token is needed. The url is presigned again for each attempt, because it may expire
+// before resuming the download of large objects.
+func downloadFromObjectStore(store *objectstore.ObjectStore, downloadPath string, offset, length int64) error {
+	resolveURL := func() string { return store.ResolveURL("") }
+	return downloadWithRetry(resolveURL, "", downloadPath, offset, length)
+}
+
+// downloadFromRegistry downloads the blob referred by the digest, the content is verified by the caller.
+func downloadFromRegistry(reg *registry.Registry, digest string, downloadPath string) error {
+	url, authorization, err := reg.ResolveBlob(digest)
+	if err != nil {
+		return err
+	}
+
+	attempts := 0
+	for {
+		attempts += 1
+
+		if err := util.DownloadRangeWithAuthorization(url, downloadPath, authorization, 0, 0); err != nil {
+			if attempts > maxAttempts {
+				return fmt.Errorf("reach maximum download attempts for %s, err: %v", downloadPath, err)
+			}
+			continue
+		}
+		break
+	}
+	return nil
+}
+
+// downloadWithRetry downloads the file with resume mode, the url is resolved for each attempt.
+func downloadWithRetry(resolveURL func() string, token string, downloadPath string, offset, length int64) error {
+	attempts := 0
+	for {
+
+		attempts += 1
+
+		if err := util.DownloadRangeWithResume(resolveURL(), downloadPath, token, offset, length); err != nil {
+			if attempts > maxAttempts {
+				return fmt.Errorf("reach maximum download attempts for %s, err: %v", downloadPath, err)
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
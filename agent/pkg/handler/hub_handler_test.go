@@ -0,0 +1,129 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inftyai/manta/pkg/hub"
+)
+
+func Test_downloadFromHub(t *testing.T) {
+	testCases := []struct {
+		name         string
+		modelID      string
+		revision     string
+		path         string
+		downloadPath string
+		wantError    bool
+	}{
+		{
+			name:         "normal download",
+			modelID:      "Qwen/Qwen2.5-72B-Instruct",
+			revision:     "main",
+			path:         "LICENSE",
+			downloadPath: "../../../tmp/LICENSE",
+			wantError:    false,
+		},
+		{
+			name:         "unknown revision",
+			modelID:      "Qwen/Qwen2.5-72B-Instruct",
+			revision:     "master", // unknown branch
+			path:         "LICENSE",
+			downloadPath: "../../../tmp/LICENSE",
+			wantError:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotError := downloadFromHub(hub.NewHuggingface(), tc.modelID, tc.revision, tc.path, tc.downloadPath, 0, 0)
+			defer func() {
+				_ = os.RemoveAll(tc.downloadPath)
+			}()
+
+			if tc.wantError && gotError == nil {
+				t.Error("expected error here")
+			}
+			if !tc.wantError {
+				if _, err := os.Stat(tc.downloadPath); err != nil {
+					t.Error("expected file downloaded successfully")
+				}
+			}
+		})
+	}
+}
+
+func Test_downloadWithRetry(t *testing.T) {
+	downloadPath := "../../../tmp/retry/LICENSE"
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/retry")
+	}()
+
+	// Each url is only valid once like an expired presigned url, and the first
+	// download is interrupted halfway, so the resume must use a new url.
+	content := "Apache License Version 2.0"
+	var mu sync.Mutex
+	used := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		signature := r.URL.Query().Get("signature")
+		reused := used[signature]
+		used[signature] = true
+		firstTime := len(used) == 1
+		mu.Unlock()
+
+		if reused {
+			http.Error(w, "request has expired", http.StatusForbidden)
+			return
+		}
+		if firstTime {
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			_, _ = w.Write([]byte(content[:10]))
+			return
+		}
+		http.ServeContent(w, r, "LICENSE", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	signatures := 0
+	resolveURL := func() string {
+		signatures++
+		return fmt.Sprintf("%s/LICENSE?signature=%d", server.URL, signatures)
+	}
+
+	if err := downloadWithRetry(resolveURL, "", downloadPath, 0, 0); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	data, err := os.ReadFile(downloadPath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(data) != content {
+		t.Errorf("unexpected file content: %s", string(data))
+	}
+	if signatures != 2 {
+		t.Errorf("the url should be resolved for each attempt, got %d attempts", signatures)
+	}
+}
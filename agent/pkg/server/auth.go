@@ -0,0 +1,128 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/inftyai/manta/agent/pkg/util"
+)
+
+const (
+	// reviewTTL is how long a successful token review is cached, to avoid
+	// reviewing the same token for every chunk.
+	reviewTTL = time.Minute
+)
+
+// Authenticator authenticates the peer requests with the projected ServiceAccount token,
+// only the agents running with the same ServiceAccount are allowed.
+type Authenticator struct {
+	client   client.Client
+	username string
+
+	mu      sync.Mutex
+	reviews map[string]time.Time
+}
+
+// NewAuthenticator returns an Authenticator accepting tokens of the ServiceAccount
+// read from the env POD_NAMESPACE and SERVICE_ACCOUNT_NAME.
+func NewAuthenticator(c client.Client) (*Authenticator, error) {
+	namespace, name := os.Getenv("POD_NAMESPACE"), os.Getenv("SERVICE_ACCOUNT_NAME")
+	if namespace == "" || name == "" {
+		return nil, fmt.Errorf("env POD_NAMESPACE and SERVICE_ACCOUNT_NAME are required")
+	}
+	return newAuthenticator(c, fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name)), nil
+}
+
+func newAuthenticator(c client.Client, username string) *Authenticator {
+	return &Authenticator{
+		client:   c,
+		username: username,
+		reviews:  map[string]time.Time{},
+	}
+}
+
+// Wrap returns a handler which only serves the authenticated requests.
+func (a *Authenticator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := a.authenticate(r.Context(), token); err != nil {
+			log.FromContext(r.Context()).Error(err, "failed to authenticate the peer request")
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, token string) error {
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+
+	a.mu.Lock()
+	expireTime, ok := a.reviews[key]
+	a.mu.Unlock()
+	if ok && time.Now().Before(expireTime) {
+		return nil
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: []string{util.PeerTokenAudience},
+		},
+	}
+	if err := a.client.Create(ctx, review); err != nil {
+		return err
+	}
+
+	if !review.Status.Authenticated {
+		return fmt.Errorf("token is not authenticated: %s", review.Status.Error)
+	}
+	if review.Status.User.Username != a.username {
+		return fmt.Errorf("user %s is not allowed", review.Status.User.Username)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Drop the expired reviews to bound the cache.
+	now := time.Now()
+	for k, t := range a.reviews {
+		if now.After(t) {
+			delete(a.reviews, k)
+		}
+	}
+	a.reviews[key] = now.Add(reviewTTL)
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/inftyai/manta/agent/pkg/util"
+)
+
+func TestAuthenticator(t *testing.T) {
+	username := "system:serviceaccount:manta-system:manta-agent"
+	// The tokens are mapped to the users, tokens not found are not authenticated.
+	users := map[string]string{
+		"agent-token":   username,
+		"another-token": "system:serviceaccount:default:default",
+	}
+
+	testCases := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantReviews   int
+	}{
+		{
+			name:          "authorized agent",
+			authorization: "Bearer agent-token",
+			wantStatus:    http.StatusOK,
+			wantReviews:   1,
+		},
+		{
+			name:       "no token",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:          "not bearer token",
+			authorization: "Basic agent-token",
+			wantStatus:    http.StatusUnauthorized,
+		},
+		{
+			name:          "unauthenticated token",
+			authorization: "Bearer unknown-token",
+			wantStatus:    http.StatusForbidden,
+			wantReviews:   1,
+		},
+		{
+			name:          "other serviceAccount",
+			authorization: "Bearer another-token",
+			wantStatus:    http.StatusForbidden,
+			wantReviews:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = authenticationv1.AddToScheme(scheme)
+
+			reviews := 0
+			c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, client client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					review := obj.(*authenticationv1.TokenReview)
+					if len(review.Spec.Audiences) != 1 || review.Spec.Audiences[0] != util.PeerTokenAudience {
+						t.Errorf("unexpected audiences: %v", review.Spec.Audiences)
+					}
+					reviews += 1
+					if user, ok := users[review.Spec.Token]; ok {
+						review.Status.Authenticated = true
+						review.Status.User.Username = user
+					}
+					return nil
+				},
+			}).Build()
+
+			handler := newAuthenticator(c, username).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			// Send twice to make sure the successful review is cached.
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/sync", nil)
+				if tc.authorization != "" {
+					req.Header.Set("Authorization", tc.authorization)
+				}
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, req)
+				if recorder.Code != tc.wantStatus {
+					t.Errorf("unexpected status code, want %d, got %d", tc.wantStatus, recorder.Code)
+				}
+			}
+
+			wantReviews := tc.wantReviews
+			if tc.wantStatus == http.StatusForbidden {
+				// Failed reviews are not cached.
+				wantReviews *= 2
+			}
+			if reviews != wantReviews {
+				t.Errorf("unexpected token reviews, want %d, got %d", wantReviews, reviews)
+			}
+		})
+	}
+}
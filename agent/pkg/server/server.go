@@ -26,12 +26,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-func Run(ctx context.Context) {
+// Run serves the sync requests from the peers, all the requests are authenticated by the authenticator.
+func Run(ctx context.Context, authenticator *Authenticator) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/sync", handler.SendChunk)
+	mux.Handle("/sync", authenticator.Wrap(http.HandlerFunc(handler.SendChunk)))
 	server := &http.Server{Addr: ":" + api.HttpPort, Handler: mux}
 
 	go func() {
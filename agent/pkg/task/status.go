@@ -0,0 +1,172 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package task
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/inftyai/manta/agent/pkg/bandwidth"
+	"github.com/inftyai/manta/agent/pkg/version"
+	cons "github.com/inftyai/manta/api"
+	api "github.com/inftyai/manta/api/v1alpha1"
+)
+
+const (
+	heartbeatDuration = 1 * time.Minute
+
+	// The node is under disk pressure once the available space is less than 10% of the capacity.
+	diskPressureThreshold = 0.1
+)
+
+// heartbeat reports the cache state of the node to the nodeTracker status periodically.
+func heartbeat(ctx context.Context, c client.Client) {
+	logger := ctrl.Log.WithName("Background tasks")
+
+	for {
+		if err := updateNodeTrackerStatus(ctx, c); err != nil {
+			logger.Error(err, "Failed to update nodeTracker status", "NodeTracker", os.Getenv("NODE_NAME"))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(heartbeatDuration):
+		}
+	}
+}
+
+func updateNodeTrackerStatus(ctx context.Context, c client.Client) error {
+	nodeTracker := &api.NodeTracker{}
+	// The nodeTracker is created by the syncChunks task, it will be updated at the next heartbeat.
+	if err := c.Get(ctx, types.NamespacedName{Name: os.Getenv("NODE_NAME")}, nodeTracker); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	capacity, available, err := diskUsage(workspace)
+	if err != nil {
+		return err
+	}
+
+	if err := applyBandwidthLimits(nodeTracker); err != nil {
+		ctrl.Log.WithName("Background tasks").Error(err, "Failed to apply bandwidth limits", "NodeTracker", nodeTracker.Name)
+	}
+
+	setNodeTrackerStatus(nodeTracker, capacity, available)
+	nodeTracker.Status.Throughput = &api.Throughput{
+		HubDownload:  bandwidth.HubDownload.Throughput(),
+		PeerDownload: bandwidth.PeerDownload.Throughput(),
+		PeerUpload:   bandwidth.PeerUpload.Throughput(),
+	}
+	return c.Status().Update(ctx, nodeTracker)
+}
+
+// applyBandwidthLimits overrides the limits set by the agent flags with the ones in the nodeTracker,
+// invalid limits are ignored and fall back to the agent flags.
+func applyBandwidthLimits(nt *api.NodeTracker) error {
+	var limits api.BandwidthLimits
+	if nt.Spec.BandwidthLimits != nil {
+		limits = *nt.Spec.BandwidthLimits
+	}
+
+	var errs []error
+	for _, item := range []struct {
+		limiter *bandwidth.Limiter
+		limit   *string
+	}{
+		{limiter: bandwidth.HubDownload, limit: limits.HubDownload},
+		{limiter: bandwidth.PeerDownload, limit: limits.PeerDownload},
+		{limiter: bandwidth.PeerUpload, limit: limits.PeerUpload},
+	} {
+		if item.limit == nil {
+			item.limiter.Override(nil)
+			continue
+		}
+		quantity, err := resource.ParseQuantity(*item.limit)
+		if err != nil {
+			item.limiter.Override(nil)
+			errs = append(errs, err)
+			continue
+		}
+		item.limiter.Override(ptr.To[int64](quantity.Value()))
+	}
+	return errors.Join(errs...)
+}
+
+// diskUsage returns the capacity and the available space of the filesystem the path located.
+func diskUsage(path string) (capacity int64, available int64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	return int64(stat.Blocks) * int64(stat.Bsize), int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
+func setNodeTrackerStatus(nt *api.NodeTracker, capacity, available int64) {
+	var usedBytes int64
+	for _, chunk := range nt.Spec.Chunks {
+		usedBytes += chunk.SizeBytes
+	}
+
+	nt.Status.UsedBytes = usedBytes
+	nt.Status.ChunkCount = int32(len(nt.Spec.Chunks))
+	nt.Status.CapacityBytes = capacity
+	nt.Status.AvailableBytes = available
+	nt.Status.AgentVersion = version.Version
+	if podIP := os.Getenv("POD_IP"); podIP != "" {
+		nt.Status.Endpoint = podIP + ":" + cons.HttpPort
+	}
+	nt.Status.LastHeartbeatTime = &metav1.Time{Time: time.Now()}
+
+	condition := metav1.Condition{
+		Type:    api.DiskPressureConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  "NoDiskPressure",
+		Message: "Node has sufficient disk space",
+	}
+	if capacity > 0 && float64(available) < float64(capacity)*diskPressureThreshold {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "InsufficientDiskSpace"
+		condition.Message = "Available disk space is less than 10% of the capacity"
+	} else if nt.Spec.SizeLimit != nil {
+		if limit, err := resource.ParseQuantity(*nt.Spec.SizeLimit); err == nil && usedBytes >= limit.Value() {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "SizeLimitExceeded"
+			condition.Message = "Chunks reach the size limit"
+		}
+	}
+	apimeta.SetStatusCondition(&nt.Status.Conditions, condition)
+
+	// The manager will set it to Unknown once the heartbeats stop.
+	apimeta.SetStatusCondition(&nt.Status.Conditions, metav1.Condition{
+		Type:    api.ReadyConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "AgentReady",
+		Message: "Agent is heartbeating",
+	})
+}
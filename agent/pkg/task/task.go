@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	"github.com/inftyai/manta/agent/pkg/util"
 	cons "github.com/inftyai/manta/api"
 	api "github.com/inftyai/manta/api/v1alpha1"
 )
@@ -45,6 +46,8 @@ const (
 func BackgroundTasks(ctx context.Context, c client.Client) {
 	// Sync the disk chunk infos to the nodeTracker.
 	go syncChunks(ctx, c)
+	// Report the cache state to the nodeTracker status.
+	go heartbeat(ctx, c)
 }
 
 func syncChunks(ctx context.Context, c client.Client) {
@@ -110,8 +113,10 @@ func UpdateChunks(nt *api.NodeTracker, chunks []chunkInfo) {
 	for _, chunk := range chunks {
 		nt.Spec.Chunks = append(nt.Spec.Chunks,
 			api.ChunkTracker{
-				ChunkName: chunk.Name,
-				SizeBytes: chunk.SizeBytes,
+				ChunkName:      chunk.Name,
+				SizeBytes:      chunk.SizeBytes,
+				Repo:           chunk.Repo,
+				LastAccessTime: ptr.To(v1.NewTime(chunk.LastAccessTime)),
 			},
 		)
 	}
@@ -165,6 +170,11 @@ func findOrCreateNodeTracker(ctx context.Context, c client.Client) error {
 type chunkInfo struct {
 	Name      string
 	SizeBytes int64
+	// Repo represents the repo folder the chunk belongs to.
+	Repo string
+	// LastAccessTime is the modification time of the blob, which will be
+	// refreshed once the blob is served to peers.
+	LastAccessTime time.Time
 }
 
 func walkThroughChunks(path string) (chunks []chunkInfo, err error) {
@@ -180,16 +190,10 @@ func walkThroughChunks(path string) (chunks []chunkInfo, err error) {
 			continue
 		}
 
+		// The repo has no snapshots yet once all the objects are still pending.
 		snapshotPath := path + repo.Name() + "/snapshots/"
-		if _, err := os.Stat(snapshotPath); err != nil {
-			if os.IsNotExist(err) {
-				return nil, nil
-			}
-			return nil, err
-		}
-
 		revisions, err := os.ReadDir(snapshotPath)
-		if err != nil {
+		if err != nil && !os.IsNotExist(err) {
 			return nil, err
 		}
 
@@ -200,40 +204,86 @@ func walkThroughChunks(path string) (chunks []chunkInfo, err error) {
 				continue
 			}
 
-			files, err := os.ReadDir(revisionPath)
-			if err != nil {
-				return nil, err
-			}
-
-			for _, file := range files {
+			// Files could be nested in the subfolders, e.g. onnx/model.onnx.
+			err := filepath.WalkDir(revisionPath, func(filePath string, file os.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
 				if file.IsDir() {
-					continue
+					return nil
 				}
 
-				filePath := revisionPath + file.Name()
 				targetPath, err := os.Readlink(filePath)
 				if err != nil {
-					return nil, err
+					return err
 				}
 				fileInfo, err := os.Stat(filePath)
 				if err != nil {
-					return nil, err
+					return err
 				}
 
-				chunkName := filepath.Base(targetPath)
+				objectChunks := []chunkInfo{{Name: filepath.Base(targetPath), SizeBytes: fileInfo.Size(), Repo: repo.Name(), LastAccessTime: fileInfo.ModTime()}}
 
-				// To avoid duplicated files
-				if _, ok := fileMap[chunkName]; !ok {
-					chunks = append(chunks, chunkInfo{
-						Name:      chunkName,
-						SizeBytes: fileInfo.Size(),
-					})
-					fileMap[chunkName] = struct{}{}
+				// The object is assembled from several chunks.
+				if manifest, err := util.ReadManifest(filepath.Join(filepath.Dir(filePath), targetPath)); err == nil {
+					objectChunks = objectChunks[:0]
+					for _, chunk := range manifest {
+						objectChunks = append(objectChunks, chunkInfo{Name: chunk.ChunkName, SizeBytes: chunk.SizeBytes, Repo: repo.Name(), LastAccessTime: fileInfo.ModTime()})
+					}
+				}
+
+				for _, chunk := range objectChunks {
+					// To avoid duplicated files
+					if _, ok := fileMap[chunk.Name]; !ok {
+						chunks = append(chunks, chunk)
+						fileMap[chunk.Name] = struct{}{}
+					}
 				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
 			}
 		}
 
+		// The chunks waiting for the rest chunks of the same object to assemble.
+		pendingChunks, err := walkThroughPendingChunks(path+repo.Name()+"/blobs/", repo.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, chunk := range pendingChunks {
+			if _, ok := fileMap[chunk.Name]; !ok {
+				chunks = append(chunks, chunk)
+				fileMap[chunk.Name] = struct{}{}
+			}
+		}
+	}
+
+	return chunks, nil
+}
+
+func walkThroughPendingChunks(blobPath string, repoName string) (chunks []chunkInfo, err error) {
+	blobs, err := os.ReadDir(blobPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
+	for _, blob := range blobs {
+		if blob.IsDir() {
+			continue
+		}
+		if _, _, total, err := cons.ParseChunkName(blob.Name()); err != nil || total <= 1 {
+			continue
+		}
+
+		fileInfo, err := blob.Info()
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunkInfo{Name: blob.Name(), SizeBytes: fileInfo.Size(), Repo: repoName, LastAccessTime: fileInfo.ModTime()})
+	}
 	return chunks, nil
 }
@@ -21,7 +21,14 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/inftyai/manta/agent/pkg/bandwidth"
 	"github.com/inftyai/manta/agent/pkg/util"
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/test/util/wrapper"
 )
 
 func Test_walkThroughChunks(t *testing.T) {
@@ -34,18 +41,161 @@ func Test_walkThroughChunks(t *testing.T) {
 		t.Error(err)
 	}
 
-	if err := util.MockRepo(rootPath, "model-2", "master", []string{"fileA", "fileB", "fileC"}, []string{"blobA", "blobB", "blob-same"}); err != nil {
+	if err := util.MockRepo(rootPath, "model-2", "master", []string{"fileA", "fileB", "fileC", "onnx/model.onnx"}, []string{"blobA", "blobB", "blob-same", "blobC"}); err != nil {
 		t.Error(err)
 	}
 
+	// The first chunk of the object is waiting for the second one to assemble, no snapshots yet.
+	if err := os.MkdirAll(rootPath+"model-0/blobs/", os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rootPath+"model-0/blobs/oid--0002", []byte("chunk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
 	chunks, err := walkThroughChunks(rootPath)
 	if err != nil {
 		t.Error(err)
 	}
 
-	wantFiles := []chunkInfo{{Name: "blob1"}, {Name: "blob2"}, {Name: "blob-same"}, {Name: "blobA"}, {Name: "blobB"}}
+	wantFiles := []chunkInfo{
+		{Name: "oid--0002", SizeBytes: 5, Repo: "model-0"},
+		{Name: "blob1", Repo: "model-1"},
+		{Name: "blob2", Repo: "model-1"},
+		{Name: "blob-same", Repo: "model-1"},
+		{Name: "blobA", Repo: "model-2"},
+		{Name: "blobB", Repo: "model-2"},
+		{Name: "blobC", Repo: "model-2"},
+	}
 
-	if diff := cmp.Diff(chunks, wantFiles); diff != "" {
+	if diff := cmp.Diff(chunks, wantFiles, cmpopts.IgnoreFields(chunkInfo{}, "LastAccessTime")); diff != "" {
 		t.Errorf("unexpected files, diff %v", diff)
 	}
 }
+
+func TestSetNodeTrackerStatus(t *testing.T) {
+	testCases := []struct {
+		name             string
+		nodeTracker      *api.NodeTracker
+		capacity         int64
+		available        int64
+		wantUsedBytes    int64
+		wantChunkCount   int32
+		wantDiskPressure metav1.ConditionStatus
+		wantReason       string
+	}{
+		{
+			name:             "sufficient disk space",
+			nodeTracker:      wrapper.MakeNodeTracker("node1").Chunk("chunk1", 100).Chunk("chunk2", 200).Obj(),
+			capacity:         1000,
+			available:        500,
+			wantUsedBytes:    300,
+			wantChunkCount:   2,
+			wantDiskPressure: metav1.ConditionFalse,
+			wantReason:       "NoDiskPressure",
+		},
+		{
+			name:             "insufficient disk space",
+			nodeTracker:      wrapper.MakeNodeTracker("node1").Chunk("chunk1", 100).Obj(),
+			capacity:         1000,
+			available:        50,
+			wantUsedBytes:    100,
+			wantChunkCount:   1,
+			wantDiskPressure: metav1.ConditionTrue,
+			wantReason:       "InsufficientDiskSpace",
+		},
+		{
+			name:             "size limit exceeded",
+			nodeTracker:      wrapper.MakeNodeTracker("node1").SizeLimit("300").Chunk("chunk1", 100).Chunk("chunk2", 200).Obj(),
+			capacity:         1000,
+			available:        500,
+			wantUsedBytes:    300,
+			wantChunkCount:   2,
+			wantDiskPressure: metav1.ConditionTrue,
+			wantReason:       "SizeLimitExceeded",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setNodeTrackerStatus(tc.nodeTracker, tc.capacity, tc.available)
+
+			status := tc.nodeTracker.Status
+			if status.UsedBytes != tc.wantUsedBytes || status.ChunkCount != tc.wantChunkCount {
+				t.Errorf("unexpected usage, want %d bytes of %d chunks, got %d bytes of %d chunks", tc.wantUsedBytes, tc.wantChunkCount, status.UsedBytes, status.ChunkCount)
+			}
+			if status.CapacityBytes != tc.capacity || status.AvailableBytes != tc.available {
+				t.Errorf("unexpected disk usage, got capacity %d, available %d", status.CapacityBytes, status.AvailableBytes)
+			}
+			if status.LastHeartbeatTime == nil || status.AgentVersion == "" {
+				t.Errorf("heartbeat time and agent version should be set")
+			}
+
+			condition := apimeta.FindStatusCondition(status.Conditions, api.DiskPressureConditionType)
+			if condition == nil || condition.Status != tc.wantDiskPressure || condition.Reason != tc.wantReason {
+				t.Errorf("unexpected DiskPressure condition: %v", condition)
+			}
+			if !apimeta.IsStatusConditionTrue(status.Conditions, api.ReadyConditionType) {
+				t.Errorf("nodeTracker should be ready")
+			}
+		})
+	}
+}
+
+func Test_diskUsage(t *testing.T) {
+	capacity, available, err := diskUsage(".")
+	if err != nil {
+		t.Fatalf("failed to get disk usage: %v", err)
+	}
+	if capacity <= 0 || available < 0 || available > capacity {
+		t.Errorf("unexpected disk usage, capacity: %d, available: %d", capacity, available)
+	}
+}
+
+func TestApplyBandwidthLimits(t *testing.T) {
+	bandwidth.HubDownload.SetDefault(100)
+	bandwidth.PeerDownload.SetDefault(200)
+	bandwidth.PeerUpload.SetDefault(300)
+	defer func() {
+		for _, limiter := range []*bandwidth.Limiter{bandwidth.HubDownload, bandwidth.PeerDownload, bandwidth.PeerUpload} {
+			limiter.SetDefault(0)
+		}
+	}()
+
+	testCases := []struct {
+		name        string
+		nodeTracker *api.NodeTracker
+		wantError   bool
+		wantLimits  []int64
+	}{
+		{
+			name:        "no limits in nodeTracker",
+			nodeTracker: wrapper.MakeNodeTracker("node1").Obj(),
+			wantLimits:  []int64{100, 200, 300},
+		},
+		{
+			name:        "limits overridden by nodeTracker",
+			nodeTracker: wrapper.MakeNodeTracker("node1").BandwidthLimits("1Ki", "0", "").Obj(),
+			wantLimits:  []int64{1024, 0, 300},
+		},
+		{
+			name:        "invalid limits",
+			nodeTracker: wrapper.MakeNodeTracker("node1").BandwidthLimits("unknown", "1Mi", "").Obj(),
+			wantError:   true,
+			wantLimits:  []int64{100, 1024 * 1024, 300},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := applyBandwidthLimits(tc.nodeTracker)
+			if tc.wantError != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gotLimits := []int64{bandwidth.HubDownload.Limit(), bandwidth.PeerDownload.Limit(), bandwidth.PeerUpload.Limit()}
+			if diff := cmp.Diff(tc.wantLimits, gotLimits); diff != "" {
+				t.Errorf("unexpected limits (-want +got): %s", diff)
+			}
+		})
+	}
+}
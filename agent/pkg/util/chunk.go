@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/hub"
+)
+
+const (
+	manifestSuffix = ".chunks"
+)
+
+// ManifestPath returns the path of the manifest which records the chunks assembled
+// into the object, e.g. /workspace/models/Qwen--Qwen2-7B-Instruct/blobs/20024bfe7c83998e9aeaf98a0cd6a2ce6306c2f0.chunks
+func ManifestPath(objectPath string) string {
+	return objectPath + manifestSuffix
+}
+
+// WriteManifest records the chunks assembled into the object.
+func WriteManifest(objectPath string, chunks []api.ChunkTracker) error {
+	data, err := json.Marshal(chunks)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ManifestPath(objectPath), data, 0644)
+}
+
+// ReadManifest returns the chunks assembled into the object, once the object is not
+// assembled from several chunks, an os.ErrNotExist error will be returned.
+func ReadManifest(objectPath string) ([]api.ChunkTracker, error) {
+	data, err := os.ReadFile(ManifestPath(objectPath))
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := []api.ChunkTracker{}
+	if err := json.Unmarshal(data, &chunks); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+const (
+	// SizeMismatchReason represents the file size is different from the expected one.
+	SizeMismatchReason = "SizeMismatch"
+	// DigestMismatchReason represents the file digest is different from the expected one.
+	DigestMismatchReason = "DigestMismatch"
+)
+
+// IntegrityError represents the replicated content is corrupted, the file should
+// be discarded rather than retried with resume mode.
+type IntegrityError struct {
+	// Reason is a brief CamelCase reason, which will be used as the condition reason.
+	Reason  string
+	Message string
+}
+
+func (e *IntegrityError) Error() string {
+	return e.Message
+}
+
+// VerifySize verifies the file size with the expected size.
+func VerifySize(path string, size int64) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if fileInfo.Size() != size {
+		return &IntegrityError{
+			Reason:  SizeMismatchReason,
+			Message: fmt.Sprintf("size mismatch for %s, expected %d, got %d", path, size, fileInfo.Size()),
+		}
+	}
+	return nil
+}
+
+// VerifyDigest verifies the file content with the digest, like sha256:<hex>, or gitsha1:<hex>
+// which refers to the git blob id.
+func VerifyDigest(path string, digest string) error {
+	algorithm, expected, found := strings.Cut(digest, ":")
+	if !found || (algorithm != hub.SHA256Algorithm && algorithm != hub.GitSHA1Algorithm) {
+		return fmt.Errorf("unsupported digest: %s", digest)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var hasher hash.Hash
+	if algorithm == hub.SHA256Algorithm {
+		hasher = sha256.New()
+	} else {
+		fileInfo, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		hasher = sha1.New()
+		fmt.Fprintf(hasher, "blob %d\x00", fileInfo.Size())
+	}
+
+	if _, err := io.Copy(hasher, file); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hasher.Sum(nil)); actual != expected {
+		return &IntegrityError{
+			Reason:  DigestMismatchReason,
+			Message: fmt.Sprintf("digest mismatch for %s, expected %s, got %s:%s", path, digest, algorithm, actual),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestVerifyDigest(t *testing.T) {
+	path := "../../../tmp/digest/object"
+	if err := os.MkdirAll("../../../tmp/digest", 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/digest")
+	}()
+	if err := os.WriteFile(path, []byte("hello manta"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name       string
+		digest     string
+		wantError  bool
+		wantReason string
+	}{
+		{
+			name:   "sha256 matched",
+			digest: "sha256:121564b6c4233a0c5418e6ac4af67c7b5d588e76ae74b5fabdfc6a39b4c4ad4b",
+		},
+		{
+			name:   "git blob id matched",
+			digest: "gitsha1:be151c4614ae200987c008ce9086a4238bf8ae1b",
+		},
+		{
+			name:       "sha256 mismatched",
+			digest:     "sha256:0000",
+			wantError:  true,
+			wantReason: DigestMismatchReason,
+		},
+		{
+			name:       "git blob id mismatched",
+			digest:     "gitsha1:0000",
+			wantError:  true,
+			wantReason: DigestMismatchReason,
+		},
+		{
+			name:      "unsupported algorithm",
+			digest:    "md5:0000",
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := VerifyDigest(path, tc.digest)
+			if tc.wantError != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var integrityErr *IntegrityError
+			if errors.As(err, &integrityErr) != (tc.wantReason != "") {
+				t.Fatalf("unexpected integrity error: %v", err)
+			}
+			if integrityErr != nil && integrityErr.Reason != tc.wantReason {
+				t.Errorf("unexpected reason, want %s, got %s", tc.wantReason, integrityErr.Reason)
+			}
+		})
+	}
+}
+
+func TestVerifySize(t *testing.T) {
+	path := "../../../tmp/size/object"
+	if err := os.MkdirAll("../../../tmp/size", 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll("../../../tmp/size")
+	}()
+	if err := os.WriteFile(path, []byte("hello manta"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifySize(path, 11); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var integrityErr *IntegrityError
+	if err := VerifySize(path, 10); !errors.As(err, &integrityErr) || integrityErr.Reason != SizeMismatchReason {
+		t.Errorf("expected size mismatch error, got: %v", err)
+	}
+}
@@ -16,7 +16,11 @@ limitations under the License.
 
 package util
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
 
 // The files length MUST BE THE SAME with blobs, and they should be one-to-one mapping.
 // The elements in blobs should not be empty, but if one element in files is empty,
@@ -35,14 +39,17 @@ func MockRepo(rootPath string, repoName string, revision string, files []string,
 		return err
 	}
 
-	symlinkPrefix := "../../blobs/"
-
 	for i := 0; i < len(files); i++ {
 		if _, err := os.Create(blobPath + blobs[i]); err != nil {
 			return err
 		}
 
 		if files[i] != "" {
+			// Files could be nested in the subfolders, e.g. onnx/model.onnx.
+			if err := os.MkdirAll(filepath.Dir(filePath+files[i]), 0755); err != nil {
+				return err
+			}
+			symlinkPrefix := strings.Repeat("../", strings.Count(files[i], "/")+2) + "blobs/"
 			if err := os.Symlink(symlinkPrefix+blobs[i], filePath+files[i]); err != nil {
 				return err
 			}
@@ -0,0 +1,40 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	// PeerTokenAudience is the audience of the ServiceAccount token used to authenticate
+	// the sync requests between agents, the token is projected via the daemonSet.
+	PeerTokenAudience = "manta-agent"
+	// PeerTokenPath is the path of the projected ServiceAccount token.
+	PeerTokenPath = "/var/run/secrets/manta/token"
+)
+
+// PeerToken returns the projected ServiceAccount token, the token is rotated by
+// kubelet, so read it every time rather than caching it.
+func PeerToken() (string, error) {
+	data, err := os.ReadFile(PeerTokenPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
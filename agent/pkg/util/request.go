@@ -17,15 +17,37 @@ limitations under the License.
 package util
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/inftyai/manta/agent/pkg/bandwidth"
 )
 
 // DownloadFileWithResume will download file with resume mode.
 func DownloadFileWithResume(url string, file string, token string) error {
+	return DownloadRangeWithResume(url, file, token, 0, 0)
+}
+
+// DownloadRangeWithResume will download the byte range [offset, offset+length) of the
+// url to the file with resume mode, once length is 0, the whole file will be downloaded.
+func DownloadRangeWithResume(url string, file string, token string, offset int64, length int64) error {
+	authorization := ""
+	if token != "" {
+		authorization = fmt.Sprintf("Bearer %s", token)
+	}
+	return DownloadRangeWithAuthorization(url, file, authorization, offset, length)
+}
+
+// DownloadRangeWithAuthorization is the same as DownloadRangeWithResume, but with the
+// whole Authorization header rather than the bearer token, e.g. Basic <credentials>.
+// The bandwidth is limited by the hub download limiter.
+func DownloadRangeWithAuthorization(url string, file string, authorization string, offset int64, length int64) error {
 	dir := filepath.Dir(file)
 	err := os.MkdirAll(dir, 0755)
 	if err != nil {
@@ -46,17 +68,25 @@ func DownloadFileWithResume(url string, file string, token string) error {
 	}
 	existingFileSize := fileInfo.Size()
 
+	if length > 0 && existingFileSize >= length {
+		// The range is already downloaded.
+		return nil
+	}
+
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return err
 	}
 
-	if existingFileSize > 0 {
+	rangeRequested := length > 0 || existingFileSize > 0
+	if length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset+existingFileSize, offset+length-1))
+	} else if existingFileSize > 0 {
 		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", existingFileSize))
 	}
 
-	if token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
 	}
 
 	resp, err := http.DefaultClient.Do(req)
@@ -73,14 +103,22 @@ func DownloadFileWithResume(url string, file string, token string) error {
 		return fmt.Errorf("unexpected status code: %v", resp.StatusCode)
 	}
 
-	// File is already downloaded. Should we be more cautious here?
+	// The requested range starts beyond the end of the remote file, it's only regarded as
+	// already downloaded once the local file has exactly the same size as the remote one,
+	// otherwise, the local file is corrupted and should be downloaded from scratch.
 	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
-		return nil
+		if length == 0 && remoteSize(resp) == existingFileSize {
+			return nil
+		}
+		if err := out.Truncate(0); err != nil {
+			return err
+		}
+		return fmt.Errorf("range not satisfiable for %s with %d bytes downloaded, restart downloading", file, existingFileSize)
 	}
 
 	// If the server doesn't support partial download, return error.
-	if resp.StatusCode != http.StatusPartialContent && existingFileSize > 0 {
-		return fmt.Errorf("server doesn't support resuming downloads, status: %s", resp.Status)
+	if resp.StatusCode != http.StatusPartialContent && rangeRequested {
+		return fmt.Errorf("server doesn't support range downloads, status: %s", resp.Status)
 	}
 
 	_, err = out.Seek(existingFileSize, io.SeekStart)
@@ -88,10 +126,129 @@ func DownloadFileWithResume(url string, file string, token string) error {
 		return err
 	}
 
-	_, err = io.Copy(out, resp.Body)
+	_, err = io.Copy(out, bandwidth.HubDownload.Reader(context.Background(), resp.Body))
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// remoteSize returns the total size of the remote file from the Content-Range header
+// of the 416 response, which looks like: bytes */<size>, -1 means unknown.
+func remoteSize(resp *http.Response) int64 {
+	_, total, found := strings.Cut(resp.Header.Get("Content-Range"), "/")
+	if !found {
+		return -1
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// ETagPath returns the path recording the ETag of the partial file.
+func ETagPath(file string) string {
+	return file + ".etag"
+}
+
+// DownloadFileWithETag will download the whole file with resume mode like DownloadFileWithResume,
+// but the partial file is only resumed once the remote ETag is the same as the one recorded
+// when the partial file was downloaded, otherwise, the file will be downloaded from scratch.
+// It's used to sync from peers, so the bandwidth is limited by the peer download limiter.
+func DownloadFileWithETag(url string, file string, authorization string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+
+	fileInfo, err := out.Stat()
+	if err != nil {
+		return err
+	}
+	existingFileSize := fileInfo.Size()
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	// The partial file without ETag recorded can't be validated, download from scratch.
+	if existingFileSize > 0 {
+		if etag, err := os.ReadFile(ETagPath(file)); err == nil && len(etag) > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", existingFileSize))
+			req.Header.Set("If-Range", string(etag))
+		} else {
+			existingFileSize = 0
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The ETag is changed or the server ignores the range, download from scratch.
+		existingFileSize = 0
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		if remoteSize(resp) == existingFileSize {
+			return removeETag(file)
+		}
+		if err := out.Truncate(0); err != nil {
+			return err
+		}
+		return fmt.Errorf("range not satisfiable for %s with %d bytes downloaded, restart downloading", file, existingFileSize)
+	default:
+		return fmt.Errorf("unexpected status code: %v", resp.StatusCode)
+	}
+
+	if err := out.Truncate(existingFileSize); err != nil {
+		return err
+	}
+	if _, err := out.Seek(existingFileSize, io.SeekStart); err != nil {
+		return err
+	}
+
+	// Record the ETag before writing, so the partial file can be resumed once interrupted.
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		if err := os.WriteFile(ETagPath(file), []byte(etag), 0644); err != nil {
+			return err
+		}
+	} else if err := removeETag(file); err != nil {
+		return err
+	}
+
+	written, err := io.Copy(out, bandwidth.PeerDownload.Reader(context.Background(), resp.Body))
+	if err != nil {
+		return err
+	}
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return fmt.Errorf("unexpected EOF, %d of %d bytes downloaded", written, resp.ContentLength)
+	}
+
+	return removeETag(file)
+}
+
+func removeETag(file string) error {
+	if err := os.Remove(ETagPath(file)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadFileWithResume(t *testing.T) {
+	content := "hello manta"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "object", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name        string
+		existing    string
+		wantError   bool
+		wantContent string
+	}{
+		{
+			name:        "download from scratch",
+			wantContent: content,
+		},
+		{
+			name:        "resume from the partial file",
+			existing:    "hello",
+			wantContent: content,
+		},
+		{
+			name:        "already downloaded",
+			existing:    content,
+			wantContent: content,
+		},
+		{
+			name:      "local file is larger than the remote one",
+			existing:  content + " corrupted",
+			wantError: true,
+			// Truncated to download from scratch next time.
+			wantContent: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := "../../../tmp/download/object"
+			defer func() {
+				_ = os.RemoveAll("../../../tmp/download")
+			}()
+
+			if tc.existing != "" {
+				if err := os.MkdirAll("../../../tmp/download", 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tc.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := DownloadFileWithResume(server.URL, path, "")
+			if tc.wantError != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.wantContent {
+				t.Errorf("unexpected content: %s", string(data))
+			}
+		})
+	}
+}
+
+func TestDownloadFileWithETag(t *testing.T) {
+	content := "hello manta"
+	etag := `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "object", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name         string
+		existing     string
+		existingETag string
+		wantContent  string
+	}{
+		{
+			name:        "download from scratch",
+			wantContent: content,
+		},
+		{
+			name:         "resume from the partial file with the same etag",
+			existing:     "hello",
+			existingETag: etag,
+			wantContent:  content,
+		},
+		{
+			name:         "partial file with a different etag",
+			existing:     "HELLO",
+			existingETag: `"v0"`,
+			wantContent:  content,
+		},
+		{
+			name:        "partial file without etag",
+			existing:    "HELLO",
+			wantContent: content,
+		},
+		{
+			name:         "already downloaded",
+			existing:     content,
+			existingETag: etag,
+			wantContent:  content,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := "../../../tmp/download/object"
+			defer func() {
+				_ = os.RemoveAll("../../../tmp/download")
+			}()
+
+			if err := os.MkdirAll("../../../tmp/download", 0755); err != nil {
+				t.Fatal(err)
+			}
+			if tc.existing != "" {
+				if err := os.WriteFile(path, []byte(tc.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.existingETag != "" {
+				if err := os.WriteFile(ETagPath(path), []byte(tc.existingETag), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := DownloadFileWithETag(server.URL, path, ""); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.wantContent {
+				t.Errorf("unexpected content: %s", string(data))
+			}
+			if _, err := os.Stat(ETagPath(path)); !os.IsNotExist(err) {
+				t.Errorf("etag file should be removed once downloaded")
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/inftyai/manta/agent/pkg/bandwidth"
+)
+
+const (
+	// maxPeerFailures is the maximum consecutive failures of one peer, the peer will
+	// be given up once reached, and the pieces will be pulled from the other peers.
+	maxPeerFailures = 3
+	// minPeerThroughput is the minimal throughput of one peer, once a piece is not
+	// finished in time, it will be handed over to the other peers.
+	minPeerThroughput = 1024 * 1024 // 1MiB/s
+	pieceTimeoutBase  = 30 * time.Second
+)
+
+var (
+	// pieceSize is the size of the byte ranges pulled from the peers.
+	pieceSize int64 = 16 * 1024 * 1024
+)
+
+// PiecesPath returns the path recording the downloaded pieces of the partial file.
+func PiecesPath(file string) string {
+	return file + ".pieces"
+}
+
+// DownloadFileFromPeers downloads the file with the given size from several peers in parallel,
+// the file is split into pieces and each peer pulls the pieces one by one, so the faster peers
+// will serve more pieces. Pieces failed or timed out will be handed over to the other peers,
+// and the peer failed continuously will be given up. Downloaded pieces are recorded so the
+// partial file could be resumed. The content is not verified, which should be done by the caller.
+func DownloadFileFromPeers(urls []string, file string, size int64, authorization string) error {
+	if len(urls) == 0 {
+		return fmt.Errorf("no peer available for %s", file)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	if err := out.Truncate(size); err != nil {
+		return err
+	}
+
+	downloaded, err := readPieces(PiecesPath(file))
+	if err != nil {
+		return err
+	}
+
+	total := (size + pieceSize - 1) / pieceSize
+	// Buffered with all the pieces, so handing over pieces will never block.
+	pieces := make(chan int64, total)
+	remaining := 0
+	for i := int64(0); i < total; i++ {
+		if !downloaded[i] {
+			pieces <- i
+			remaining += 1
+		}
+	}
+	if remaining == 0 {
+		return removePieces(file)
+	}
+
+	record, err := os.OpenFile(PiecesPath(file), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = record.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+
+	for _, url := range urls {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+
+			failures := 0
+			for {
+				var index int64
+				select {
+				case <-ctx.Done():
+					return
+				case index = <-pieces:
+				}
+
+				offset := index * pieceSize
+				length := min(pieceSize, size-offset)
+				if err := downloadPiece(ctx, url, out, authorization, offset, length); err != nil {
+					// Hand over the piece to the other peers.
+					pieces <- index
+					failures += 1
+					if failures >= maxPeerFailures {
+						mu.Lock()
+						errs = append(errs, fmt.Errorf("peer %s is given up: %v", url, err))
+						mu.Unlock()
+						return
+					}
+					continue
+				}
+				failures = 0
+
+				mu.Lock()
+				_, err := fmt.Fprintf(record, "%d\n", index)
+				remaining -= 1
+				if err != nil {
+					errs = append(errs, err)
+				}
+				if remaining == 0 {
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(url)
+	}
+	wg.Wait()
+
+	if remaining > 0 {
+		return fmt.Errorf("failed to download %s from peers, %d pieces remaining: %v", file, remaining, errors.Join(errs...))
+	}
+	return removePieces(file)
+}
+
+// downloadPiece downloads the byte range [offset, offset+length) of the url to the same
+// range of the file, the piece should be finished in time or it will be canceled.
+func downloadPiece(ctx context.Context, url string, out *os.File, authorization string, offset, length int64) error {
+	timeout := pieceTimeoutBase + time.Duration(length/minPeerThroughput)*time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("unexpected status code: %v", resp.StatusCode)
+	}
+
+	written, err := io.Copy(io.NewOffsetWriter(out, offset), bandwidth.PeerDownload.Reader(ctx, io.LimitReader(resp.Body, length)))
+	if err != nil {
+		return err
+	}
+	if written != length {
+		return fmt.Errorf("unexpected EOF, %d of %d bytes downloaded", written, length)
+	}
+	return nil
+}
+
+// DownloadedBytes returns the bytes downloaded of the partial file. Once the file is downloaded
+// from several peers, it's preallocated, so only the recorded pieces are counted.
+func DownloadedBytes(file string) (int64, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if _, err := os.Stat(PiecesPath(file)); os.IsNotExist(err) {
+		return info.Size(), nil
+	}
+	pieces, err := readPieces(PiecesPath(file))
+	if err != nil {
+		return 0, err
+	}
+
+	var downloaded int64
+	for index := range pieces {
+		offset := index * pieceSize
+		if offset < info.Size() {
+			downloaded += min(pieceSize, info.Size()-offset)
+		}
+	}
+	return downloaded, nil
+}
+
+func readPieces(path string) (map[int64]bool, error) {
+	pieces := map[int64]bool{}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pieces, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Ignore the broken lines, the pieces will be downloaded again.
+		if index, err := strconv.ParseInt(scanner.Text(), 10, 64); err == nil {
+			pieces[index] = true
+		}
+	}
+	return pieces, scanner.Err()
+}
+
+func removePieces(file string) error {
+	if err := os.Remove(PiecesPath(file)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
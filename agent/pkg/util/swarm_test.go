@@ -0,0 +1,203 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadFileFromPeers(t *testing.T) {
+	oldPieceSize := pieceSize
+	pieceSize = 4
+	defer func() {
+		pieceSize = oldPieceSize
+	}()
+
+	content := "hello manta, pulled from several peers"
+
+	var requests atomic.Int32
+	healthyPeer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.ServeContent(w, r, "object", time.Time{}, strings.NewReader(content))
+	}))
+	defer healthyPeer.Close()
+	slowPeer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		http.ServeContent(w, r, "object", time.Time{}, strings.NewReader(content))
+	}))
+	defer slowPeer.Close()
+	brokenPeer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	defer brokenPeer.Close()
+
+	testCases := []struct {
+		name         string
+		peers        []string
+		existing     string
+		pieces       string
+		wantError    bool
+		wantRequests int32
+	}{
+		{
+			name:         "healthy peers",
+			peers:        []string{healthyPeer.URL, slowPeer.URL},
+			wantRequests: 10,
+		},
+		{
+			name:         "one peer is broken",
+			peers:        []string{brokenPeer.URL, healthyPeer.URL, slowPeer.URL},
+			wantRequests: 10,
+		},
+		{
+			name:      "all peers are broken",
+			peers:     []string{brokenPeer.URL, brokenPeer.URL},
+			wantError: true,
+		},
+		{
+			name:         "resume from the downloaded pieces",
+			peers:        []string{healthyPeer.URL, slowPeer.URL},
+			existing:     content[:8],
+			pieces:       "0\n1\n",
+			wantRequests: 8,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := "../../../tmp/swarm/object"
+			defer func() {
+				_ = os.RemoveAll("../../../tmp/swarm")
+			}()
+			requests.Store(0)
+
+			if err := os.MkdirAll("../../../tmp/swarm", 0755); err != nil {
+				t.Fatal(err)
+			}
+			if tc.existing != "" {
+				if err := os.WriteFile(path, []byte(tc.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.pieces != "" {
+				if err := os.WriteFile(PiecesPath(path), []byte(tc.pieces), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := DownloadFileFromPeers(tc.peers, path, int64(len(content)), "")
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("expected error here")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != content {
+				t.Errorf("unexpected content: %s", string(data))
+			}
+			if requests.Load() != tc.wantRequests {
+				t.Errorf("unexpected requests, want %d, got %d", tc.wantRequests, requests.Load())
+			}
+			if _, err := os.Stat(PiecesPath(path)); !os.IsNotExist(err) {
+				t.Errorf("pieces file should be removed once downloaded")
+			}
+		})
+	}
+}
+
+func TestDownloadedBytes(t *testing.T) {
+	oldPieceSize := pieceSize
+	pieceSize = 4
+	defer func() {
+		pieceSize = oldPieceSize
+	}()
+
+	testCases := []struct {
+		name     string
+		existing string
+		pieces   string
+		want     int64
+	}{
+		{
+			name: "not started",
+			want: 0,
+		},
+		{
+			name:     "downloaded from one peer",
+			existing: "hello",
+			want:     5,
+		},
+		{
+			name:     "downloaded from several peers",
+			existing: "hello manta",
+			pieces:   "0\n2\n",
+			want:     7,
+		},
+		{
+			name:     "preallocated without pieces downloaded",
+			existing: "hello manta",
+			pieces:   "\n",
+			want:     0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := "../../../tmp/downloaded/object"
+			defer func() {
+				_ = os.RemoveAll("../../../tmp/downloaded")
+			}()
+
+			if err := os.MkdirAll("../../../tmp/downloaded", 0755); err != nil {
+				t.Fatal(err)
+			}
+			if tc.existing != "" {
+				if err := os.WriteFile(path, []byte(tc.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tc.pieces != "" {
+				if err := os.WriteFile(PiecesPath(path), []byte(tc.pieces), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := DownloadedBytes(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("unexpected downloaded bytes, want %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxChunkNumber is the maximum chunks of one object, limited by the two digits
+	// of the chunk number in the chunk name.
+	MaxChunkNumber = 99
+
+	chunkSeparator = "--"
+)
+
+// ChunkName returns the chunk name formatted as <object hash>--<chunk index><chunk total>,
+// the index starts from 0, e.g. 945c19bff66ba533eb2032a33dcc6281c4a1e032--0210 refers
+// to the third chunk of the total 10 chunks.
+func ChunkName(hash string, index int, total int) string {
+	return fmt.Sprintf("%s%s%02d%02d", hash, chunkSeparator, index, total)
+}
+
+// ParseChunkName is the reverse of ChunkName.
+func ParseChunkName(name string) (hash string, index int, total int, err error) {
+	pos := strings.LastIndex(name, chunkSeparator)
+	if pos <= 0 {
+		return "", 0, 0, fmt.Errorf("unexpected chunk name: %s", name)
+	}
+
+	hash, suffix := name[:pos], name[pos+len(chunkSeparator):]
+	if len(suffix) != 4 {
+		return "", 0, 0, fmt.Errorf("unexpected chunk name: %s", name)
+	}
+
+	if index, err = strconv.Atoi(suffix[:2]); err != nil {
+		return "", 0, 0, fmt.Errorf("unexpected chunk name: %s", name)
+	}
+	if total, err = strconv.Atoi(suffix[2:]); err != nil {
+		return "", 0, 0, fmt.Errorf("unexpected chunk name: %s", name)
+	}
+	if total == 0 || index >= total {
+		return "", 0, 0, fmt.Errorf("unexpected chunk name: %s", name)
+	}
+	return hash, index, total, nil
+}
+
+// ChunkSizes splits an object into fixed-size chunks, the last chunk takes the remainder.
+// Once the object is too large, the chunk size will be enlarged to not exceed the MaxChunkNumber.
+func ChunkSizes(objectSize int64, chunkSize int64) []int64 {
+	if chunkSize <= 0 || objectSize <= chunkSize {
+		return []int64{objectSize}
+	}
+
+	if minSize := (objectSize + MaxChunkNumber - 1) / MaxChunkNumber; chunkSize < minSize {
+		chunkSize = minSize
+	}
+
+	sizes := []int64{}
+	for offset := int64(0); offset < objectSize; offset += chunkSize {
+		sizes = append(sizes, min(chunkSize, objectSize-offset))
+	}
+	return sizes
+}
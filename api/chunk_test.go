@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChunkName(t *testing.T) {
+	testCases := []struct {
+		name      string
+		chunkName string
+		hash      string
+		index     int
+		total     int
+		wantError bool
+	}{
+		{
+			name:      "single chunk",
+			chunkName: "945c19bff66ba533eb2032a33dcc6281c4a1e032--0001",
+			hash:      "945c19bff66ba533eb2032a33dcc6281c4a1e032",
+			index:     0,
+			total:     1,
+		},
+		{
+			name:      "the third chunk of the total 10 chunks",
+			chunkName: "945c19bff66ba533eb2032a33dcc6281c4a1e032--0210",
+			hash:      "945c19bff66ba533eb2032a33dcc6281c4a1e032",
+			index:     2,
+			total:     10,
+		},
+		{
+			name:      "no separator",
+			chunkName: "945c19bff66ba533eb2032a33dcc6281c4a1e032",
+			wantError: true,
+		},
+		{
+			name:      "index out of range",
+			chunkName: "945c19bff66ba533eb2032a33dcc6281c4a1e032--1010",
+			wantError: true,
+		},
+		{
+			name:      "malformed suffix",
+			chunkName: "945c19bff66ba533eb2032a33dcc6281c4a1e032--01a1",
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hash, index, total, err := ParseChunkName(tc.chunkName)
+			if tc.wantError {
+				if err == nil {
+					t.Error("expected error here")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if hash != tc.hash || index != tc.index || total != tc.total {
+				t.Errorf("unexpected chunk, want %s/%d/%d, got %s/%d/%d", tc.hash, tc.index, tc.total, hash, index, total)
+			}
+			if name := ChunkName(hash, index, total); name != tc.chunkName {
+				t.Errorf("unexpected chunk name, want %s, got %s", tc.chunkName, name)
+			}
+		})
+	}
+}
+
+func TestChunkSizes(t *testing.T) {
+	testCases := []struct {
+		name       string
+		objectSize int64
+		chunkSize  int64
+		want       []int64
+	}{
+		{
+			name:       "chunk size not set",
+			objectSize: 100,
+			chunkSize:  0,
+			want:       []int64{100},
+		},
+		{
+			name:       "object smaller than chunk size",
+			objectSize: 100,
+			chunkSize:  200,
+			want:       []int64{100},
+		},
+		{
+			name:       "object equal to chunk size",
+			objectSize: 100,
+			chunkSize:  100,
+			want:       []int64{100},
+		},
+		{
+			name:       "last chunk takes the remainder",
+			objectSize: 250,
+			chunkSize:  100,
+			want:       []int64{100, 100, 50},
+		},
+		{
+			name:       "chunk size enlarged to not exceed the max chunk number",
+			objectSize: 1000,
+			chunkSize:  1,
+			want: func() []int64 {
+				sizes := make([]int64, 0, 91)
+				for i := 0; i < 90; i++ {
+					sizes = append(sizes, 11)
+				}
+				return append(sizes, 10)
+			}(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ChunkSizes(tc.objectSize, tc.chunkSize)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected chunk sizes, diff %v", diff)
+			}
+		})
+	}
+}
@@ -17,9 +17,15 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// NodeTrackerNameLabelKey is set to the Replications evicting chunks from the node.
+	NodeTrackerNameLabelKey = "manta.io/nodetracker-name"
+)
+
 // No file Path here is just because one chunk could be referenced by several
 // different files, no limitations here. But one chunk could only be belonged
 // to one repo if there's no hash conflicts, we're happy here.
@@ -28,6 +34,16 @@ type ChunkTracker struct {
 	ChunkName string `json:"chunkName"`
 	// SizeBytes represents the chunk size.
 	SizeBytes int64 `json:"sizeBytes"`
+	// Repo represents the repo folder the chunk belongs to in the workspace,
+	// e.g. Qwen--Qwen2-0.5B-Instruct-GGUF, it helps to locate the chunk once evicted.
+	// +optional
+	Repo string `json:"repo,omitempty"`
+	// LastAccessTime represents the last time the chunk was replicated or served to peers,
+	// chunks least recently accessed will be evicted first once the node is running out of space.
+	// Note: reading the models by the local workloads is not tracked, so chunks only used locally
+	// look cold, use the Retain reclaim policy to pin the chunks of the Torrents in use.
+	// +optional
+	LastAccessTime *metav1.Time `json:"lastAccessTime,omitempty"`
 }
 
 // NodeTrackerSpec defines the desired state of NodeTracker
@@ -36,19 +52,107 @@ type NodeTrackerSpec struct {
 	// Chunks represents a list of chunks replicated in this node.
 	// +optional
 	Chunks []ChunkTracker `json:"chunks,omitempty"`
-	// SizeLimit sets the maximum memory reserved for chunks.
-	// If nil, means no limit here, use the whole disk,
-	// use 1Tib instead right now.
+	// SizeLimit sets the maximum memory reserved for chunks, both for dispatching and evicting.
+	// If nil, the defaultSizeLimit of the DiskAware plugin is used, default to 100Gi.
 	// +optional
 	SizeLimit *string `json:"sizeLimit,omitempty"`
+	// BandwidthLimits overrides the bandwidth limits set by the agent flags for this node.
+	// +optional
+	BandwidthLimits *BandwidthLimits `json:"bandwidthLimits,omitempty"`
 }
 
+// BandwidthLimits represents the bandwidth limits of the agent in bytes per second,
+// e.g. 100Mi, 0 means unlimited. If nil, the limit set by the agent flags will be used.
+type BandwidthLimits struct {
+	// HubDownload limits downloading from the model hubs, object storages and image registries.
+	// +optional
+	HubDownload *string `json:"hubDownload,omitempty"`
+	// PeerDownload limits syncing chunks from the peers.
+	// +optional
+	PeerDownload *string `json:"peerDownload,omitempty"`
+	// PeerUpload limits serving chunks to the peers.
+	// +optional
+	PeerUpload *string `json:"peerUpload,omitempty"`
+}
+
+// Throughput represents the average throughput of the agent in bytes per second
+// since the last heartbeat.
+type Throughput struct {
+	// HubDownload represents the throughput of downloading from the model hubs,
+	// object storages and image registries.
+	HubDownload int64 `json:"hubDownload"`
+	// PeerDownload represents the throughput of syncing chunks from the peers.
+	PeerDownload int64 `json:"peerDownload"`
+	// PeerUpload represents the throughput of serving chunks to the peers.
+	PeerUpload int64 `json:"peerUpload"`
+}
+
+// NodeState represents the state of the Node mirrored by the manager, nodes not Ready,
+// cordoned or tainted will be skipped once dispatching the chunks.
+type NodeState struct {
+	// Ready represents whether the Node is Ready.
+	Ready bool `json:"ready"`
+	// Unschedulable represents whether the Node is cordoned.
+	// +optional
+	Unschedulable bool `json:"unschedulable,omitempty"`
+	// Taints represents the taints of the Node.
+	// +optional
+	Taints []corev1.Taint `json:"taints,omitempty"`
+}
+
+const (
+	// DiskPressureConditionType represents the node is running out of the disk space
+	// or the chunks exceed the size limit.
+	DiskPressureConditionType = "DiskPressure"
+)
+
 // NodeTrackerStatus defines the observed state of NodeTracker
-type NodeTrackerStatus struct{}
+type NodeTrackerStatus struct {
+	// Conditions represents the NodeTracker condition, including Ready and DiskPressure.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// UsedBytes represents the total size of the chunks cached in the node.
+	// +optional
+	UsedBytes int64 `json:"usedBytes,omitempty"`
+	// ChunkCount represents the number of the chunks cached in the node.
+	// +optional
+	ChunkCount int32 `json:"chunkCount,omitempty"`
+	// CapacityBytes represents the capacity of the filesystem the workspace located.
+	// +optional
+	CapacityBytes int64 `json:"capacityBytes,omitempty"`
+	// AvailableBytes represents the free space of the filesystem the workspace located.
+	// +optional
+	AvailableBytes int64 `json:"availableBytes,omitempty"`
+	// AgentVersion represents the version of the agent running in the node.
+	// +optional
+	AgentVersion string `json:"agentVersion,omitempty"`
+	// Endpoint represents the address of the agent serving the chunks to peers.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// LastHeartbeatTime represents the last time the agent reported the status,
+	// the status is out of date once the agent stops heartbeating, and the Ready
+	// condition will be set to Unknown by the manager.
+	// +optional
+	LastHeartbeatTime *metav1.Time `json:"lastHeartbeatTime,omitempty"`
+	// Throughput represents the current throughput of the agent.
+	// +optional
+	Throughput *Throughput `json:"throughput,omitempty"`
+	// Node represents the state of the Node, nil means unknown yet and the node is
+	// regarded as available.
+	// +optional
+	Node *NodeState `json:"node,omitempty"`
+}
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Chunks",type=integer,JSONPath=".status.chunkCount"
+//+kubebuilder:printcolumn:name="Used",type=integer,JSONPath=".status.usedBytes"
+//+kubebuilder:printcolumn:name="SizeLimit",type=string,JSONPath=".spec.sizeLimit"
+//+kubebuilder:printcolumn:name="Available",type=integer,JSONPath=".status.availableBytes"
+//+kubebuilder:printcolumn:name="NodeReady",type=boolean,JSONPath=".status.node.ready",priority=1
+//+kubebuilder:printcolumn:name="DiskPressure",type=string,JSONPath=".status.conditions[?(@.type==\"DiskPressure\")].status"
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
 
 // NodeTracker is the Schema for the nodetrackers API
 type NodeTracker struct {
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -30,17 +31,28 @@ const (
 // it means to delete the file.
 type Target struct {
 	// URI represents the file address with different storages, e.g.:
+	// 	 - s3://<bucket>/<path-to-your-file>
 	// 	 - oss://<bucket>.<endpoint>/<path-to-your-file>
+	// 	 - img://<registry>/<repository>@<digest>
 	// 	 - localhost://<path-to-your-file>
 	// 	 - remote://<node-name>@<path-to-your-file>
 	// Localhost means the local host path, remote means the host path of the provided node.
 	// Note: if it's a folder, all the files under the folder will be considered,
 	// otherwise, only one file will be replicated.
 	URI *string `json:"uri,omitempty"`
+	// PeerNodeNames represents the other nodes holding the same file as the remote URI,
+	// only works with the remote URI. Once set, the file will be pulled from all of them
+	// in parallel, each with disjoint byte ranges.
+	// +optional
+	PeerNodeNames []string `json:"peerNodeNames,omitempty"`
 	// Hub represents the model registry for model downloads.
 	// Hub and address are exclusive.
 	// +optional
 	Hub *Hub `json:"hub,omitempty"`
+	// CredentialSecretRef refers to the Secret with the credentials to access the URI,
+	// only works with object storages like s3 and oss, or image registries.
+	// +optional
+	CredentialSecretRef *corev1.SecretReference `json:"credentialSecretRef,omitempty"`
 }
 
 // ReplicationSpec defines the desired state of Replication
@@ -58,8 +70,24 @@ type ReplicationSpec struct {
 	Destination *Target `json:"destination,omitempty"`
 	// SizeBytes represents the chunk size.
 	SizeBytes int64 `json:"sizeBytes"`
+	// OffsetBytes represents the offset of the chunk in the object, together with
+	// the SizeBytes, they make up the byte range of the chunk once the object is
+	// split into several chunks.
+	// +optional
+	OffsetBytes int64 `json:"offsetBytes,omitempty"`
+	// ObjectDigest represents the digest of the whole object the chunk belongs to, formatted
+	// as <algorithm>:<hex>. The object will be verified once downloaded or assembled, on mismatch,
+	// the file will be discarded and the Replication will be failed.
+	// +optional
+	ObjectDigest string `json:"objectDigest,omitempty"`
 }
 
+const (
+	// FailedConditionType represents the Replication is failed, e.g. the replicated content is corrupted,
+	// or the maximum attempts are reached, the chunk will be rescheduled to another node.
+	FailedConditionType = "Failed"
+)
+
 type ReplicateState string
 
 const (
@@ -67,6 +95,19 @@ const (
 	ReadyReplicateState       ReplicateState = "Ready"
 )
 
+// ReplicationProgress represents the progress of the Replication reported by the agent.
+type ReplicationProgress struct {
+	// TransferredBytes represents the bytes of the chunk transferred so far,
+	// the partial downloads before retries are counted as well.
+	TransferredBytes int64 `json:"transferredBytes"`
+	// Throughput represents the average throughput in bytes per second since the last report.
+	Throughput int64 `json:"throughput"`
+	// EstimatedCompletionTime represents the estimated time the transferring will be finished
+	// at the current throughput, nil means unknown, e.g. no bytes transferred since the last report.
+	// +optional
+	EstimatedCompletionTime *metav1.Time `json:"estimatedCompletionTime,omitempty"`
+}
+
 // ReplicationStatus defines the observed state of Replication
 type ReplicationStatus struct {
 	// Conditions represents the Torrent condition.
@@ -74,6 +115,20 @@ type ReplicationStatus struct {
 	// Phase represents the current state.
 	// +optional
 	Phase *string `json:"phase,omitempty"`
+	// FailedAttempts represents the number of the failed attempts, the Replication is retried
+	// with exponential backoff until reaching the maximum attempts of the agent.
+	// +optional
+	FailedAttempts int32 `json:"failedAttempts,omitempty"`
+	// LastFailureTime represents the last time the Replication failed.
+	// +optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+	// LastHeartbeatTime represents the last time the agent reported the Replication is in progress,
+	// the Replication will be marked as Failed and rescheduled once stalled for a long time.
+	// +optional
+	LastHeartbeatTime *metav1.Time `json:"lastHeartbeatTime,omitempty"`
+	// Progress represents the progress of the transferring, reported together with the heartbeats.
+	// +optional
+	Progress *ReplicationProgress `json:"progress,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -81,6 +136,11 @@ type ReplicationStatus struct {
 //+kubebuilder:resource:scope=Cluster
 //+kubebuilder:printcolumn:name="node",type=string,JSONPath=".spec.nodeName"
 //+kubebuilder:printcolumn:name="phase",type=string,JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="transferred",type=integer,JSONPath=".status.progress.transferredBytes"
+//+kubebuilder:printcolumn:name="size",type=integer,JSONPath=".spec.sizeBytes"
+//+kubebuilder:printcolumn:name="throughput",type=integer,JSONPath=".status.progress.throughput",priority=1
+//+kubebuilder:printcolumn:name="eta",type=date,JSONPath=".status.progress.estimatedCompletionTime",priority=1
+//+kubebuilder:printcolumn:name="attempts",type=integer,JSONPath=".status.failedAttempts",priority=1
 //+kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
 
 // Replication is the Schema for the replications API
@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,15 +29,19 @@ const (
 	ParentPodNameAnnoKey       = "manta.io/parent-pod-name"
 
 	HUGGINGFACE_MODEL_HUB = "Huggingface"
+	MODELSCOPE_MODEL_HUB  = "ModelScope"
+
+	// The default branches of the repos differ between the hubs.
+	HUGGINGFACE_DEFAULT_REVISION = "main"
+	MODELSCOPE_DEFAULT_REVISION  = "master"
 )
 
 // This is inspired by https://github.com/InftyAI/llmaz.
 // Hub represents the model registry for model downloads.
 type Hub struct {
-	// TODO: support ModelScope
 	// Name refers to the model registry, such as huggingface.
 	// +kubebuilder:default=Huggingface
-	// +kubebuilder:validation:Enum={Huggingface}
+	// +kubebuilder:validation:Enum={Huggingface,ModelScope}
 	// +optional
 	Name *string `json:"name,omitempty"`
 	// RepoID refers to the identifier on hub,
@@ -45,13 +50,47 @@ type Hub struct {
 	// Filename refers to a specified model file rather than the whole repo.
 	// This is helpful to download a specified GGUF model rather than downloading
 	// the whole repo which includes all kinds of quantized models.
-	// TODO: this is only supported with Huggingface, add support for ModelScope
-	// in the near future.
 	Filename *string `json:"filename,omitempty"`
+	// AllowPatterns represents the glob patterns of the files to download, the same as
+	// the allow_patterns of huggingface_hub, e.g. *.safetensors, files matching any of
+	// them will be downloaded. * matches / as well, and patterns ending with / match the
+	// whole directory. Default to empty indicates all the files are allowed.
+	// Patterns are exclusive with the filename.
+	// +optional
+	AllowPatterns []string `json:"allowPatterns,omitempty"`
+	// IgnorePatterns represents the glob patterns of the files not to download, the same
+	// as the ignore_patterns of huggingface_hub, e.g. *.bin, files matching any of them will
+	// be ignored even if allowed by the allowPatterns.
+	// +optional
+	IgnorePatterns []string `json:"ignorePatterns,omitempty"`
 	// Revision refers to a Git revision id which can be a branch name, a tag, or a commit hash.
-	// +kubebuilder:default=main
+	// Default to the default branch of the hub, i.e. main for Huggingface and master for ModelScope.
 	// +optional
 	Revision *string `json:"revision,omitempty"`
+	// TokenSecretRef refers to the Secret with the token to access the hub under the key
+	// token, which is helpful for gated and private repos. It's used both for listing
+	// the repo and downloading the files, the token itself never appears in Replications.
+	// The Secret can only be referred by users allowed to get it, and it should live in
+	// the namespace Manta is deployed in, i.e. manta-system, which is also the default namespace.
+	// Default to nil indicates the token of the agent envs is used, e.g. HF_TOKEN.
+	// +optional
+	TokenSecretRef *corev1.SecretReference `json:"tokenSecretRef,omitempty"`
+}
+
+// UpdatePolicy represents how to detect and apply the new commits of the hub revision.
+type UpdatePolicy struct {
+	// IntervalSeconds represents the interval to check whether the revision points to
+	// a new commit once the Torrent is Ready.
+	// +kubebuilder:default=3600
+	// +kubebuilder:validation:Minimum=60
+	// +optional
+	IntervalSeconds *int32 `json:"intervalSeconds,omitempty"`
+	// AutoRollout represents whether to roll the Torrent to the new commit automatically,
+	// the chunks not changed will be reused. Default to false indicates only the
+	// UpdateAvailable condition will be set.
+	// +kubebuilder:default=false
+	// +optional
+	AutoRollout *bool `json:"autoRollout,omitempty"`
 }
 
 // URIProtocol represents the protocol of the URI.
@@ -79,12 +118,27 @@ type TorrentSpec struct {
 	// +optional
 	Hub *Hub `json:"hub,omitempty"`
 
-	// TODO: not implemented.
 	// URI represents a various kinds of file sources following the uri protocol, e.g.
-	// 	- Image: img://nginx:1.14.2
+	// 	- S3: s3://<bucket>/<path-to-your-files>
 	// 	- OSS: oss://<bucket>.<endpoint>/<path-to-your-files>
+	// 	- Image: img://<registry>/<repository>:<tag>, e.g. img://nginx:1.14.2
+	// For object storages, the path refers to a directory, all the objects under it
+	// will be replicated, or an object once nothing under it, a path ending with a slash
+	// always refers to a directory. For images, each layer is regarded as an object, named after
+	// the org.opencontainers.image.title annotation or the layer digest.
+	// Hub and URI are exclusive.
 	// +optional
-	// URI *URIProtocol `json:"uri,omitempty"`
+	URI *URIProtocol `json:"uri,omitempty"`
+	// CredentialSecretRef refers to the Secret with the credentials to access the URI.
+	// For object storages, the keys are the same with the aws cli envs, including
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_REGION and AWS_ENDPOINT_URL,
+	// AWS_ENDPOINT_URL is helpful for self-hosted S3-compatible storages.
+	// For images, it's a Secret in type kubernetes.io/dockerconfigjson.
+	// The Secret should live in the namespace Manta is deployed in, i.e. manta-system,
+	// which is also the default namespace, and can only be referred by users allowed to get it.
+	// Default to nil indicates the objects are accessed anonymously.
+	// +optional
+	CredentialSecretRef *corev1.SecretReference `json:"credentialSecretRef,omitempty"`
 
 	// Replicas represents the replication number of each object.
 	// +kubebuilder:default=1
@@ -97,15 +151,32 @@ type TorrentSpec struct {
 	// +kubebuilder:validation:Enum={Retain,Delete}
 	// +optional
 	ReclaimPolicy *ReclaimPolicy `json:"reclaimPolicy,omitempty"`
-	// TTLSecondsAfterReady represents the waiting time to delete the Torrent once Ready.
+	// TTLSecondsAfterReady represents the waiting time in seconds to delete the Torrent once Ready.
+	// The deadline is calculated from the last transition time of the Ready condition,
+	// 0 means the Torrent will be deleted right after it's Ready.
 	// Default to nil indicates Torrent will not be deleted.
-	// TODO: We only support nil and 0 right now.
 	// +optional
 	TTLSecondsAfterReady *time.Duration `json:"ttlSecondsAfterReady,omitempty"`
 	// NodeSelector represents the node constraints to download the chunks.
 	// It can be used to download the model to a specified node for preheating.
 	// +optional
 	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// NodeAffinity represents the node affinity constraints to download the chunks, it works
+	// together with the NodeSelector, the same as the Pod's node affinity, nodes should satisfy
+	// one of the required terms, and the preferred terms are scored by the weights.
+	// The matchFields only supports the metadata.name, which refers to the node name.
+	// +optional
+	NodeAffinity *corev1.NodeAffinity `json:"nodeAffinity,omitempty"`
+	// Tolerations represents the tolerations of the chunks, the same as the Pod's tolerations,
+	// chunks will not be dispatched to the nodes with untolerated NoSchedule or NoExecute taints,
+	// including the cordoned nodes, which are tainted with node.kubernetes.io/unschedulable.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// UpdatePolicy represents how to handle the new commits pushed to the hub revision,
+	// e.g. a branch or a tag moved. Only works with hub.
+	// Default to nil indicates the updates will not be checked.
+	// +optional
+	UpdatePolicy *UpdatePolicy `json:"updatePolicy,omitempty"`
 }
 
 type TrackerState string
@@ -124,7 +195,7 @@ type ChunkStatus struct {
 	// The chunk name is formatted as: <object hash>--<chunk number>,
 	// e.g. "945c19bff66ba533eb2032a33dcc6281c4a1e032--0210", which means:
 	// - the object hash is 945c19bff66ba533eb2032a33dcc6281c4a1e032
-	// - the chunk is the second chunk of the total 10 chunks
+	// - the chunk is the third chunk of the total 10 chunks, chunk number starts from 0
 	Name string `json:"name"`
 	// SizeBytes represents the chunk size.
 	SizeBytes int64 `json:"sizeBytes"`
@@ -150,12 +221,37 @@ type ObjectStatus struct {
 	// Type represents the object type, limits to file or directory.
 	// +kubebuilder:validation:Enum={file,directory}
 	Type ObjectType `json:"type"`
+	// Digest represents the digest of the object content, formatted as <algorithm>:<hex>,
+	// e.g. sha256:<hex>, or gitsha1:<hex> for the git blob id. It's used to verify the
+	// object once replicated, empty means no verification.
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// FailedNodeNames represents the nodes failed to replicate the object, they'll not be
+	// picked as the targets once the object is rescheduled, but could still be the sync sources.
+	// +optional
+	FailedNodeNames []string `json:"failedNodeNames,omitempty"`
 }
 
 type RepoStatus struct {
 	// Objects represents the whole objects belongs to the repo.
 	// +optional
 	Objects []ObjectStatus `json:"objects,omitempty"`
+	// Commit represents the commit the hub revision resolved to, all the objects
+	// are downloaded from the commit, so the nodes always get the same content.
+	// For hubs not able to resolve the revision, it's the same as the revision.
+	// +optional
+	Commit string `json:"commit,omitempty"`
+	// MatchedObjects represents the number of the objects matched the filename or the patterns.
+	// +optional
+	MatchedObjects int32 `json:"matchedObjects,omitempty"`
+	// MatchedBytes represents the total size of the objects matched the filename or the patterns.
+	// +optional
+	MatchedBytes int64 `json:"matchedBytes,omitempty"`
+	// SupersededChunks represents the chunks of the previous commits no longer referred by the
+	// objects after rolling out, they'll be reclaimed from the nodes once the Torrent is Ready
+	// again or deleted, only with the Delete reclaim policy.
+	// +optional
+	SupersededChunks []string `json:"supersededChunks,omitempty"`
 }
 
 const (
@@ -167,8 +263,25 @@ const (
 	ReadyConditionType = "Ready"
 	// ReclaimingConditionType represents the Torrent is removing chunks.
 	ReclaimingConditionType = "Reclaiming"
+	// UpdateAvailableConditionType represents the hub revision points to a new commit.
+	UpdateAvailableConditionType = "UpdateAvailable"
 )
 
+// TorrentProgress represents the replicating progress of the Torrent, the bytes
+// are counted for all the replicas.
+type TorrentProgress struct {
+	// TotalBytes represents the bytes of all the chunks multiplied by the replicas.
+	TotalBytes int64 `json:"totalBytes"`
+	// ReadyBytes represents the bytes of the chunks cached in the nodes.
+	ReadyBytes int64 `json:"readyBytes"`
+	// InFlightBytes represents the bytes transferred by the replicating Replications.
+	InFlightBytes int64 `json:"inFlightBytes"`
+	// TotalObjects represents the number of the objects.
+	TotalObjects int32 `json:"totalObjects"`
+	// ReadyObjects represents the number of the objects with all the chunks ready.
+	ReadyObjects int32 `json:"readyObjects"`
+}
+
 // TorrentStatus defines the observed state of Torrent
 type TorrentStatus struct {
 	// Conditions represents the Torrent condition.
@@ -178,12 +291,29 @@ type TorrentStatus struct {
 	// Phase represents the current state.
 	// +optional
 	Phase *string `json:"phase,omitempty"`
+	// ExpireTime represents the time the Torrent will be deleted at,
+	// only set when ttlSecondsAfterReady is not nil and the Torrent is Ready.
+	// +optional
+	ExpireTime *metav1.Time `json:"expireTime,omitempty"`
+	// Progress represents the replicating progress, aggregated from the nodes and the Replications.
+	// +optional
+	Progress *TorrentProgress `json:"progress,omitempty"`
+	// LastUpdateCheckTime represents the last time checking whether the hub revision
+	// points to a new commit, only works with the updatePolicy.
+	// +optional
+	LastUpdateCheckTime *metav1.Time `json:"lastUpdateCheckTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
 //+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="Objects",type=integer,JSONPath=".status.progress.totalObjects"
+//+kubebuilder:printcolumn:name="ReadyObjects",type=integer,JSONPath=".status.progress.readyObjects"
+//+kubebuilder:printcolumn:name="TotalBytes",type=integer,JSONPath=".status.progress.totalBytes"
+//+kubebuilder:printcolumn:name="ReadyBytes",type=integer,JSONPath=".status.progress.readyBytes"
+//+kubebuilder:printcolumn:name="Commit",type=string,JSONPath=".status.repo.commit",priority=1
+//+kubebuilder:printcolumn:name="InFlightBytes",type=integer,JSONPath=".status.progress.inFlightBytes",priority=1
 //+kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
 
 // Torrent is the Schema for the torrents API
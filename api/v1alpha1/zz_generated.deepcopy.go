@@ -21,11 +21,42 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	timex "time"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BandwidthLimits) DeepCopyInto(out *BandwidthLimits) {
+	*out = *in
+	if in.HubDownload != nil {
+		in, out := &in.HubDownload, &out.HubDownload
+		*out = new(string)
+		**out = **in
+	}
+	if in.PeerDownload != nil {
+		in, out := &in.PeerDownload, &out.PeerDownload
+		*out = new(string)
+		**out = **in
+	}
+	if in.PeerUpload != nil {
+		in, out := &in.PeerUpload, &out.PeerUpload
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BandwidthLimits.
+func (in *BandwidthLimits) DeepCopy() *BandwidthLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(BandwidthLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChunkStatus) DeepCopyInto(out *ChunkStatus) {
 	*out = *in
@@ -44,6 +75,10 @@ func (in *ChunkStatus) DeepCopy() *ChunkStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChunkTracker) DeepCopyInto(out *ChunkTracker) {
 	*out = *in
+	if in.LastAccessTime != nil {
+		in, out := &in.LastAccessTime, &out.LastAccessTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChunkTracker.
@@ -69,11 +104,26 @@ func (in *Hub) DeepCopyInto(out *Hub) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.AllowPatterns != nil {
+		in, out := &in.AllowPatterns, &out.AllowPatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IgnorePatterns != nil {
+		in, out := &in.IgnorePatterns, &out.IgnorePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Revision != nil {
 		in, out := &in.Revision, &out.Revision
 		*out = new(string)
 		**out = **in
 	}
+	if in.TokenSecretRef != nil {
+		in, out := &in.TokenSecretRef, &out.TokenSecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hub.
@@ -86,13 +136,35 @@ func (in *Hub) DeepCopy() *Hub {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeState) DeepCopyInto(out *NodeState) {
+	*out = *in
+	if in.Taints != nil {
+		in, out := &in.Taints, &out.Taints
+		*out = make([]v1.Taint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeState.
+func (in *NodeState) DeepCopy() *NodeState {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeState)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeTracker) DeepCopyInto(out *NodeTracker) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeTracker.
@@ -151,13 +223,20 @@ func (in *NodeTrackerSpec) DeepCopyInto(out *NodeTrackerSpec) {
 	if in.Chunks != nil {
 		in, out := &in.Chunks, &out.Chunks
 		*out = make([]ChunkTracker, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.SizeLimit != nil {
 		in, out := &in.SizeLimit, &out.SizeLimit
 		*out = new(string)
 		**out = **in
 	}
+	if in.BandwidthLimits != nil {
+		in, out := &in.BandwidthLimits, &out.BandwidthLimits
+		*out = new(BandwidthLimits)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeTrackerSpec.
@@ -173,6 +252,27 @@ func (in *NodeTrackerSpec) DeepCopy() *NodeTrackerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeTrackerStatus) DeepCopyInto(out *NodeTrackerStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastHeartbeatTime != nil {
+		in, out := &in.LastHeartbeatTime, &out.LastHeartbeatTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Throughput != nil {
+		in, out := &in.Throughput, &out.Throughput
+		*out = new(Throughput)
+		**out = **in
+	}
+	if in.Node != nil {
+		in, out := &in.Node, &out.Node
+		*out = new(NodeState)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeTrackerStatus.
@@ -193,6 +293,11 @@ func (in *ObjectStatus) DeepCopyInto(out *ObjectStatus) {
 		*out = make([]ChunkStatus, len(*in))
 		copy(*out, *in)
 	}
+	if in.FailedNodeNames != nil {
+		in, out := &in.FailedNodeNames, &out.FailedNodeNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStatus.
@@ -264,6 +369,25 @@ func (in *ReplicationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationProgress) DeepCopyInto(out *ReplicationProgress) {
+	*out = *in
+	if in.EstimatedCompletionTime != nil {
+		in, out := &in.EstimatedCompletionTime, &out.EstimatedCompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationProgress.
+func (in *ReplicationProgress) DeepCopy() *ReplicationProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReplicationSpec) DeepCopyInto(out *ReplicationSpec) {
 	*out = *in
@@ -290,7 +414,7 @@ func (in *ReplicationStatus) DeepCopyInto(out *ReplicationStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -300,6 +424,19 @@ func (in *ReplicationStatus) DeepCopyInto(out *ReplicationStatus) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastHeartbeatTime != nil {
+		in, out := &in.LastHeartbeatTime, &out.LastHeartbeatTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(ReplicationProgress)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationStatus.
@@ -322,6 +459,11 @@ func (in *RepoStatus) DeepCopyInto(out *RepoStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SupersededChunks != nil {
+		in, out := &in.SupersededChunks, &out.SupersededChunks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoStatus.
@@ -342,11 +484,21 @@ func (in *Target) DeepCopyInto(out *Target) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.PeerNodeNames != nil {
+		in, out := &in.PeerNodeNames, &out.PeerNodeNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Hub != nil {
 		in, out := &in.Hub, &out.Hub
 		*out = new(Hub)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CredentialSecretRef != nil {
+		in, out := &in.CredentialSecretRef, &out.CredentialSecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Target.
@@ -359,6 +511,21 @@ func (in *Target) DeepCopy() *Target {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Throughput) DeepCopyInto(out *Throughput) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Throughput.
+func (in *Throughput) DeepCopy() *Throughput {
+	if in == nil {
+		return nil
+	}
+	out := new(Throughput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Torrent) DeepCopyInto(out *Torrent) {
 	*out = *in
@@ -418,6 +585,21 @@ func (in *TorrentList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TorrentProgress) DeepCopyInto(out *TorrentProgress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TorrentProgress.
+func (in *TorrentProgress) DeepCopy() *TorrentProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(TorrentProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TorrentSpec) DeepCopyInto(out *TorrentSpec) {
 	*out = *in
@@ -431,6 +613,16 @@ func (in *TorrentSpec) DeepCopyInto(out *TorrentSpec) {
 		*out = new(Hub)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.URI != nil {
+		in, out := &in.URI, &out.URI
+		*out = new(URIProtocol)
+		**out = **in
+	}
+	if in.CredentialSecretRef != nil {
+		in, out := &in.CredentialSecretRef, &out.CredentialSecretRef
+		*out = new(v1.SecretReference)
+		**out = **in
+	}
 	if in.Replicas != nil {
 		in, out := &in.Replicas, &out.Replicas
 		*out = new(int32)
@@ -453,6 +645,23 @@ func (in *TorrentSpec) DeepCopyInto(out *TorrentSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.NodeAffinity != nil {
+		in, out := &in.NodeAffinity, &out.NodeAffinity
+		*out = new(v1.NodeAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UpdatePolicy != nil {
+		in, out := &in.UpdatePolicy, &out.UpdatePolicy
+		*out = new(UpdatePolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TorrentSpec.
@@ -470,7 +679,7 @@ func (in *TorrentStatus) DeepCopyInto(out *TorrentStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
+		*out = make([]metav1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -485,6 +694,19 @@ func (in *TorrentStatus) DeepCopyInto(out *TorrentStatus) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.ExpireTime != nil {
+		in, out := &in.ExpireTime, &out.ExpireTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(TorrentProgress)
+		**out = **in
+	}
+	if in.LastUpdateCheckTime != nil {
+		in, out := &in.LastUpdateCheckTime, &out.LastUpdateCheckTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TorrentStatus.
@@ -496,3 +718,28 @@ func (in *TorrentStatus) DeepCopy() *TorrentStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpdatePolicy) DeepCopyInto(out *UpdatePolicy) {
+	*out = *in
+	if in.IntervalSeconds != nil {
+		in, out := &in.IntervalSeconds, &out.IntervalSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AutoRollout != nil {
+		in, out := &in.AutoRollout, &out.AutoRollout
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpdatePolicy.
+func (in *UpdatePolicy) DeepCopy() *UpdatePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(UpdatePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -19,13 +19,17 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -34,9 +38,8 @@ import (
 	"github.com/inftyai/manta/pkg/cert"
 	"github.com/inftyai/manta/pkg/controller"
 	"github.com/inftyai/manta/pkg/dispatcher"
-	"github.com/inftyai/manta/pkg/dispatcher/framework"
-	"github.com/inftyai/manta/pkg/dispatcher/plugins/diskaware"
-	"github.com/inftyai/manta/pkg/dispatcher/plugins/nodeselector"
+	"github.com/inftyai/manta/pkg/dispatcher/config"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins"
 	"github.com/inftyai/manta/pkg/webhook"
 	//+kubebuilder:scaffold:imports
 )
@@ -57,24 +60,72 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var chunkSize string
+	var highWatermark, lowWatermark float64
+	var dispatcherConfig string
+	var stallTimeout time.Duration
+	var heartbeatTimeout time.Duration
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&chunkSize, "chunk-size", "2Gi",
+		"The size of each chunk once the object is split into several chunks, e.g. 2Gi. "+
+			"Objects no larger than this will not be split.")
+	flag.Float64Var(&highWatermark, "eviction-high-watermark", 0.9,
+		"The ratio of the node size limit, once the cached chunks exceed it, "+
+			"the least recently used chunks will be evicted.")
+	flag.Float64Var(&lowWatermark, "eviction-low-watermark", 0.8,
+		"The ratio of the node size limit, the eviction stops once the cached chunks are under it.")
+	flag.StringVar(&dispatcherConfig, "dispatcher-config", "",
+		"The path of the DispatcherConfiguration file, the default plugins will be used once not set.")
+	flag.DurationVar(&stallTimeout, "replication-stall-timeout", 10*time.Minute,
+		"How long a replicating Replication could go without heartbeats from the agent, it will be "+
+			"marked as Failed and rescheduled to another node once exceeded, 0 means never.")
+	flag.DurationVar(&heartbeatTimeout, "nodetracker-heartbeat-timeout", 3*time.Minute,
+		"How long the agent could go without heartbeats, the Ready condition of the NodeTracker "+
+			"will be set to Unknown once exceeded, 0 means never. The agent heartbeats every minute.")
 	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	chunkSizeQuantity, err := resource.ParseQuantity(chunkSize)
+	if err != nil {
+		setupLog.Error(err, "unable to parse chunk size", "chunk-size", chunkSize)
+		os.Exit(1)
+	}
+	if lowWatermark <= 0 || lowWatermark > highWatermark || highWatermark > 1 {
+		setupLog.Error(nil, "eviction watermarks must satisfy 0 < low <= high <= 1", "eviction-high-watermark", highWatermark, "eviction-low-watermark", lowWatermark)
+		os.Exit(1)
+	}
+	if stallTimeout < 0 {
+		setupLog.Error(nil, "replication stall timeout must be non-negative", "replication-stall-timeout", stallTimeout)
+		os.Exit(1)
+	}
+	if heartbeatTimeout < 0 {
+		setupLog.Error(nil, "nodeTracker heartbeat timeout must be non-negative", "nodetracker-heartbeat-timeout", heartbeatTimeout)
+		os.Exit(1)
+	}
+	dispatcherCfg, err := config.Load(dispatcherConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to load dispatcher configuration", "dispatcher-config", dispatcherConfig)
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
 		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "11bde122.manta.io",
+		// Secrets are read on demand only, no need to list and watch all of them.
+		Client: client.Options{
+			Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}}},
+		},
+		LeaderElection:   enableLeaderElection,
+		LeaderElectionID: "11bde122.manta.io",
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -102,7 +153,7 @@ func main() {
 	// Cert won't be ready until manager starts, so start a goroutine here which
 	// will block until the cert is ready before setting up the controllers.
 	// Controllers who register after manager starts will start directly.
-	go setupControllers(mgr, certsReady)
+	go setupControllers(mgr, certsReady, chunkSizeQuantity.Value(), highWatermark, lowWatermark, stallTimeout, heartbeatTimeout, dispatcherCfg)
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -121,14 +172,14 @@ func main() {
 	}
 }
 
-func setupControllers(mgr ctrl.Manager, certsReady chan struct{}) {
+func setupControllers(mgr ctrl.Manager, certsReady chan struct{}, chunkSize int64, highWatermark, lowWatermark float64, stallTimeout, heartbeatTimeout time.Duration, dispatcherCfg *config.DispatcherConfiguration) {
 	// The controllers won't work until the webhooks are operating,
 	// and the webhook won't work until the certs are all in places.
 	setupLog.Info("waiting for the cert generation to complete")
 	<-certsReady
 	setupLog.Info("certs ready")
 
-	dispatcher, err := dispatcher.NewDispatcher([]framework.RegisterFunc{nodeselector.New, diskaware.New})
+	dispatcher, err := dispatcher.NewDispatcher(plugins.NewInTreeRegistry(), dispatcherCfg)
 	if err != nil {
 		setupLog.Error(err, "unable to create dispatcher")
 		os.Exit(1)
@@ -137,6 +188,8 @@ func setupControllers(mgr ctrl.Manager, certsReady chan struct{}) {
 	if err := controller.NewReplicationReconciler(
 		mgr.GetClient(),
 		mgr.GetScheme(),
+		dispatcher,
+		stallTimeout,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Replication")
 		os.Exit(1)
@@ -145,6 +198,9 @@ func setupControllers(mgr ctrl.Manager, certsReady chan struct{}) {
 		mgr.GetClient(),
 		mgr.GetScheme(),
 		dispatcher,
+		highWatermark,
+		lowWatermark,
+		heartbeatTimeout,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "NodeTracker")
 		os.Exit(1)
@@ -153,6 +209,7 @@ func setupControllers(mgr ctrl.Manager, certsReady chan struct{}) {
 		mgr.GetClient(),
 		mgr.GetScheme(),
 		dispatcher,
+		chunkSize,
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Torrent")
 		os.Exit(1)
@@ -19,10 +19,12 @@ import (
 	cert "github.com/open-policy-agent/cert-controller/pkg/rotator"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+
+	defaults "github.com/inftyai/manta/pkg"
 )
 
 const (
-	secretNamespace         = "manta-system"
+	secretNamespace         = defaults.MANTA_NAMESPACE
 	serviceName             = "manta-webhook-service"
 	secretName              = "manta-webhook-server-cert"
 	certDir                 = "/tmp/k8s-webhook-server/serving-certs"
@@ -35,7 +37,7 @@ const (
 // dnsName is the format of <service name>.<namespace>.svc
 var dnsName = fmt.Sprintf("%s.%s.svc", serviceName, secretNamespace)
 
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups="",namespace=manta-system,resources=secrets,verbs=get;list;watch;update
 //+kubebuilder:rbac:groups="admissionregistration.k8s.io",resources=mutatingwebhookconfigurations,verbs=get;list;watch;update
 //+kubebuilder:rbac:groups="admissionregistration.k8s.io",resources=validatingwebhookconfigurations,verbs=get;list;watch;update
 
@@ -19,10 +19,18 @@ package controller
 import (
 	"context"
 	"reflect"
+	"slices"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -40,13 +48,23 @@ type NodeTrackerReconciler struct {
 	client.Client
 	Scheme     *runtime.Scheme
 	dispatcher *dispatcher.Dispatcher
+	// Chunks will be evicted once the node exceeds the highWatermark of the size limit,
+	// until it's under the lowWatermark.
+	highWatermark float64
+	lowWatermark  float64
+	// heartbeatTimeout represents how long the agent could go without heartbeats, the Ready
+	// condition will be set to Unknown once exceeded, 0 means never.
+	heartbeatTimeout time.Duration
 }
 
-func NewNodeTrackerReconciler(client client.Client, scheme *runtime.Scheme, dispatcher *dispatcher.Dispatcher) *NodeTrackerReconciler {
+func NewNodeTrackerReconciler(client client.Client, scheme *runtime.Scheme, dispatcher *dispatcher.Dispatcher, highWatermark, lowWatermark float64, heartbeatTimeout time.Duration) *NodeTrackerReconciler {
 	return &NodeTrackerReconciler{
-		Client:     client,
-		Scheme:     scheme,
-		dispatcher: dispatcher,
+		Client:           client,
+		Scheme:           scheme,
+		dispatcher:       dispatcher,
+		highWatermark:    highWatermark,
+		lowWatermark:     lowWatermark,
+		heartbeatTimeout: heartbeatTimeout,
 	}
 }
 
@@ -68,10 +86,31 @@ func (r *NodeTrackerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if err := r.handleEviction(ctx, nodeTracker); err != nil {
+		logger.Error(err, "failed to evict chunks", "NodeTracker", klog.KObj(nodeTracker))
+		return ctrl.Result{}, err
+	}
+
+	// The Ready condition is refreshed by the agent heartbeats, like the Node conditions by the kubelet.
+	var requeueAfter time.Duration
+	if r.heartbeatTimeout > 0 && nodeTracker.Status.LastHeartbeatTime != nil {
+		if remaining := heartbeatRemaining(nodeTracker, r.heartbeatTimeout, time.Now()); remaining > 0 {
+			requeueAfter = remaining
+		} else if setNodeTrackerUnknown(nodeTracker) {
+			logger.Info("agent stopped heartbeating", "NodeTracker", klog.KObj(nodeTracker), "lastHeartbeatTime", nodeTracker.Status.LastHeartbeatTime)
+			if err := r.Client.Status().Update(ctx, nodeTracker); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
 	node := &corev1.Node{}
 	if err := r.Get(ctx, types.NamespacedName{Name: req.Name}, node); err != nil {
 		// Work for integration test.
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
+		return ctrl.Result{}, err
 	}
 
 	if !reflect.DeepEqual(node.Labels, nodeTracker.Labels) {
@@ -81,7 +120,133 @@ func (r *NodeTrackerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// Mirror the node state for dispatching, nodes not Ready, cordoned or tainted will be skipped.
+	if state := nodeState(node); !reflect.DeepEqual(state, nodeTracker.Status.Node) {
+		nodeTracker.Status.Node = state
+		if err := r.Client.Status().Update(ctx, nodeTracker); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// heartbeatRemaining returns the remaining time before the agent is regarded as not heartbeating.
+func heartbeatRemaining(nodeTracker *api.NodeTracker, heartbeatTimeout time.Duration, now time.Time) time.Duration {
+	return nodeTracker.Status.LastHeartbeatTime.Add(heartbeatTimeout).Sub(now)
+}
+
+// setNodeTrackerUnknown sets the Ready condition to Unknown, it'll be set to True again once the
+// agent recovers heartbeating.
+func setNodeTrackerUnknown(nodeTracker *api.NodeTracker) (changed bool) {
+	return apimeta.SetStatusCondition(&nodeTracker.Status.Conditions, metav1.Condition{
+		Type:    api.ReadyConditionType,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "AgentStatusUnknown",
+		Message: "Agent stopped heartbeating",
+	})
+}
+
+func nodeState(node *corev1.Node) *api.NodeState {
+	state := &api.NodeState{
+		Unschedulable: node.Spec.Unschedulable,
+		Taints:        node.Spec.Taints,
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			state.Ready = condition.Status == corev1.ConditionTrue
+			break
+		}
+	}
+	return state
+}
+
+// handleEviction evicts the least recently used chunks once the node is running out of space.
+func (r *NodeTrackerReconciler) handleEviction(ctx context.Context, nodeTracker *api.NodeTracker) error {
+	logger := log.FromContext(ctx)
+
+	replicationList := api.ReplicationList{}
+	selector := labels.SelectorFromSet(labels.Set{api.NodeTrackerNameLabelKey: nodeTracker.Name})
+	if err := r.List(ctx, &replicationList, &client.ListOptions{
+		LabelSelector: selector,
+	}); err != nil {
+		return err
+	}
+
+	reported := sets.New[string]()
+	for _, chunk := range nodeTracker.Spec.Chunks {
+		reported.Insert(chunk.ChunkName)
+	}
+
+	// Wait for the on-going evictions, the nodeTracker will be updated once finished.
+	evicting := false
+	evicted := sets.New[string]()
+	for _, replication := range replicationList.Items {
+		if !apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.ReadyConditionType) {
+			evicting = true
+			continue
+		}
+		// The agent reports the chunks periodically, the finished evictions are kept until the chunks
+		// disappear from the report, or the chunks will be regarded as cached and evicted again.
+		if reported.Has(replication.Spec.ChunkName) {
+			evicted.Insert(replication.Spec.ChunkName)
+			continue
+		}
+		if err := r.Client.Delete(ctx, &replication); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	if evicting {
+		return nil
+	}
+
+	if evicted.Len() > 0 {
+		nodeTracker = nodeTracker.DeepCopy()
+		nodeTracker.Spec.Chunks = slices.DeleteFunc(nodeTracker.Spec.Chunks, func(chunk api.ChunkTracker) bool {
+			return evicted.Has(chunk.ChunkName)
+		})
+	}
+
+	torrents := api.TorrentList{}
+	if err := r.List(ctx, &torrents); err != nil {
+		return err
+	}
+
+	replications, err := r.dispatcher.EvictReplications(nodeTracker, pinnedChunks(torrents.Items), r.highWatermark, r.lowWatermark)
+	if err != nil {
+		return err
+	}
+	if len(replications) > 0 {
+		logger.Info("start to evict chunks", "NodeTracker", klog.KObj(nodeTracker), "number", len(replications))
+	}
+
+	for _, rep := range replications {
+		if err := r.Client.Create(ctx, rep); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// pinnedChunks returns the chunks couldn't be evicted, including the chunks of the Ready Torrents
+// with the Retain policy, and the chunks of the Torrents still under replicating.
+func pinnedChunks(torrents []api.Torrent) sets.Set[string] {
+	pinned := sets.New[string]()
+	for _, torrent := range torrents {
+		if torrent.Status.Repo == nil {
+			continue
+		}
+		if torrentReady(&torrent) && torrent.Spec.ReclaimPolicy != nil && *torrent.Spec.ReclaimPolicy == api.DeleteReclaimPolicy {
+			continue
+		}
+
+		for _, obj := range torrent.Status.Repo.Objects {
+			for _, chunk := range obj.Chunks {
+				pinned.Insert(chunk.Name)
+			}
+		}
+	}
+	return pinned
 }
 
 func (r *NodeTrackerReconciler) Create(e event.CreateEvent) bool {
@@ -122,6 +287,16 @@ func (r *NodeTrackerReconciler) Generic(e event.GenericEvent) bool {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *NodeTrackerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	mapFunc := func(ctx context.Context, obj client.Object) []ctrl.Request {
+		value := obj.GetLabels()[api.NodeTrackerNameLabelKey]
+		if value == "" {
+			return nil
+		}
+		return []ctrl.Request{
+			{NamespacedName: types.NamespacedName{Name: value}},
+		}
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&api.NodeTracker{}).
 		WithEventFilter(r).
@@ -131,11 +306,19 @@ func (r *NodeTrackerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				UpdateFunc: func(e event.UpdateEvent) bool {
 					oldNode := e.ObjectOld.(*corev1.Node)
 					newNode := e.ObjectNew.(*corev1.Node)
-					return !reflect.DeepEqual(oldNode.Labels, newNode.Labels)
-
+					return !reflect.DeepEqual(oldNode.Labels, newNode.Labels) ||
+						!reflect.DeepEqual(nodeState(oldNode), nodeState(newNode))
 				},
 				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
 				GenericFunc: func(e event.GenericEvent) bool { return false },
 			})).
+		// Evicting Replications are watched to clean up once ready.
+		Watches(&api.Replication{}, handler.EnqueueRequestsFromMapFunc(mapFunc),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(e event.CreateEvent) bool { return false },
+				UpdateFunc:  func(e event.UpdateEvent) bool { return true },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+			})).
 		Complete(r)
 }
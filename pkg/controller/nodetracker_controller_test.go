@@ -0,0 +1,163 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher"
+	"github.com/inftyai/manta/pkg/dispatcher/config"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins"
+	"github.com/inftyai/manta/test/util/wrapper"
+)
+
+func TestNodeTrackerHeartbeat(t *testing.T) {
+	now := time.Now()
+	ready := metav1.Condition{Type: api.ReadyConditionType, Status: metav1.ConditionTrue, Reason: "AgentReady"}
+	unknown := metav1.Condition{Type: api.ReadyConditionType, Status: metav1.ConditionUnknown, Reason: "AgentStatusUnknown", Message: "Agent stopped heartbeating"}
+
+	testCases := []struct {
+		name              string
+		lastHeartbeatTime time.Time
+		condition         metav1.Condition
+		wantRemaining     time.Duration
+		wantChanged       bool
+	}{
+		{
+			name:              "heartbeat recently",
+			lastHeartbeatTime: now.Add(-time.Minute),
+			condition:         ready,
+			wantRemaining:     2 * time.Minute,
+		},
+		{
+			name:              "heartbeat expired",
+			lastHeartbeatTime: now.Add(-4 * time.Minute),
+			condition:         ready,
+			wantRemaining:     -time.Minute,
+			wantChanged:       true,
+		},
+		{
+			name:              "already unknown",
+			lastHeartbeatTime: now.Add(-4 * time.Minute),
+			condition:         unknown,
+			wantRemaining:     -time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nodeTracker := wrapper.MakeNodeTracker("node1").Obj()
+			nodeTracker.Status.LastHeartbeatTime = &metav1.Time{Time: tc.lastHeartbeatTime}
+			nodeTracker.Status.Conditions = []metav1.Condition{tc.condition}
+
+			remaining := heartbeatRemaining(nodeTracker, 3*time.Minute, now)
+			if remaining != tc.wantRemaining {
+				t.Errorf("unexpected remaining time, want %v, got %v", tc.wantRemaining, remaining)
+			}
+			if remaining > 0 {
+				return
+			}
+			if changed := setNodeTrackerUnknown(nodeTracker); changed != tc.wantChanged {
+				t.Errorf("unexpected changed, want %v, got %v", tc.wantChanged, changed)
+			}
+			if condition := apimeta.FindStatusCondition(nodeTracker.Status.Conditions, api.ReadyConditionType); condition.Status != metav1.ConditionUnknown {
+				t.Errorf("ready condition should be unknown, got %s", condition.Status)
+			}
+		})
+	}
+}
+
+func TestHandleEviction(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := api.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	d, err := dispatcher.NewDispatcher(plugins.NewInTreeRegistry(), config.Default())
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&api.Replication{}).Build()
+	r := NewNodeTrackerReconciler(c, scheme, d, 0.9, 0.8, 3*time.Minute)
+
+	now := time.Now()
+	chunk := func(name string, size int64, minutesAgo int) api.ChunkTracker {
+		return api.ChunkTracker{ChunkName: name, SizeBytes: size, Repo: "repo", LastAccessTime: &metav1.Time{Time: now.Add(-time.Duration(minutesAgo) * time.Minute)}}
+	}
+	nodeTracker := wrapper.MakeNodeTracker("node1").SizeLimit("100").Obj()
+	nodeTracker.Spec.Chunks = []api.ChunkTracker{chunk("a--0001", 40, 30), chunk("b--0001", 30, 20), chunk("c--0001", 25, 10)}
+
+	evictedChunks := func() (names []string) {
+		replications := api.ReplicationList{}
+		if err := c.List(ctx, &replications); err != nil {
+			t.Fatalf("failed to list replications: %v", err)
+		}
+		for _, replication := range replications.Items {
+			names = append(names, replication.Spec.ChunkName)
+		}
+		return names
+	}
+
+	if err := r.handleEviction(ctx, nodeTracker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a--0001"}, evictedChunks()); diff != "" {
+		t.Fatalf("unexpected evicted chunks (-want +got): %s", diff)
+	}
+
+	// The eviction finished, but the agent hasn't reported the chunks yet.
+	replications := api.ReplicationList{}
+	if err := c.List(ctx, &replications); err != nil {
+		t.Fatalf("failed to list replications: %v", err)
+	}
+	replication := replications.Items[0]
+	replication.Status.Conditions = []metav1.Condition{{Type: api.ReadyConditionType, Status: metav1.ConditionTrue, Reason: "Ready", LastTransitionTime: metav1.Now()}}
+	if err := c.Status().Update(ctx, &replication); err != nil {
+		t.Fatalf("failed to update replication: %v", err)
+	}
+	if err := r.handleEviction(ctx, nodeTracker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a--0001"}, evictedChunks()); diff != "" {
+		t.Fatalf("unexpected evicted chunks (-want +got): %s", diff)
+	}
+	got := api.ReplicationList{}
+	if err := c.List(ctx, &got); err != nil {
+		t.Fatalf("failed to list replications: %v", err)
+	}
+	if !apimeta.IsStatusConditionTrue(got.Items[0].Status.Conditions, api.ReadyConditionType) {
+		t.Errorf("the finished eviction should be kept rather than created again")
+	}
+
+	// The finished eviction is cleaned up once the chunk disappears from the report.
+	nodeTracker.Spec.Chunks = nodeTracker.Spec.Chunks[1:]
+	if err := r.handleEviction(ctx, nodeTracker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string(nil), evictedChunks()); diff != "" {
+		t.Errorf("unexpected evicted chunks (-want +got): %s", diff)
+	}
+}
@@ -18,7 +18,10 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -26,23 +29,38 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher"
+)
+
+const (
+	// replicationNodeNameKey is the index of the Replications by the node name.
+	replicationNodeNameKey = "spec.nodeName"
 )
 
 // ReplicationReconciler reconciles a Replication object
 type ReplicationReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme     *runtime.Scheme
+	dispatcher *dispatcher.Dispatcher
+	// stallTimeout represents how long a replicating Replication could go without heartbeats,
+	// it will be marked as Failed and rescheduled once exceeded, 0 means never.
+	stallTimeout time.Duration
 }
 
-func NewReplicationReconciler(client client.Client, scheme *runtime.Scheme) *ReplicationReconciler {
+func NewReplicationReconciler(client client.Client, scheme *runtime.Scheme, dispatcher *dispatcher.Dispatcher, stallTimeout time.Duration) *ReplicationReconciler {
 	return &ReplicationReconciler{
-		Client: client,
-		Scheme: scheme,
+		Client:       client,
+		Scheme:       scheme,
+		dispatcher:   dispatcher,
+		stallTimeout: stallTimeout,
 	}
 }
 
@@ -67,20 +85,61 @@ func (r *ReplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, r.Status().Update(ctx, replication)
 	}
 
-	return ctrl.Result{}, nil
+	// Only the in-flight downloading or syncing Replications are swept, the failed ones
+	// will be rescheduled to other nodes by the Torrent controller.
+	if replication.Spec.Destination == nil || replicationFinished(replication) {
+		return ctrl.Result{}, nil
+	}
+
+	nodeTracker := &api.NodeTracker{}
+	if err := r.Get(ctx, types.NamespacedName{Name: replication.Spec.NodeName}, nodeTracker); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		logger.Info("nodeTracker of the replication is deleted", "Replication", klog.KObj(replication), "NodeTracker", replication.Spec.NodeName)
+		setReplicationFailed(replication, "NodeTrackerDeleted", fmt.Sprintf("NodeTracker %s is deleted", replication.Spec.NodeName))
+		return ctrl.Result{}, r.Status().Update(ctx, replication)
+	}
+
+	// Pending Replications may wait for the agent for a long time because of the limited concurrency.
+	if r.stallTimeout == 0 || !apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.ReplicateConditionType) {
+		return ctrl.Result{}, nil
+	}
+
+	if remaining := stallRemaining(replication, r.stallTimeout, time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	logger.Info("replication is stalled", "Replication", klog.KObj(replication), "timeout", r.stallTimeout)
+	setReplicationFailed(replication, "Stalled", fmt.Sprintf("No heartbeat from the agent for %s", r.stallTimeout))
+	return ctrl.Result{}, r.Status().Update(ctx, replication)
 }
 
-// Only watch for create events.
+// Create and update events of the in-flight Replications are reconciled to detect the stalled ones,
+// all the events are watched to maintain the loads of nodes in the dispatcher as well.
 func (r *ReplicationReconciler) Create(e event.CreateEvent) bool {
+	if replication, match := e.Object.(*api.Replication); match {
+		r.dispatcher.AddReplication(replication)
+	}
 	return true
 }
 
 func (r *ReplicationReconciler) Delete(e event.DeleteEvent) bool {
+	replication, match := e.Object.(*api.Replication)
+	// Other objs like NodeTrackers should not be handled below.
+	if !match {
+		return true
+	}
+	r.dispatcher.DeleteReplication(replication)
 	return false
 }
 
 func (r *ReplicationReconciler) Update(e event.UpdateEvent) bool {
-	return false
+	newObj, match := e.ObjectNew.(*api.Replication)
+	if !match {
+		return false
+	}
+	r.dispatcher.UpdateReplication(e.ObjectOld.(*api.Replication), newObj)
+	return newObj.Spec.Destination != nil && !replicationFinished(newObj)
 }
 
 func (r *ReplicationReconciler) Generic(e event.GenericEvent) bool {
@@ -89,12 +148,76 @@ func (r *ReplicationReconciler) Generic(e event.GenericEvent) bool {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ReplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &api.Replication{}, replicationNodeNameKey, func(rawObj client.Object) []string {
+		replication := rawObj.(*api.Replication)
+		return []string{replication.Spec.NodeName}
+	}); err != nil {
+		return err
+	}
+
+	// Replications assigned to the deleted NodeTrackers are marked as Failed and rescheduled.
+	mapFunc := func(ctx context.Context, obj client.Object) []ctrl.Request {
+		replicationList := api.ReplicationList{}
+		if err := r.List(ctx, &replicationList, client.MatchingFields{replicationNodeNameKey: obj.GetName()}); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list replications", "NodeTracker", obj.GetName())
+			return nil
+		}
+		requests := make([]ctrl.Request, 0, len(replicationList.Items))
+		for _, replication := range replicationList.Items {
+			requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Name: replication.Name}})
+		}
+		return requests
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&api.Replication{}).
 		WithEventFilter(r).
+		Watches(&api.NodeTracker{}, handler.EnqueueRequestsFromMapFunc(mapFunc),
+			builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(e event.CreateEvent) bool { return false },
+				UpdateFunc:  func(e event.UpdateEvent) bool { return false },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return true },
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+			})).
 		Complete(r)
 }
 
+// stallRemaining returns the remaining time before the Replication is regarded as stalled,
+// counting from the last heartbeat, or the time it started replicating once no heartbeats.
+func stallRemaining(replication *api.Replication, stallTimeout time.Duration, now time.Time) time.Duration {
+	lastHeartbeatTime := replication.Status.LastHeartbeatTime
+	if lastHeartbeatTime == nil {
+		condition := apimeta.FindStatusCondition(replication.Status.Conditions, api.ReplicateConditionType)
+		if condition == nil {
+			return stallTimeout
+		}
+		lastHeartbeatTime = &condition.LastTransitionTime
+	}
+	return lastHeartbeatTime.Add(stallTimeout).Sub(now)
+}
+
+// setReplicationFailed marks the Replication as Failed, the same as the agent does.
+func setReplicationFailed(replication *api.Replication, reason, message string) {
+	apimeta.SetStatusCondition(&replication.Status.Conditions, metav1.Condition{
+		Type:    api.ReplicateConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: "Replication failed",
+	})
+	apimeta.SetStatusCondition(&replication.Status.Conditions, metav1.Condition{
+		Type:    api.FailedConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	replication.Status.Phase = ptr.To[string](api.FailedConditionType)
+}
+
+func replicationFinished(replication *api.Replication) bool {
+	return apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.ReadyConditionType) ||
+		apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.FailedConditionType)
+}
+
 func setReplicationCondition(replication *api.Replication) (changed bool) {
 	if len(replication.Status.Conditions) == 0 {
 		condition := metav1.Condition{
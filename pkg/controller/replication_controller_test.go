@@ -0,0 +1,72 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/test/util/wrapper"
+)
+
+func TestStallRemaining(t *testing.T) {
+	now := time.Now()
+	replicating := func(transitionTime time.Time) []metav1.Condition {
+		return []metav1.Condition{{Type: api.ReplicateConditionType, Status: metav1.ConditionTrue, LastTransitionTime: metav1.NewTime(transitionTime)}}
+	}
+
+	testCases := []struct {
+		name              string
+		conditions        []metav1.Condition
+		lastHeartbeatTime *metav1.Time
+		want              time.Duration
+	}{
+		{
+			name:              "heartbeat recently",
+			conditions:        replicating(now.Add(-time.Hour)),
+			lastHeartbeatTime: ptr.To(metav1.NewTime(now.Add(-time.Minute))),
+			want:              9 * time.Minute,
+		},
+		{
+			name:              "heartbeat expired",
+			conditions:        replicating(now.Add(-time.Hour)),
+			lastHeartbeatTime: ptr.To(metav1.NewTime(now.Add(-11 * time.Minute))),
+			want:              -time.Minute,
+		},
+		{
+			name:       "no heartbeat since replicating",
+			conditions: replicating(now.Add(-2 * time.Minute)),
+			want:       8 * time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			replication := wrapper.MakeReplication("replication").Obj()
+			replication.Status.Conditions = tc.conditions
+			replication.Status.LastHeartbeatTime = tc.lastHeartbeatTime
+
+			if got := stallRemaining(replication, 10*time.Minute, now); got != tc.want {
+				t.Errorf("unexpected remaining time, want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -47,27 +47,41 @@ import (
 	api "github.com/inftyai/manta/api/v1alpha1"
 	defaults "github.com/inftyai/manta/pkg"
 	"github.com/inftyai/manta/pkg/dispatcher"
+	"github.com/inftyai/manta/pkg/hub"
+	"github.com/inftyai/manta/pkg/objectstore"
+	"github.com/inftyai/manta/pkg/registry"
 	"github.com/inftyai/manta/pkg/util"
 )
 
+const (
+	// defaultUpdateCheckIntervalSeconds is the interval to check the updates of the hub
+	// revision once the intervalSeconds of the updatePolicy is not set.
+	defaultUpdateCheckIntervalSeconds = 3600
+)
+
 // TorrentReconciler reconciles a Torrent object
 type TorrentReconciler struct {
 	client.Client
 	Scheme     *runtime.Scheme
 	dispatcher *dispatcher.Dispatcher
+	// chunkSize represents the maximum size of one chunk, objects larger than
+	// this will be split into several chunks.
+	chunkSize int64
 }
 
-func NewTorrentReconciler(client client.Client, scheme *runtime.Scheme, dispatcher *dispatcher.Dispatcher) *TorrentReconciler {
+func NewTorrentReconciler(client client.Client, scheme *runtime.Scheme, dispatcher *dispatcher.Dispatcher, chunkSize int64) *TorrentReconciler {
 	return &TorrentReconciler{
 		Client:     client,
 		Scheme:     scheme,
 		dispatcher: dispatcher,
+		chunkSize:  chunkSize,
 	}
 }
 
 //+kubebuilder:rbac:groups=manta.io,resources=torrents,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=manta.io,resources=torrents/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=manta.io,resources=torrents/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,namespace=manta-system,resources=secrets,verbs=get
 
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.16.3/pkg/reconcile
@@ -101,11 +115,12 @@ func (r *TorrentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if torrentReady(torrent) {
 		logger.Info("start to handle torrent ready")
 
-		if err := r.handleReady(ctx, torrent); err != nil {
+		result, err := r.handleReady(ctx, torrent)
+		if err != nil {
 			logger.Error(err, "failed to handle ready status", "Torrent", klog.KObj(torrent))
 			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil
+		return result, nil
 	}
 
 	if torrent.Status.Repo == nil {
@@ -123,27 +138,100 @@ func (r *TorrentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, err
 	}
 
+	replications, err := r.replications(ctx, torrent)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The chunks of the failed Replications are reset to pending and dispatched again below.
+	failedReplications := rescheduleFailedReplications(torrent, replications)
+	for _, rep := range failedReplications {
+		logger.Info("reschedule the failed replication", "Replication", klog.KObj(rep), "node", rep.Spec.NodeName)
+	}
+
 	// handleDispatcher should be idempotent.
 	torrentStatusChanged, err := r.handleDispatcher(ctx, torrent, nodeTrackers.Items)
 	if err != nil {
 		logger.Error(err, "failed to dispatcher torrent")
+		// Persist the rescheduling anyway, the chunks will be dispatched once nodes are available.
+		if len(failedReplications) > 0 {
+			if err := r.persistRescheduling(ctx, torrent, failedReplications); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
 		return ctrl.Result{}, err
 	}
 
-	replications, err := r.replications(ctx, torrent)
+	// The rescheduled Replications may not be observed yet, wait for the next reconciliation
+	// to set the condition.
+	if len(failedReplications) > 0 {
+		return ctrl.Result{}, r.persistRescheduling(ctx, torrent, failedReplications)
+	}
+
+	replications, err = r.replications(ctx, torrent)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
 	// set the condition.
 	conditionChanged := setTorrentCondition(torrent, replications)
-	if torrentStatusChanged || conditionChanged {
+	progressChanged := setTorrentProgress(torrent, replications, r.dispatcher.ChunkNodes)
+	if torrentStatusChanged || conditionChanged || progressChanged {
 		return ctrl.Result{}, r.Status().Update(ctx, torrent)
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// persistRescheduling updates the Torrent status, then deletes the failed Replications,
+// they're deleted only after the rescheduling is persisted, or the chunks will be lost.
+func (r *TorrentReconciler) persistRescheduling(ctx context.Context, torrent *api.Torrent, failedReplications []*api.Replication) error {
+	if err := r.Status().Update(ctx, torrent); err != nil {
+		return err
+	}
+	for _, rep := range failedReplications {
+		if err := r.Client.Delete(ctx, rep); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// rescheduleFailedReplications resets the chunks of the failed Replications to pending, and
+// records the failed nodes to the objects to reschedule the chunks to other nodes. All the
+// chunks of the object are reset, or the object couldn't be assembled in the same node.
+func rescheduleFailedReplications(torrent *api.Torrent, replications []api.Replication) (failed []*api.Replication) {
+	for i := range replications {
+		replication := &replications[i]
+		if replication.Spec.Destination == nil || !apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.FailedConditionType) {
+			continue
+		}
+
+		for j, obj := range torrent.Status.Repo.Objects {
+			if !objectHasChunk(obj, replication.Spec.ChunkName) {
+				continue
+			}
+			for k := range obj.Chunks {
+				torrent.Status.Repo.Objects[j].Chunks[k].State = api.PendingTrackerState
+			}
+			if !util.SetContains(obj.FailedNodeNames, replication.Spec.NodeName) {
+				torrent.Status.Repo.Objects[j].FailedNodeNames = append(obj.FailedNodeNames, replication.Spec.NodeName)
+			}
+		}
+		failed = append(failed, replication)
+	}
+	return failed
+}
+
+func objectHasChunk(obj api.ObjectStatus, chunkName string) bool {
+	for _, chunk := range obj.Chunks {
+		if chunk.Name == chunkName {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *TorrentReconciler) handleCreation(ctx context.Context, torrent *api.Torrent) (err error) {
 	if controllerutil.AddFinalizer(torrent, api.TorrentProtectionFinalizer) {
 		if err := r.Client.Update(ctx, torrent); err != nil {
@@ -155,16 +243,75 @@ func (r *TorrentReconciler) handleCreation(ctx context.Context, torrent *api.Tor
 
 	_ = setTorrentCondition(torrent, nil)
 
-	// TODO: We only support hub right now, we need to support spec.URI in the future as well.
-	objects, err := util.ListRepoObjects(torrent.Spec.Hub.RepoID, *torrent.Spec.Hub.Revision)
+	// The hub revision is resolved to the commit, so the nodes always get the same content
+	// even if new commits are pushed to the revision during replicating.
+	var commit string
+	if torrent.Spec.Hub != nil {
+		modelHub, err := r.newHub(ctx, torrent)
+		if err != nil {
+			return err
+		}
+		commit, err = modelHub.ResolveRevision(torrent.Spec.Hub.RepoID, *torrent.Spec.Hub.Revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	objects, err := r.listObjects(ctx, torrent, commit)
 	if err != nil {
 		return err
 	}
-	constructRepoStatus(torrent, objects)
+	chunkSize := r.chunkSize
+	// Image layers are verified by the digest once downloaded, so they're not split.
+	if torrent.Spec.URI != nil && registry.IsImageURI(string(*torrent.Spec.URI)) {
+		chunkSize = 0
+	}
+	constructRepoStatus(torrent, objects, chunkSize)
+	torrent.Status.Repo.Commit = commit
 
 	return r.Client.Status().Update(ctx, torrent)
 }
 
+// listObjects lists the objects either from the model hub, the object storage or the image registry,
+// the objects of the model hub are listed in the commit.
+func (r *TorrentReconciler) listObjects(ctx context.Context, torrent *api.Torrent, commit string) ([]*hub.ObjectBody, error) {
+	if torrent.Spec.URI != nil {
+		credentials, err := util.SecretData(ctx, r.Client, torrent.Spec.CredentialSecretRef)
+		if err != nil {
+			return nil, err
+		}
+
+		if registry.IsImageURI(string(*torrent.Spec.URI)) {
+			reg, err := registry.NewRegistry(string(*torrent.Spec.URI), credentials)
+			if err != nil {
+				return nil, err
+			}
+			return reg.ListObjects()
+		}
+
+		store, err := objectstore.NewObjectStore(string(*torrent.Spec.URI), credentials)
+		if err != nil {
+			return nil, err
+		}
+		return store.ListObjects()
+	}
+
+	modelHub, err := r.newHub(ctx, torrent)
+	if err != nil {
+		return nil, err
+	}
+	return modelHub.ListRepoObjects(torrent.Spec.Hub.RepoID, commit)
+}
+
+// newHub returns the model hub of the torrent, with the token in the referred Secret if any.
+func (r *TorrentReconciler) newHub(ctx context.Context, torrent *api.Torrent) (hub.Hub, error) {
+	credentials, err := util.SecretData(ctx, r.Client, torrent.Spec.Hub.TokenSecretRef)
+	if err != nil {
+		return nil, err
+	}
+	return hub.NewHub(*torrent.Spec.Hub.Name, credentials)
+}
+
 func (r *TorrentReconciler) handleDeletion(ctx context.Context, torrent *api.Torrent) error {
 	if *torrent.Spec.ReclaimPolicy == api.RetainReclaimPolicy {
 		if controllerutil.RemoveFinalizer(torrent, api.TorrentProtectionFinalizer) {
@@ -209,34 +356,222 @@ func (r *TorrentReconciler) handleDeletion(ctx context.Context, torrent *api.Tor
 	return nil
 }
 
-func (r *TorrentReconciler) handleReady(ctx context.Context, torrent *api.Torrent) error {
+func (r *TorrentReconciler) handleReady(ctx context.Context, torrent *api.Torrent) (ctrl.Result, error) {
 	// request the callback to notify the pod, model download/sync is finished.
 	if torrent.Annotations[api.ParentPodNameAnnoKey] != "" {
 		if err := callback(ctx, r.Client, torrent); err != nil {
-			return err
+			return ctrl.Result{}, err
 		}
 	}
 
-	// TODO: once ttl supports other values than 0, we need to refactor here.
 	if torrent.Spec.TTLSecondsAfterReady != nil && *torrent.Spec.TTLSecondsAfterReady == time.Duration(0) {
 		// Corresponding Replications will be deleted as well.
-		if err := r.Client.Delete(ctx, torrent); err != nil {
-			return err
-		}
-		return nil
+		return ctrl.Result{}, r.Client.Delete(ctx, torrent)
 	}
 
 	replications, err := r.replications(ctx, torrent)
 	if err != nil {
-		return err
+		return ctrl.Result{}, err
 	}
 
 	for _, replication := range replications {
-		if err := r.Client.Delete(ctx, &replication); err != nil {
-			return err
+		// The superseded chunks are still being reclaimed.
+		if replication.Spec.Destination == nil && !apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.ReadyConditionType) {
+			continue
+		}
+		if err := r.Client.Delete(ctx, &replication); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
 		}
 	}
-	return nil
+
+	if torrent.Status.Repo != nil && len(torrent.Status.Repo.SupersededChunks) > 0 {
+		return ctrl.Result{}, r.reclaimSupersededChunks(ctx, torrent)
+	}
+
+	nextCheck, rolledOut, err := r.checkUpdate(ctx, torrent)
+	if err != nil || rolledOut {
+		return ctrl.Result{}, err
+	}
+
+	if torrent.Spec.TTLSecondsAfterReady == nil {
+		return ctrl.Result{RequeueAfter: nextCheck}, nil
+	}
+
+	// The deadline is calculated from the time Torrent becomes Ready.
+	expireTime := expireTime(torrent)
+	if !expireTime.Equal(torrent.Status.ExpireTime) {
+		torrent.Status.ExpireTime = &expireTime
+		if err := r.Status().Update(ctx, torrent); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if remaining := time.Until(expireTime.Time); remaining > 0 {
+		if nextCheck > 0 && nextCheck < remaining {
+			return ctrl.Result{RequeueAfter: nextCheck}, nil
+		}
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	return ctrl.Result{}, r.Client.Delete(ctx, torrent)
+}
+
+// checkUpdate checks whether the hub revision points to a new commit periodically once the
+// updatePolicy is set, the UpdateAvailable condition will be set, and the new commit will be
+// rolled out once autoRollout is true. It returns the waiting time before the next check.
+func (r *TorrentReconciler) checkUpdate(ctx context.Context, torrent *api.Torrent) (nextCheck time.Duration, rolledOut bool, err error) {
+	if !updateCheckEnabled(torrent) {
+		return 0, false, nil
+	}
+
+	interval := time.Duration(defaultUpdateCheckIntervalSeconds) * time.Second
+	if torrent.Spec.UpdatePolicy.IntervalSeconds != nil {
+		interval = time.Duration(*torrent.Spec.UpdatePolicy.IntervalSeconds) * time.Second
+	}
+	if remaining := time.Until(lastUpdateCheckTime(torrent).Add(interval)); remaining > 0 {
+		return remaining, false, nil
+	}
+
+	modelHub, err := r.newHub(ctx, torrent)
+	if err != nil {
+		return 0, false, err
+	}
+	commit, err := modelHub.ResolveRevision(torrent.Spec.Hub.RepoID, *torrent.Spec.Hub.Revision)
+	if err != nil {
+		return 0, false, err
+	}
+	torrent.Status.LastUpdateCheckTime = ptr.To(metav1.Now())
+
+	if commit == torrent.Status.Repo.Commit {
+		apimeta.SetStatusCondition(&torrent.Status.Conditions, metav1.Condition{
+			Type:    api.UpdateAvailableConditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "UpToDate",
+			Message: fmt.Sprintf("Revision %s is still at commit %s", *torrent.Spec.Hub.Revision, commit),
+		})
+		return interval, false, r.Status().Update(ctx, torrent)
+	}
+
+	log.FromContext(ctx).Info("new commit available", "Torrent", klog.KObj(torrent), "revision", *torrent.Spec.Hub.Revision, "commit", commit)
+	apimeta.SetStatusCondition(&torrent.Status.Conditions, metav1.Condition{
+		Type:    api.UpdateAvailableConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "NewCommit",
+		Message: fmt.Sprintf("Revision %s is moved from commit %s to %s", *torrent.Spec.Hub.Revision, torrent.Status.Repo.Commit, commit),
+	})
+	if torrent.Spec.UpdatePolicy.AutoRollout == nil || !*torrent.Spec.UpdatePolicy.AutoRollout {
+		return interval, false, r.Status().Update(ctx, torrent)
+	}
+
+	objects, err := modelHub.ListRepoObjects(torrent.Spec.Hub.RepoID, commit)
+	if err != nil {
+		return 0, false, err
+	}
+	rolloutRepoStatus(torrent, objects, commit, r.chunkSize)
+	return 0, true, r.Status().Update(ctx, torrent)
+}
+
+// updateCheckEnabled returns whether to check the updates of the hub revision, revisions
+// already pinned to a commit, or not resolvable by the hub, will never be updated.
+func updateCheckEnabled(torrent *api.Torrent) bool {
+	return torrent.Spec.UpdatePolicy != nil && torrent.Spec.Hub != nil &&
+		torrent.Status.Repo != nil && torrent.Status.Repo.Commit != "" &&
+		torrent.Status.Repo.Commit != *torrent.Spec.Hub.Revision
+}
+
+// lastUpdateCheckTime returns the last time checking the updates, the revision is resolved
+// when the Torrent is created, so it defaults to the time Torrent becomes Ready.
+func lastUpdateCheckTime(torrent *api.Torrent) time.Time {
+	if torrent.Status.LastUpdateCheckTime != nil {
+		return torrent.Status.LastUpdateCheckTime.Time
+	}
+	if condition := apimeta.FindStatusCondition(torrent.Status.Conditions, api.ReadyConditionType); condition != nil {
+		return condition.LastTransitionTime.Time
+	}
+	return time.Time{}
+}
+
+// reclaimSupersededChunks deletes the superseded chunks from the nodes once the rollout is Ready,
+// the chunks are retained with the Retain reclaim policy. The Replications are created before
+// clearing the superseded chunks, or the chunks will be leaked once failed to update the status.
+func (r *TorrentReconciler) reclaimSupersededChunks(ctx context.Context, torrent *api.Torrent) error {
+	if *torrent.Spec.ReclaimPolicy == api.DeleteReclaimPolicy {
+		for _, rep := range r.dispatcher.SupersededReplications(ctx, torrent) {
+			if err := r.Client.Create(ctx, rep); err != nil && !apierrors.IsAlreadyExists(err) {
+				return err
+			}
+		}
+	}
+	torrent.Status.Repo.SupersededChunks = nil
+	return r.Status().Update(ctx, torrent)
+}
+
+// rolloutRepoStatus rolls the Torrent to the new commit, all the chunks are reset to pending and
+// dispatched again like the Torrent is just created, the chunks not changed are already cached
+// in the nodes, so they're reused without replicating again.
+// The chunks of the changed objects are recorded as superseded to be reclaimed later.
+func rolloutRepoStatus(torrent *api.Torrent, objects []*hub.ObjectBody, commit string, chunkSize int64) {
+	failedNodeNames := map[string][]string{}
+	// The superseded chunks not reclaimed yet, e.g. rolled out again before Ready, are kept.
+	previousChunks := append([]string{}, torrent.Status.Repo.SupersededChunks...)
+	for _, obj := range torrent.Status.Repo.Objects {
+		if len(obj.Chunks) > 0 {
+			failedNodeNames[obj.Chunks[0].Name] = obj.FailedNodeNames
+		}
+		for _, chunk := range obj.Chunks {
+			previousChunks = append(previousChunks, chunk.Name)
+		}
+	}
+
+	constructRepoStatus(torrent, objects, chunkSize)
+	torrent.Status.Repo.Commit = commit
+	currentChunks := map[string]bool{}
+	// The nodes failed to replicate the objects not changed are still excluded.
+	for i, obj := range torrent.Status.Repo.Objects {
+		if len(obj.Chunks) > 0 {
+			torrent.Status.Repo.Objects[i].FailedNodeNames = failedNodeNames[obj.Chunks[0].Name]
+		}
+		for _, chunk := range obj.Chunks {
+			currentChunks[chunk.Name] = true
+		}
+	}
+	for _, name := range previousChunks {
+		if !currentChunks[name] && !util.SetContains(torrent.Status.Repo.SupersededChunks, name) {
+			torrent.Status.Repo.SupersededChunks = append(torrent.Status.Repo.SupersededChunks, name)
+		}
+	}
+
+	message := fmt.Sprintf("Rolling out commit %s", commit)
+	for _, conditionType := range []string{api.ReadyConditionType, api.ReplicateConditionType} {
+		apimeta.SetStatusCondition(&torrent.Status.Conditions, metav1.Condition{
+			Type:    conditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  "RollingOut",
+			Message: message,
+		})
+	}
+	apimeta.SetStatusCondition(&torrent.Status.Conditions, metav1.Condition{
+		Type:    api.UpdateAvailableConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  "RollingOut",
+		Message: message,
+	})
+	_ = setTorrentConditionTo(torrent, metav1.Condition{
+		Type:    api.PendingConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  "RollingOut",
+		Message: message,
+	})
+	torrent.Status.ExpireTime = nil
+}
+
+// expireTime returns the time the Torrent should be deleted at, only works when
+// Torrent is Ready and ttlSecondsAfterReady is not nil.
+func expireTime(torrent *api.Torrent) metav1.Time {
+	condition := apimeta.FindStatusCondition(torrent.Status.Conditions, api.ReadyConditionType)
+	// The ttl is serialized as a plain number of seconds, which is decoded as nanoseconds.
+	ttl := *torrent.Spec.TTLSecondsAfterReady * time.Second
+	// Truncate to seconds to keep consistent with the serialized status.
+	return metav1.NewTime(condition.LastTransitionTime.Add(ttl)).Rfc3339Copy()
 }
 
 func (r *TorrentReconciler) handleDispatcher(ctx context.Context, torrent *api.Torrent, nodeTrackers []api.NodeTracker) (statusChanged bool, err error) {
@@ -355,8 +690,8 @@ func setTorrentCondition(torrent *api.Torrent, replications []api.Replication) (
 		return false
 	}
 
-	// Set to Ready condition.
-	if apimeta.IsStatusConditionTrue(torrent.Status.Conditions, api.ReplicateConditionType) && replicationsReady(replications) {
+	// Set to Ready condition, objects may be left pending once the nodes are busy.
+	if apimeta.IsStatusConditionTrue(torrent.Status.Conditions, api.ReplicateConditionType) && replicationsReady(replications) && !chunksPending(torrent) {
 		condition := metav1.Condition{
 			Type:    api.ReadyConditionType,
 			Status:  metav1.ConditionTrue,
@@ -380,6 +715,57 @@ func setTorrentCondition(torrent *api.Torrent, replications []api.Replication) (
 	return false
 }
 
+// setTorrentProgress aggregates the progress of the Torrent, chunks cached in the nodes are
+// regarded as ready, the bytes transferred by the unfinished Replications are regarded as in-flight.
+func setTorrentProgress(torrent *api.Torrent, replications []api.Replication, chunkNodes func(string) []string) (changed bool) {
+	if torrent.Status.Repo == nil {
+		return false
+	}
+
+	replicas := int64(1)
+	if torrent.Spec.Replicas != nil {
+		replicas = int64(*torrent.Spec.Replicas)
+	}
+	ready := torrentReady(torrent)
+
+	progress := &api.TorrentProgress{TotalObjects: int32(len(torrent.Status.Repo.Objects))}
+	for _, obj := range torrent.Status.Repo.Objects {
+		objectReady := true
+		for _, chunk := range obj.Chunks {
+			progress.TotalBytes += chunk.SizeBytes * replicas
+
+			readyReplicas := replicas
+			if !ready {
+				readyReplicas = min(int64(len(chunkNodes(chunk.Name))), replicas)
+			}
+			progress.ReadyBytes += chunk.SizeBytes * readyReplicas
+			if readyReplicas < replicas {
+				objectReady = false
+			}
+		}
+		if objectReady {
+			progress.ReadyObjects += 1
+		}
+	}
+
+	if !ready {
+		for _, replication := range replications {
+			if replication.Spec.Destination == nil || replication.Status.Progress == nil ||
+				apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.ReadyConditionType) ||
+				apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.FailedConditionType) {
+				continue
+			}
+			progress.InFlightBytes += replication.Status.Progress.TransferredBytes
+		}
+	}
+
+	if torrent.Status.Progress != nil && *torrent.Status.Progress == *progress {
+		return false
+	}
+	torrent.Status.Progress = progress
+	return true
+}
+
 func setTorrentConditionTo(torrent *api.Torrent, condition metav1.Condition) (changed bool) {
 	torrent.Status.Phase = ptr.To[string](condition.Type)
 	return apimeta.SetStatusCondition(&torrent.Status.Conditions, condition)
@@ -404,6 +790,17 @@ func replicationsReady(replications []api.Replication) bool {
 	return true
 }
 
+func chunksPending(torrent *api.Torrent) bool {
+	for _, obj := range torrent.Status.Repo.Objects {
+		for _, chunk := range obj.Chunks {
+			if chunk.State == api.PendingTrackerState {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func torrentReady(torrent *api.Torrent) bool {
 	return apimeta.IsStatusConditionTrue(torrent.Status.Conditions, api.ReadyConditionType)
 }
@@ -412,51 +809,52 @@ func torrentDeleting(torrent *api.Torrent) bool {
 	return !torrent.DeletionTimestamp.IsZero()
 }
 
-// We have one chunk for one file for now.
-func constructRepoStatus(torrent *api.Torrent, objects []*util.ObjectBody) {
+// Objects larger than the chunkSize will be split into several chunks.
+func constructRepoStatus(torrent *api.Torrent, objects []*hub.ObjectBody, chunkSize int64) {
 	repo := &api.RepoStatus{}
 
-	if torrent.Spec.Hub.Filename != nil {
-		// The repo could contain multiple objects(files) in the same directory, but
-		// we only need one file.
-		for _, obj := range objects {
-			if obj.Path == *torrent.Spec.Hub.Filename {
-				chunks := []api.ChunkStatus{}
-				chunks = append(chunks, api.ChunkStatus{
-					// TODO: Each file only has one chunk for now.
-					Name:      fmt.Sprintf("%s--0001", obj.Oid),
-					State:     api.PendingTrackerState,
-					SizeBytes: obj.Size,
-				})
-				repo.Objects = []api.ObjectStatus{
-					{
-						Path:   obj.Path,
-						Type:   api.ObjectType(obj.Type),
-						Chunks: chunks,
-					},
-				}
-				break
-			}
-		}
-	} else {
-		for _, obj := range objects {
-			chunks := []api.ChunkStatus{}
-			chunks = append(chunks, api.ChunkStatus{
-				// TODO: Each file only has one chunk for now.
-				Name:      fmt.Sprintf("%s--0001", obj.Oid),
-				State:     api.PendingTrackerState,
-				SizeBytes: obj.Size,
-			})
-			repo.Objects = append(repo.Objects, api.ObjectStatus{
-				Path:   obj.Path,
-				Type:   api.ObjectType(obj.Type),
-				Chunks: chunks,
-			})
+	for _, obj := range objects {
+		if !objectMatched(torrent, obj) {
+			continue
 		}
+		repo.Objects = append(repo.Objects, api.ObjectStatus{
+			Path:   obj.Path,
+			Type:   api.ObjectType(obj.Type),
+			Chunks: constructChunks(obj, chunkSize),
+			Digest: obj.Digest,
+		})
+		repo.MatchedObjects += 1
+		repo.MatchedBytes += obj.Size
 	}
 	torrent.Status.Repo = repo
 }
 
+// objectMatched returns whether the object should be replicated, the repo could contain multiple
+// objects(files), e.g. all kinds of quantized models, but we may only need some of them.
+func objectMatched(torrent *api.Torrent, obj *hub.ObjectBody) bool {
+	if torrent.Spec.Hub == nil {
+		return true
+	}
+	if torrent.Spec.Hub.Filename != nil {
+		return obj.Path == *torrent.Spec.Hub.Filename
+	}
+	return util.MatchPatterns(obj.Path, torrent.Spec.Hub.AllowPatterns, torrent.Spec.Hub.IgnorePatterns)
+}
+
+func constructChunks(obj *hub.ObjectBody, chunkSize int64) []api.ChunkStatus {
+	sizes := cons.ChunkSizes(obj.Size, chunkSize)
+
+	chunks := make([]api.ChunkStatus, 0, len(sizes))
+	for i, size := range sizes {
+		chunks = append(chunks, api.ChunkStatus{
+			Name:      cons.ChunkName(obj.Oid, i, len(sizes)),
+			State:     api.PendingTrackerState,
+			SizeBytes: size,
+		})
+	}
+	return chunks
+}
+
 func callback(ctx context.Context, cli client.Client, torrent *api.Torrent) error {
 	splits := strings.Split(torrent.Annotations[api.ParentPodNameAnnoKey], "/")
 	if len(splits) != 2 {
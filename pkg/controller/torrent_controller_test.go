@@ -0,0 +1,402 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/hub"
+	"github.com/inftyai/manta/test/util/wrapper"
+)
+
+func TestRescheduleFailedReplications(t *testing.T) {
+	failed := []metav1.Condition{{Type: api.FailedConditionType, Status: metav1.ConditionTrue}}
+	replications := []api.Replication{
+		*wrapper.MakeReplication("chunk2--node1").NodeName("node1").ChunkName("chunk2--0002").DestinationOfURI("localhost:///workspace/models/blobs/chunk2--0002").Obj(),
+		*wrapper.MakeReplication("chunk3--node1").NodeName("node1").ChunkName("chunk3--0001").DestinationOfURI("localhost:///workspace/models/blobs/chunk3--0001").Obj(),
+		// The deletion Replications are not rescheduled.
+		*wrapper.MakeReplication("chunk3--node2").NodeName("node2").ChunkName("chunk3--0001").Obj(),
+	}
+	replications[0].Status.Conditions = failed
+	replications[2].Status.Conditions = failed
+
+	torrent := wrapper.MakeTorrent("torrent").Obj()
+	torrent.Status.Repo = &api.RepoStatus{
+		Objects: []api.ObjectStatus{
+			{
+				Path: "model.safetensors",
+				Chunks: []api.ChunkStatus{
+					{Name: "chunk1--0002", State: api.ReadyTrackerState},
+					{Name: "chunk2--0002", State: api.ReadyTrackerState},
+				},
+				FailedNodeNames: []string{"node3"},
+			},
+			{
+				Path:   "LICENSE",
+				Chunks: []api.ChunkStatus{{Name: "chunk3--0001", State: api.ReadyTrackerState}},
+			},
+		},
+	}
+
+	got := rescheduleFailedReplications(torrent, replications)
+	if len(got) != 1 || got[0].Name != "chunk2--node1" {
+		t.Fatalf("unexpected failed replications: %v", got)
+	}
+
+	want := []api.ObjectStatus{
+		{
+			Path: "model.safetensors",
+			Chunks: []api.ChunkStatus{
+				{Name: "chunk1--0002", State: api.PendingTrackerState},
+				{Name: "chunk2--0002", State: api.PendingTrackerState},
+			},
+			FailedNodeNames: []string{"node3", "node1"},
+		},
+		{
+			Path:   "LICENSE",
+			Chunks: []api.ChunkStatus{{Name: "chunk3--0001", State: api.ReadyTrackerState}},
+		},
+	}
+	if diff := cmp.Diff(want, torrent.Status.Repo.Objects); diff != "" {
+		t.Errorf("unexpected objects (-want +got): %s", diff)
+	}
+}
+
+func TestSetTorrentProgress(t *testing.T) {
+	chunkNodes := map[string][]string{
+		"chunk1--0002": {"node1", "node2"},
+		"chunk2--0002": {"node1"},
+		"chunk3--0001": {"node1", "node2", "node3"},
+	}
+	inProgress := func(name, chunkName string, transferred int64, conditionType string) api.Replication {
+		replication := wrapper.MakeReplication(name).ChunkName(chunkName).DestinationOfURI("localhost:///workspace/models/blobs/" + chunkName).Obj()
+		replication.Status.Conditions = []metav1.Condition{{Type: conditionType, Status: metav1.ConditionTrue}}
+		replication.Status.Progress = &api.ReplicationProgress{TransferredBytes: transferred}
+		return *replication
+	}
+
+	testCases := []struct {
+		name         string
+		ready        bool
+		replications []api.Replication
+		want         *api.TorrentProgress
+	}{
+		{
+			name: "replicating",
+			replications: []api.Replication{
+				inProgress("chunk2--node2", "chunk2--0002", 30, api.ReplicateConditionType),
+				inProgress("chunk1--node2", "chunk1--0002", 100, api.ReadyConditionType),
+				inProgress("chunk2--node3", "chunk2--0002", 10, api.FailedConditionType),
+			},
+			want: &api.TorrentProgress{
+				TotalBytes:    400,
+				ReadyBytes:    350,
+				InFlightBytes: 30,
+				TotalObjects:  2,
+				ReadyObjects:  1,
+			},
+		},
+		{
+			name:  "ready",
+			ready: true,
+			replications: []api.Replication{
+				inProgress("chunk2--node2", "chunk2--0002", 30, api.ReplicateConditionType),
+			},
+			want: &api.TorrentProgress{
+				TotalBytes:   400,
+				ReadyBytes:   400,
+				TotalObjects: 2,
+				ReadyObjects: 2,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			torrent := wrapper.MakeTorrent("torrent").Replicas(2).Obj()
+			torrent.Status.Repo = &api.RepoStatus{
+				Objects: []api.ObjectStatus{
+					{
+						Path: "model.safetensors",
+						Chunks: []api.ChunkStatus{
+							{Name: "chunk1--0002", SizeBytes: 100},
+							{Name: "chunk2--0002", SizeBytes: 50},
+						},
+					},
+					{
+						Path:   "LICENSE",
+						Chunks: []api.ChunkStatus{{Name: "chunk3--0001", SizeBytes: 50}},
+					},
+				},
+			}
+			if tc.ready {
+				torrent.Status.Conditions = []metav1.Condition{{Type: api.ReadyConditionType, Status: metav1.ConditionTrue}}
+			}
+
+			if !setTorrentProgress(torrent, tc.replications, func(name string) []string { return chunkNodes[name] }) {
+				t.Fatal("progress should be changed")
+			}
+			if diff := cmp.Diff(tc.want, torrent.Status.Progress); diff != "" {
+				t.Errorf("unexpected progress (-want +got): %s", diff)
+			}
+			if setTorrentProgress(torrent, tc.replications, func(name string) []string { return chunkNodes[name] }) {
+				t.Error("progress should not be changed once aggregated")
+			}
+		})
+	}
+}
+
+func TestRolloutRepoStatus(t *testing.T) {
+	torrent := wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj()
+	torrent.Status.Repo = &api.RepoStatus{
+		Objects: []api.ObjectStatus{
+			{
+				Path:            "config.json",
+				Chunks:          []api.ChunkStatus{{Name: "oid1--0001", State: api.ReadyTrackerState, SizeBytes: 10}},
+				FailedNodeNames: []string{"node1"},
+			},
+			{
+				Path:            "model.safetensors",
+				Chunks:          []api.ChunkStatus{{Name: "oid2--0001", State: api.ReadyTrackerState, SizeBytes: 20}},
+				FailedNodeNames: []string{"node2"},
+			},
+		},
+		Commit: "commit1",
+	}
+	torrent.Status.Conditions = []metav1.Condition{
+		{Type: api.ReplicateConditionType, Status: metav1.ConditionTrue},
+		{Type: api.ReadyConditionType, Status: metav1.ConditionTrue},
+	}
+
+	objects := []*hub.ObjectBody{
+		{Path: "config.json", Type: "file", Oid: "oid1", Size: 10},
+		{Path: "model.safetensors", Type: "file", Oid: "oid3", Size: 30},
+	}
+	rolloutRepoStatus(torrent, objects, "commit2", 0)
+
+	want := &api.RepoStatus{
+		Objects: []api.ObjectStatus{
+			{
+				Path:            "config.json",
+				Type:            api.FileObjectType,
+				Chunks:          []api.ChunkStatus{{Name: "oid1--0001", State: api.PendingTrackerState, SizeBytes: 10}},
+				FailedNodeNames: []string{"node1"},
+			},
+			{
+				Path:   "model.safetensors",
+				Type:   api.FileObjectType,
+				Chunks: []api.ChunkStatus{{Name: "oid3--0001", State: api.PendingTrackerState, SizeBytes: 30}},
+			},
+		},
+		Commit:           "commit2",
+		MatchedObjects:   2,
+		MatchedBytes:     40,
+		SupersededChunks: []string{"oid2--0001"},
+	}
+	if diff := cmp.Diff(want, torrent.Status.Repo); diff != "" {
+		t.Errorf("unexpected repo status (-want +got): %s", diff)
+	}
+	if torrentReady(torrent) || *torrent.Status.Phase != api.PendingConditionType {
+		t.Errorf("torrent should be pending once rolling out, conditions: %v", torrent.Status.Conditions)
+	}
+
+	// Rolled back before Ready, the chunk referred again is no longer superseded.
+	objects = []*hub.ObjectBody{
+		{Path: "config.json", Type: "file", Oid: "oid1", Size: 10},
+		{Path: "model.safetensors", Type: "file", Oid: "oid2", Size: 20},
+	}
+	rolloutRepoStatus(torrent, objects, "commit1", 0)
+	if diff := cmp.Diff([]string{"oid3--0001"}, torrent.Status.Repo.SupersededChunks); diff != "" {
+		t.Errorf("unexpected superseded chunks (-want +got): %s", diff)
+	}
+}
+
+func TestUpdateCheckEnabled(t *testing.T) {
+	testCases := []struct {
+		name    string
+		torrent *api.Torrent
+		commit  string
+		want    bool
+	}{
+		{
+			name:    "updatePolicy set",
+			torrent: wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").UpdatePolicy(3600, false).Obj(),
+			commit:  "f2826a00ceef68f0f2b946d945ecc0477ce4450c",
+			want:    true,
+		},
+		{
+			name:    "updatePolicy not set",
+			torrent: wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj(),
+			commit:  "f2826a00ceef68f0f2b946d945ecc0477ce4450c",
+		},
+		{
+			name:    "revision not resolved",
+			torrent: wrapper.MakeTorrent("torrent").Hub("ModelScope", "Qwen/Qwen2-7B-Instruct", "").UpdatePolicy(3600, false).Obj(),
+			commit:  "main",
+		},
+		{
+			name:    "created by the older versions",
+			torrent: wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").UpdatePolicy(3600, false).Obj(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.torrent.Spec.Hub.Revision = ptr.To[string]("main")
+			tc.torrent.Status.Repo = &api.RepoStatus{Commit: tc.commit}
+			if got := updateCheckEnabled(tc.torrent); got != tc.want {
+				t.Errorf("unexpected result, want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLastUpdateCheckTime(t *testing.T) {
+	readyTime := metav1.NewTime(time.Now().Add(-time.Hour))
+	checkTime := metav1.NewTime(time.Now())
+
+	torrent := wrapper.MakeTorrent("torrent").Obj()
+	torrent.Status.Conditions = []metav1.Condition{{Type: api.ReadyConditionType, Status: metav1.ConditionTrue, LastTransitionTime: readyTime}}
+	if got := lastUpdateCheckTime(torrent); !got.Equal(readyTime.Time) {
+		t.Errorf("should default to the ready time, got %v", got)
+	}
+
+	torrent.Status.LastUpdateCheckTime = &checkTime
+	if got := lastUpdateCheckTime(torrent); !got.Equal(checkTime.Time) {
+		t.Errorf("unexpected last update check time, want %v, got %v", checkTime, got)
+	}
+}
+
+func TestExpireTime(t *testing.T) {
+	readyTime := metav1.NewTime(time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC))
+
+	testCases := []struct {
+		name     string
+		spec     string
+		wantTime time.Time
+	}{
+		{
+			name:     "expire right after ready",
+			spec:     `{"ttlSecondsAfterReady":0}`,
+			wantTime: readyTime.Time,
+		},
+		{
+			name:     "expire one hour after ready",
+			spec:     `{"ttlSecondsAfterReady":3600}`,
+			wantTime: readyTime.Add(time.Hour),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			torrent := wrapper.MakeTorrent("torrent").Obj()
+			// Decode from the raw spec, the same as the apiserver does.
+			if err := json.Unmarshal([]byte(tc.spec), &torrent.Spec); err != nil {
+				t.Fatal(err)
+			}
+			torrent.Status.Conditions = []metav1.Condition{{Type: api.ReadyConditionType, Status: metav1.ConditionTrue, LastTransitionTime: readyTime}}
+
+			if got := expireTime(torrent); !got.Time.Equal(tc.wantTime) {
+				t.Errorf("unexpected expire time, want %v, got %v", tc.wantTime, got)
+			}
+		})
+	}
+}
+
+func TestConstructRepoStatus(t *testing.T) {
+	objects := []*hub.ObjectBody{
+		{Path: "config.json", Type: "file", Oid: "oid1", Size: 10},
+		{Path: "model.safetensors", Type: "file", Oid: "oid2", Size: 100},
+		{Path: "pytorch_model.bin", Type: "file", Oid: "oid3", Size: 100},
+		{Path: "onnx/model.onnx", Type: "file", Oid: "oid4", Size: 100},
+	}
+
+	testCases := []struct {
+		name           string
+		torrent        *api.Torrent
+		allowPatterns  []string
+		ignorePatterns []string
+		wantPaths      []string
+		wantBytes      int64
+	}{
+		{
+			name:      "whole repo",
+			torrent:   wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj(),
+			wantPaths: []string{"config.json", "model.safetensors", "pytorch_model.bin", "onnx/model.onnx"},
+			wantBytes: 310,
+		},
+		{
+			name:      "one file",
+			torrent:   wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "model.safetensors").Obj(),
+			wantPaths: []string{"model.safetensors"},
+			wantBytes: 100,
+		},
+		{
+			name:          "allow patterns",
+			torrent:       wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj(),
+			allowPatterns: []string{"*.json", "*.safetensors"},
+			wantPaths:     []string{"config.json", "model.safetensors"},
+			wantBytes:     110,
+		},
+		{
+			name:           "ignore patterns",
+			torrent:        wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj(),
+			ignorePatterns: []string{"*.bin", "onnx/"},
+			wantPaths:      []string{"config.json", "model.safetensors"},
+			wantBytes:      110,
+		},
+		{
+			name:          "nothing matched",
+			torrent:       wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj(),
+			allowPatterns: []string{"*.gguf"},
+		},
+		{
+			name:      "patterns not applied to uri",
+			torrent:   wrapper.MakeTorrent("torrent").URI("s3://models/Qwen/Qwen2-7B-Instruct").Obj(),
+			wantPaths: []string{"config.json", "model.safetensors", "pytorch_model.bin", "onnx/model.onnx"},
+			wantBytes: 310,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.torrent.Spec.Hub != nil {
+				tc.torrent.Spec.Hub.AllowPatterns = tc.allowPatterns
+				tc.torrent.Spec.Hub.IgnorePatterns = tc.ignorePatterns
+			}
+			constructRepoStatus(tc.torrent, objects, 0)
+
+			var gotPaths []string
+			for _, obj := range tc.torrent.Status.Repo.Objects {
+				gotPaths = append(gotPaths, obj.Path)
+			}
+			if diff := cmp.Diff(tc.wantPaths, gotPaths); diff != "" {
+				t.Errorf("unexpected objects (-want +got): %s", diff)
+			}
+			if tc.torrent.Status.Repo.MatchedObjects != int32(len(tc.wantPaths)) || tc.torrent.Status.Repo.MatchedBytes != tc.wantBytes {
+				t.Errorf("unexpected matched objects %d and bytes %d", tc.torrent.Status.Repo.MatchedObjects, tc.torrent.Status.Repo.MatchedBytes)
+			}
+		})
+	}
+}
@@ -19,4 +19,9 @@ package pkg
 const (
 	PREHEAT_IMAGE          = "inftyai/manta-preheat:v0.0.1"
 	PREHEAT_CONTAINER_NAME = "preheat"
+
+	// MANTA_NAMESPACE is the namespace Manta is deployed in, the Secrets referred by
+	// Torrents and Replications should live in it, because the controller and the agents
+	// are only allowed to get the Secrets of this namespace.
+	MANTA_NAMESPACE = "manta-system"
 )
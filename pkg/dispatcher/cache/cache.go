@@ -30,6 +30,12 @@ type Cache struct {
 	chunks map[string]*ChunkInfo
 	// nodes with the key refers to the node name and the value refers to the chunk names it hosts.
 	nodes map[string]sets.Set[string]
+	// replications with the key refers to the in-flight Replication name and the value refers to
+	// the nodes it occupies.
+	replications map[string]replicationNodes
+	// loads with the key refers to the node name and the value refers to the in-flight Replications
+	// the node is serving or receiving.
+	loads map[string]*NodeLoad
 
 	// These fileds are only used in dispatching, will be set in snapshot.
 	// No concurrency happens in dispatching right now, so no need to consider the lock right now,
@@ -46,10 +52,25 @@ type ChunkInfo struct {
 	SizeBytes int64
 }
 
+// NodeLoad represents the number of in-flight Replications of the node.
+type NodeLoad struct {
+	// Source is the number of Replications syncing from the node, including as a peer.
+	Source int32
+	// Destination is the number of Replications downloading or syncing to the node.
+	Destination int32
+}
+
+type replicationNodes struct {
+	destination string
+	sources     []string
+}
+
 func NewCache() *Cache {
 	c := Cache{
-		chunks: make(map[string]*ChunkInfo),
-		nodes:  make(map[string]sets.Set[string]),
+		chunks:       make(map[string]*ChunkInfo),
+		nodes:        make(map[string]sets.Set[string]),
+		replications: make(map[string]replicationNodes),
+		loads:        make(map[string]*NodeLoad),
 	}
 	return &c
 }
@@ -152,14 +173,76 @@ func (c *Cache) ChunkExistInNode(nodename, chunkname string) bool {
 	return chunks.Has(chunkname)
 }
 
+// AddReplication accounts the in-flight Replication to the destination and source nodes,
+// it's idempotent for the same Replication name.
+func (c *Cache) AddReplication(name string, destination string, sources []string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.replications[name]; ok {
+		return
+	}
+	c.replications[name] = replicationNodes{destination: destination, sources: sources}
+
+	c.nodeLoad(destination).Destination += 1
+	for _, source := range sources {
+		c.nodeLoad(source).Source += 1
+	}
+}
+
+// DeleteReplication reverts the AddReplication once the Replication is finished or deleted.
+func (c *Cache) DeleteReplication(name string) {
+	c.Lock()
+	defer c.Unlock()
+
+	nodes, ok := c.replications[name]
+	if !ok {
+		return
+	}
+	delete(c.replications, name)
+
+	c.nodeLoad(nodes.destination).Destination -= 1
+	for _, source := range nodes.sources {
+		c.nodeLoad(source).Source -= 1
+	}
+	for _, nodeName := range append([]string{nodes.destination}, nodes.sources...) {
+		if load := c.loads[nodeName]; load != nil && *load == (NodeLoad{}) {
+			delete(c.loads, nodeName)
+		}
+	}
+}
+
+// NodeLoad returns the in-flight Replications of the node.
+func (c *Cache) NodeLoad(nodename string) NodeLoad {
+	c.RLock()
+	defer c.RUnlock()
+
+	if load, ok := c.loads[nodename]; ok {
+		return *load
+	}
+	return NodeLoad{}
+}
+
+// nodeLoad should be called with the lock held.
+func (c *Cache) nodeLoad(nodename string) *NodeLoad {
+	load, ok := c.loads[nodename]
+	if !ok {
+		load = &NodeLoad{}
+		c.loads[nodename] = load
+	}
+	return load
+}
+
 // Snapshot is called before dispatching.
 func (c *Cache) Snapshot() *Cache {
 	c.Lock()
 	defer c.Unlock()
 
 	newCache := &Cache{
-		chunks: make(map[string]*ChunkInfo, len(c.chunks)),
-		nodes:  make(map[string]sets.Set[string], len(c.nodes)),
+		chunks:       make(map[string]*ChunkInfo, len(c.chunks)),
+		nodes:        make(map[string]sets.Set[string], len(c.nodes)),
+		replications: make(map[string]replicationNodes, len(c.replications)),
+		loads:        make(map[string]*NodeLoad, len(c.loads)),
 	}
 
 	for k, v := range c.chunks {
@@ -177,6 +260,15 @@ func (c *Cache) Snapshot() *Cache {
 		newCache.nodes[k] = chunkNames
 	}
 
+	for k, v := range c.replications {
+		newCache.replications[k] = v
+	}
+
+	for k, v := range c.loads {
+		load := *v
+		newCache.loads[k] = &load
+	}
+
 	newCache.state = make(map[string]interface{})
 	return newCache
 }
@@ -98,3 +98,49 @@ func TestCacheOP(t *testing.T) {
 		t.Errorf("unexpected nodes: %v", diff)
 	}
 }
+
+func TestReplicationLoad(t *testing.T) {
+	cache := NewCache()
+
+	cache.AddReplication("replication1", "node1", nil)
+	cache.AddReplication("replication2", "node2", []string{"node1", "node3"})
+	// Added repeatedly.
+	cache.AddReplication("replication2", "node2", []string{"node1", "node3"})
+
+	wantLoads := map[string]NodeLoad{
+		"node1": {Source: 1, Destination: 1},
+		"node2": {Destination: 1},
+		"node3": {Source: 1},
+		"node4": {},
+	}
+	for nodeName, want := range wantLoads {
+		if diff := cmp.Diff(want, cache.NodeLoad(nodeName)); diff != "" {
+			t.Errorf("unexpected load of %s: %v", nodeName, diff)
+		}
+	}
+
+	// Snapshot should not be affected by the origin cache.
+	newCache := cache.Snapshot()
+	cache.DeleteReplication("replication2")
+	// Deleted repeatedly.
+	cache.DeleteReplication("replication2")
+
+	wantLoads = map[string]NodeLoad{
+		"node1": {Destination: 1},
+		"node2": {},
+		"node3": {},
+	}
+	for nodeName, want := range wantLoads {
+		if diff := cmp.Diff(want, cache.NodeLoad(nodeName)); diff != "" {
+			t.Errorf("unexpected load of %s: %v", nodeName, diff)
+		}
+	}
+	if diff := cmp.Diff(NodeLoad{Destination: 1}, newCache.NodeLoad("node2")); diff != "" {
+		t.Errorf("unexpected load of snapshot: %v", diff)
+	}
+
+	cache.DeleteReplication("replication1")
+	if len(cache.loads) != 0 || len(cache.replications) != 0 {
+		t.Errorf("loads should be cleaned up, got %v", cache.loads)
+	}
+}
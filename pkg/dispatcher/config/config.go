@@ -0,0 +1,169 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// disableAll disables all the default plugins of the extension point.
+	disableAll = "*"
+)
+
+// Default returns the configuration with the default plugins only.
+func Default() *DispatcherConfiguration {
+	cfg := &DispatcherConfiguration{}
+	cfg.APIVersion, cfg.Kind = APIVersion, Kind
+	cfg.Plugins = defaultPlugins()
+	return cfg
+}
+
+func defaultPlugins() Plugins {
+	return Plugins{
+		PreFilter: PluginSet{
+			Enabled: []Plugin{
+				{Name: "NodeSelector"},
+				{Name: "NodeAffinity"},
+			},
+		},
+		Filter: PluginSet{
+			Enabled: []Plugin{
+				{Name: "NodeAvailability"},
+				{Name: "NodeSelector"},
+				{Name: "NodeAffinity"},
+				{Name: "DiskAware"},
+				{Name: "Topology"},
+				{Name: "LoadAware"},
+			},
+		},
+		Score: PluginSet{
+			Enabled: []Plugin{
+				{Name: "NodeAffinity", Weight: ptr.To[int32](1)},
+				{Name: "DiskAware", Weight: ptr.To[int32](1)},
+				{Name: "Topology", Weight: ptr.To[int32](1)},
+				{Name: "LoadAware", Weight: ptr.To[int32](1)},
+			},
+		},
+	}
+}
+
+// Load reads the configuration from the file, the plugins are merged with the default
+// ones. Once the path is empty, the default configuration will be returned.
+func Load(path string) (*DispatcherConfiguration, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &DispatcherConfiguration{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode dispatcher configuration %s: %v", path, err)
+	}
+	if cfg.APIVersion != APIVersion || cfg.Kind != Kind {
+		return nil, fmt.Errorf("unexpected dispatcher configuration %s/%s, want %s/%s", cfg.APIVersion, cfg.Kind, APIVersion, Kind)
+	}
+
+	defaults := defaultPlugins()
+	cfg.Plugins = Plugins{
+		PreFilter:  mergePluginSet(defaults.PreFilter, cfg.Plugins.PreFilter),
+		Filter:     mergePluginSet(defaults.Filter, cfg.Plugins.Filter),
+		PostFilter: mergePluginSet(defaults.PostFilter, cfg.Plugins.PostFilter),
+		Score:      mergePluginSet(defaults.Score, cfg.Plugins.Score),
+		Reserve:    mergePluginSet(defaults.Reserve, cfg.Plugins.Reserve),
+	}
+	for i := range cfg.Plugins.Score.Enabled {
+		if cfg.Plugins.Score.Enabled[i].Weight == nil {
+			cfg.Plugins.Score.Enabled[i].Weight = ptr.To[int32](1)
+		}
+	}
+
+	if err := validate(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// mergePluginSet removes the disabled plugins from the default ones and appends the enabled ones,
+// once the enabled plugin is a default one, it'll replace the default one in place.
+func mergePluginSet(defaults PluginSet, custom PluginSet) PluginSet {
+	disabled := map[string]bool{}
+	for _, plugin := range custom.Disabled {
+		disabled[plugin.Name] = true
+	}
+
+	enabled := map[string]Plugin{}
+	for _, plugin := range custom.Enabled {
+		enabled[plugin.Name] = plugin
+	}
+
+	merged := PluginSet{}
+	for _, plugin := range defaults.Enabled {
+		if disabled[disableAll] || disabled[plugin.Name] {
+			continue
+		}
+		if p, ok := enabled[plugin.Name]; ok {
+			plugin = p
+			delete(enabled, plugin.Name)
+		}
+		merged.Enabled = append(merged.Enabled, plugin)
+	}
+	for _, plugin := range custom.Enabled {
+		if _, ok := enabled[plugin.Name]; ok {
+			merged.Enabled = append(merged.Enabled, plugin)
+		}
+	}
+	return merged
+}
+
+func validate(cfg *DispatcherConfiguration) error {
+	for extensionPoint, set := range map[string]PluginSet{
+		"preFilter":  cfg.Plugins.PreFilter,
+		"filter":     cfg.Plugins.Filter,
+		"postFilter": cfg.Plugins.PostFilter,
+		"score":      cfg.Plugins.Score,
+		"reserve":    cfg.Plugins.Reserve,
+	} {
+		names := map[string]bool{}
+		for _, plugin := range set.Enabled {
+			if names[plugin.Name] {
+				return fmt.Errorf("plugin %s is enabled more than once at %s", plugin.Name, extensionPoint)
+			}
+			names[plugin.Name] = true
+			if plugin.Weight != nil && *plugin.Weight <= 0 {
+				return fmt.Errorf("weight of plugin %s must be positive, got %d", plugin.Name, *plugin.Weight)
+			}
+		}
+	}
+
+	names := map[string]bool{}
+	for _, pc := range cfg.PluginConfig {
+		if names[pc.Name] {
+			return fmt.Errorf("plugin %s is configured more than once", pc.Name)
+		}
+		names[pc.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/utils/ptr"
+)
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		wantError   bool
+		wantPlugins Plugins
+	}{
+		{
+			name: "default plugins",
+			content: `
+apiVersion: config.manta.io/v1alpha1
+kind: DispatcherConfiguration
+`,
+			wantPlugins: defaultPlugins(),
+		},
+		{
+			name: "enable and disable plugins",
+			content: `
+apiVersion: config.manta.io/v1alpha1
+kind: DispatcherConfiguration
+plugins:
+  filter:
+    enabled:
+    - name: Foo
+    disabled:
+    - name: NodeSelector
+  score:
+    enabled:
+    - name: Foo
+    - name: DiskAware
+      weight: 3
+`,
+			wantPlugins: Plugins{
+				PreFilter: defaultPlugins().PreFilter,
+				Filter: PluginSet{
+					Enabled: []Plugin{{Name: "NodeAvailability"}, {Name: "NodeAffinity"}, {Name: "DiskAware"}, {Name: "Topology"}, {Name: "LoadAware"}, {Name: "Foo"}},
+				},
+				Score: PluginSet{
+					Enabled: []Plugin{{Name: "NodeAffinity", Weight: ptr.To[int32](1)}, {Name: "DiskAware", Weight: ptr.To[int32](3)}, {Name: "Topology", Weight: ptr.To[int32](1)}, {Name: "LoadAware", Weight: ptr.To[int32](1)}, {Name: "Foo", Weight: ptr.To[int32](1)}},
+				},
+			},
+		},
+		{
+			name: "disable all default plugins",
+			content: `
+apiVersion: config.manta.io/v1alpha1
+kind: DispatcherConfiguration
+plugins:
+  score:
+    enabled:
+    - name: Foo
+      weight: 2
+    disabled:
+    - name: "*"
+`,
+			wantPlugins: Plugins{
+				PreFilter: defaultPlugins().PreFilter,
+				Filter:    defaultPlugins().Filter,
+				Score: PluginSet{
+					Enabled: []Plugin{{Name: "Foo", Weight: ptr.To[int32](2)}},
+				},
+			},
+		},
+		{
+			name: "unknown apiVersion",
+			content: `
+apiVersion: config.manta.io/v1
+kind: DispatcherConfiguration
+`,
+			wantError: true,
+		},
+		{
+			name: "unknown field",
+			content: `
+apiVersion: config.manta.io/v1alpha1
+kind: DispatcherConfiguration
+profiles: []
+`,
+			wantError: true,
+		},
+		{
+			name: "non-positive weight",
+			content: `
+apiVersion: config.manta.io/v1alpha1
+kind: DispatcherConfiguration
+plugins:
+  score:
+    enabled:
+    - name: DiskAware
+      weight: 0
+`,
+			wantError: true,
+		},
+		{
+			name: "plugin enabled twice",
+			content: `
+apiVersion: config.manta.io/v1alpha1
+kind: DispatcherConfiguration
+plugins:
+  filter:
+    enabled:
+    - name: Foo
+    - name: Foo
+`,
+			wantError: true,
+		},
+		{
+			name: "plugin configured twice",
+			content: `
+apiVersion: config.manta.io/v1alpha1
+kind: DispatcherConfiguration
+pluginConfig:
+- name: DiskAware
+- name: DiskAware
+`,
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := "../../../tmp/config/dispatcher-config.yaml"
+			defer func() {
+				_ = os.RemoveAll("../../../tmp/config")
+			}()
+			if err := os.MkdirAll("../../../tmp/config", 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path)
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("expected error here")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantPlugins, cfg.Plugins, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("unexpected plugins (-want +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(Default(), cfg); diff != "" {
+		t.Errorf("unexpected configuration (-want +got): %s", diff)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	APIVersion = "config.manta.io/v1alpha1"
+	Kind       = "DispatcherConfiguration"
+)
+
+// DispatcherConfiguration configures the dispatcher, in the spirit of the KubeSchedulerConfiguration,
+// which decides what plugins to run at each extension point and how.
+type DispatcherConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Plugins specifies the plugins to enable or disable at each extension point,
+	// they're merged with the default plugins.
+	// +optional
+	Plugins Plugins `json:"plugins,omitempty"`
+	// PluginConfig is a set of arguments for the plugins, each plugin could have at most one.
+	// +optional
+	PluginConfig []PluginConfig `json:"pluginConfig,omitempty"`
+}
+
+// Plugins includes the plugins of each extension point. Plugins run in the configured order,
+// the default ones first, then the enabled ones.
+type Plugins struct {
+	// PreFilter is a list of plugins running once per chunk before filtering.
+	// +optional
+	PreFilter PluginSet `json:"preFilter,omitempty"`
+	// Filter is a list of plugins filtering out the unqualified nodes.
+	// +optional
+	Filter PluginSet `json:"filter,omitempty"`
+	// PostFilter is a list of plugins running once no nodes pass the filter.
+	// +optional
+	PostFilter PluginSet `json:"postFilter,omitempty"`
+	// Score is a list of plugins ranking the nodes passed the filter.
+	// +optional
+	Score PluginSet `json:"score,omitempty"`
+	// Reserve is a list of plugins accounting for the chunks dispatched.
+	// +optional
+	Reserve PluginSet `json:"reserve,omitempty"`
+}
+
+// PluginSet specifies the enabled and disabled plugins of one extension point.
+type PluginSet struct {
+	// Enabled specifies the plugins to run besides the default ones, once the plugin
+	// is already a default one, only its weight is updated.
+	// +optional
+	Enabled []Plugin `json:"enabled,omitempty"`
+	// Disabled specifies the default plugins not to run, "*" disables all of them.
+	// +optional
+	Disabled []Plugin `json:"disabled,omitempty"`
+}
+
+// Plugin specifies a plugin name and its weight.
+type Plugin struct {
+	// Name is the name of the plugin.
+	Name string `json:"name"`
+	// Weight is the weight of the plugin, only works with the score plugins, default to 1.
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+// PluginConfig specifies the arguments passed to the plugin once initialized.
+type PluginConfig struct {
+	// Name is the name of the plugin.
+	Name string `json:"name"`
+	// Args is the arguments of the plugin, the format is defined by the plugin.
+	Args runtime.RawExtension `json:"args,omitempty"`
+}
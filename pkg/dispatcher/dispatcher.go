@@ -22,7 +22,10 @@ import (
 	"sort"
 	"strings"
 
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -30,7 +33,11 @@ import (
 	cons "github.com/inftyai/manta/api"
 	api "github.com/inftyai/manta/api/v1alpha1"
 	"github.com/inftyai/manta/pkg/dispatcher/cache"
+	"github.com/inftyai/manta/pkg/dispatcher/config"
 	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/diskaware"
+	"github.com/inftyai/manta/pkg/objectstore"
+	"github.com/inftyai/manta/pkg/registry"
 	"github.com/inftyai/manta/pkg/util"
 )
 
@@ -39,27 +46,53 @@ const (
 	remote        = api.URI_REMOTE + "://"
 	workspace     = cons.DefaultWorkspace
 	labelHostname = "kubernetes.io/hostname"
+
+	// maxSyncSources is the maximum number of source nodes one chunk is synced from.
+	maxSyncSources = 4
 )
 
 type Dispatcher struct {
 	cache *cache.Cache
-	framework.DefaultFramework
+	*framework.DefaultFramework
+	// diskAware shares the args with the DiskAware plugin, so the evictions agree with the
+	// dispatching about the size limits of the nodes, even the plugin is disabled.
+	diskAware *diskaware.DiskAware
 }
 
-func NewDispatcher(plugins []framework.RegisterFunc) (*Dispatcher, error) {
-	dispatcher := &Dispatcher{
-		cache: cache.NewCache(),
+// NewDispatcher initializes the plugins from the registry as configured.
+func NewDispatcher(registry framework.Registry, cfg *config.DispatcherConfiguration) (*Dispatcher, error) {
+	fwk, err := framework.NewFramework(registry, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var args *runtime.RawExtension
+	for i := range cfg.PluginConfig {
+		if cfg.PluginConfig[i].Name == diskaware.Name {
+			args = &cfg.PluginConfig[i].Args
+		}
 	}
-	if err := dispatcher.RegisterPlugins(plugins); err != nil {
+	diskAware, err := diskaware.New(args)
+	if err != nil {
 		return nil, err
 	}
-	return dispatcher, nil
+
+	return &Dispatcher{
+		cache:            cache.NewCache(),
+		DefaultFramework: fwk,
+		diskAware:        diskAware.(*diskaware.DiskAware),
+	}, nil
 }
 
 func (d *Dispatcher) snapshot() *cache.Cache {
 	return d.cache.Snapshot()
 }
 
+// ChunkNodes returns the nodes with the chunk cached.
+func (d *Dispatcher) ChunkNodes(chunkName string) []string {
+	return d.cache.ChunkNodes(chunkName)
+}
+
 // PrepareReplications will construct the replications needed to created and
 // update the torrent status the same time.
 // This function must be idempotent or we'll create duplicated replications.
@@ -70,47 +103,124 @@ func (d *Dispatcher) PrepareReplications(ctx context.Context, torrent *api.Torre
 		return nil, false, false, fmt.Errorf("repo is nil, couldn't dispatch chunks")
 	}
 
+	logger := log.FromContext(ctx)
+
 	// snapshot will deepcopy the cache.
 	// Note: because we list the nodeTrackers before so there maybe a bit difference
 	// between cache and nodeTrackers.
 	cache := d.snapshot()
 
+	// Reservations will be reverted once the dispatching failed.
+	type reservation struct {
+		chunk    framework.ChunkInfo
+		nodeName string
+	}
+	var reservations []reservation
+	defer func() {
+		if err != nil {
+			for _, r := range reservations {
+				d.RunUnreservePlugins(ctx, r.chunk, r.nodeName, cache)
+			}
+		}
+	}()
+
+	// The nodeInfos of the sync sources, which may be not in the candidate nodeTrackers.
+	nodeInfos := make(map[string]framework.NodeInfo, len(nodeTrackers))
+	for _, nt := range nodeTrackers {
+		nodeInfos[nt.Name] = framework.NodeInfo{Name: nt.Name, Labels: nt.Labels, State: nt.Status.Node}
+	}
+
 	pendingNumber := 0
+	var dispatchErr error
 	for i, obj := range torrent.Status.Repo.Objects {
+		var offset int64
+		// Chunks of the same object should be dispatched to the same nodes,
+		// or the object couldn't be assembled.
+		var objectNodeNames []string
+
+		var objectReplications []*api.Replication
+		var objectReservations []reservation
+		var readyChunks []int
+		var objectErr error
+
 		for j, chunk := range obj.Chunks {
+			chunkOffset := offset
+			offset += chunk.SizeBytes
+
 			if chunk.State != api.PendingTrackerState {
 				continue
 			}
 
 			pendingNumber += 1
 
+			if objectErr != nil {
+				continue
+			}
+
 			chunk := framework.ChunkInfo{
 				Name:         chunk.Name,
 				Size:         chunk.SizeBytes,
+				Offset:       chunkOffset,
 				Path:         obj.Path,
 				Revision:     revision(torrent),
+				Digest:       obj.Digest,
 				NodeSelector: torrent.Spec.NodeSelector,
+				NodeAffinity: torrent.Spec.NodeAffinity,
+				Tolerations:  torrent.Spec.Tolerations,
 			}
 
+			candidateTrackers := nodeTrackers
+			if len(objectNodeNames) > 0 {
+				candidateTrackers = filterNodeTrackers(nodeTrackers, objectNodeNames)
+			}
+			// The nodes failed to replicate the object are not targets anymore, but they're
+			// still in the nodeInfos as sync sources.
+			if len(obj.FailedNodeNames) > 0 {
+				candidateTrackers = excludeNodeTrackers(candidateTrackers, obj.FailedNodeNames)
+			}
+
+			var newReplications []*api.Replication
 			if d.cache.ChunkExist(chunk.Name) {
-				newReplications, err := d.schedulingSyncChunk(ctx, torrent, chunk, nodeTrackers, cache)
-				if err != nil {
-					return nil, false, false, err
-				}
-				replications = append(replications, newReplications...)
+				newReplications, objectErr = d.schedulingSyncChunk(ctx, torrent, chunk, candidateTrackers, nodeInfos, cache)
 			} else {
-				newReplications, err := d.schedulingDownloadChunk(ctx, torrent, chunk, nodeTrackers, cache)
-				if err != nil {
-					return nil, false, false, err
-				}
-				replications = append(replications, newReplications...)
+				newReplications, objectErr = d.schedulingDownloadChunk(ctx, torrent, chunk, candidateTrackers, *torrent.Spec.Replicas, cache)
 			}
+			if objectErr != nil {
+				continue
+			}
+			for _, r := range newReplications {
+				objectReservations = append(objectReservations, reservation{chunk: chunk, nodeName: r.Spec.NodeName})
+			}
+			objectReplications = append(objectReplications, newReplications...)
+
+			objectNodeNames = intersectNodeNames(objectNodeNames, cache.ChunkNodes(chunk.Name))
+			readyChunks = append(readyChunks, j)
+		}
+
+		// The object is left pending once any of its chunks is unschedulable, e.g. all the nodes
+		// are busy, it will be dispatched as a whole in the following reconciliations.
+		if objectErr != nil {
+			logger.Info("object is not dispatched, leave it pending", "Torrent", klog.KObj(torrent), "object", obj.Path, "reason", objectErr.Error())
+			for _, r := range objectReservations {
+				d.RunUnreservePlugins(ctx, r.chunk, r.nodeName, cache)
+			}
+			dispatchErr = objectErr
+			continue
+		}
 
+		reservations = append(reservations, objectReservations...)
+		replications = append(replications, objectReplications...)
+		for _, j := range readyChunks {
 			torrent.Status.Repo.Objects[i].Chunks[j].State = api.ReadyTrackerState
 			torrentStatusChanged = true
 		}
 	}
 
+	// Nothing dispatched at all.
+	if dispatchErr != nil && !torrentStatusChanged {
+		return nil, false, false, dispatchErr
+	}
+
 	// If all the object is pending, it's the first time for dispatching Replications.
 	if len(torrent.Status.Repo.Objects) == pendingNumber {
 		firstTime = true
@@ -147,55 +257,113 @@ func (d *Dispatcher) ReclaimReplications(ctx context.Context, torrent *api.Torre
 			}
 		}
 	}
+
+	// The Torrent may be deleted before the superseded chunks are reclaimed.
+	if len(torrent.Status.Repo.SupersededChunks) > 0 {
+		replications = append(replications, d.SupersededReplications(ctx, torrent)...)
+		torrent.Status.Repo.SupersededChunks = nil
+		torrentStatusChanged = true
+	}
 	return replications, torrentStatusChanged, nil
 }
 
-func (d *Dispatcher) schedulingDownloadChunk(ctx context.Context, torrent *api.Torrent, chunk framework.ChunkInfo, nodeTrackers []api.NodeTracker, cache *cache.Cache) (replications []*api.Replication, err error) {
+// SupersededReplications will create replications to delete the superseded chunks of the previous
+// commits from the nodes holding them, the caller should clear the superseded chunks afterwards.
+func (d *Dispatcher) SupersededReplications(ctx context.Context, torrent *api.Torrent) (replications []*api.Replication) {
+	logger := log.FromContext(ctx)
+
+	for _, chunkName := range torrent.Status.Repo.SupersededChunks {
+		nodeNames := d.cache.ChunkNodes(chunkName)
+		logger.Info("reclaiming superseded replications", "chunk", chunkName, "nodes", nodeNames)
+		for _, nodeName := range nodeNames {
+			replications = append(replications, buildSupersededReplication(torrent, chunkName, nodeName))
+		}
+	}
+	return replications
+}
+
+func (d *Dispatcher) schedulingDownloadChunk(ctx context.Context, torrent *api.Torrent, chunk framework.ChunkInfo, nodeTrackers []api.NodeTracker, replicas int32, cache *cache.Cache) (replications []*api.Replication, err error) {
 	logger := log.FromContext(ctx)
 	logger.Info("start to schedule download chunk", "Torrent", klog.KObj(torrent), "chunk", chunk.Name)
 
+	if status := d.RunPreFilterPlugins(ctx, chunk, cache); status.Code != framework.SuccessStatus {
+		return nil, fmt.Errorf("chunk %s is unschedulable", chunk.Name)
+	}
+
 	candidates := d.RunFilterPlugins(ctx, chunk, nil, nodeTrackers, cache)
+	if len(candidates) == 0 {
+		candidates = d.RunPostFilterPlugins(ctx, chunk, nil, nodeTrackers, cache)
+	}
 
 	if len(candidates) == 0 {
 		return nil, fmt.Errorf("no candidate available")
 	}
 
 	candidates = d.RunScorePlugins(ctx, chunk, nil, candidates, cache)
-	sort.Slice(candidates, func(i, j int) bool {
+	sort.SliceStable(candidates, func(i, j int) bool {
 		return candidates[i].Score > candidates[j].Score
 	})
 
-	if len(candidates) > int(*torrent.Spec.Replicas) {
-		candidates = candidates[0:int(*torrent.Spec.Replicas)]
-	}
-
 	// TODO: once replicas > 1, we only need to download once and sync the rest, will this be better?
 	for _, candidate := range candidates {
-		replica := buildCreationReplication(torrent, chunk, candidate.Node.Name)
+		if len(replications) >= int(replicas) {
+			break
+		}
+		// Candidates failed to reserve are skipped, the next one will be picked.
+		if status := d.RunReservePlugins(ctx, chunk, candidate.Node.Name, cache); status.Code != framework.SuccessStatus {
+			continue
+		}
+
+		var replica *api.Replication
+		if torrent.Spec.URI != nil {
+			replica = buildObjectStoreReplication(torrent, chunk, candidate.Node.Name)
+		} else {
+			replica = buildCreationReplication(torrent, chunk, candidate.Node.Name)
+		}
 		replications = append(replications, replica)
 
 		// Make sure the snapshotted cache is always updated.
 		cache.AddChunks([]api.ChunkTracker{
 			{ChunkName: replica.Spec.ChunkName, SizeBytes: replica.Spec.SizeBytes},
 		}, candidate.Node.Name)
+		addReplication(cache, replica)
+	}
+
+	if len(replications) == 0 {
+		return nil, fmt.Errorf("no candidate available")
 	}
 	return
 }
 
-func (d *Dispatcher) schedulingSyncChunk(ctx context.Context, torrent *api.Torrent, chunk framework.ChunkInfo, nodeTrackers []api.NodeTracker, cache *cache.Cache) (replications []*api.Replication, err error) {
+// schedulingSyncChunk dispatches the chunk to be synced from the nodes already have it, the nodeInfos
+// provide the labels of the source nodes. It falls back to downloading for the nodes no sources are
+// allowed to sync from, e.g. cross-region syncing is forbidden.
+func (d *Dispatcher) schedulingSyncChunk(ctx context.Context, torrent *api.Torrent, chunk framework.ChunkInfo, nodeTrackers []api.NodeTracker, nodeInfos map[string]framework.NodeInfo, cache *cache.Cache) (replications []*api.Replication, err error) {
 	logger := log.FromContext(ctx).WithValues("chunk", chunk.Name)
 	logger.Info("start to schedule sync chunk")
 
+	if status := d.RunPreFilterPlugins(ctx, chunk, cache); status.Code != framework.SuccessStatus {
+		return nil, fmt.Errorf("chunk %s is unschedulable", chunk.Name)
+	}
+
 	cachedNodeNames := cache.ChunkNodes(chunk.Name)
 	replicas := *torrent.Spec.Replicas
 
 	totalCandidates := []framework.ScoreCandidate{}
+	// The candidates already have the chunk, counted once even with several sources.
+	replicatedNodeNames := sets.New[string]()
 
 	// Once the logic becomes complex, we can use a goroutine pool here for concurrency.
 	for _, nodeName := range cachedNodeNames {
-		nodeInfo := framework.NodeInfo{Name: nodeName}
+		nodeInfo, ok := nodeInfos[nodeName]
+		if !ok {
+			nodeInfo = framework.NodeInfo{Name: nodeName}
+		}
 		// Filter out not qualified nodes.
 		candidates := d.RunFilterPlugins(ctx, chunk, &nodeInfo, nodeTrackers, cache)
+		if len(candidates) == 0 {
+			candidates = d.RunPostFilterPlugins(ctx, chunk, &nodeInfo, nodeTrackers, cache)
+		}
 
 		if len(candidates) == 0 {
 			continue
@@ -209,7 +377,7 @@ func (d *Dispatcher) schedulingSyncChunk(ctx context.Context, torrent *api.Torre
 			// Filter out already replicated nodes.
 			logger.Info("candidate node name", "value", candidate.Node.Name)
 			if util.SetContains(cachedNodeNames, candidate.Node.Name) {
-				replicas -= 1
+				replicatedNodeNames.Insert(candidate.Node.Name)
 				continue
 			}
 
@@ -218,36 +386,85 @@ func (d *Dispatcher) schedulingSyncChunk(ctx context.Context, torrent *api.Torre
 	}
 
 	// We have enough replicated nodes.
+	replicas -= int32(replicatedNodeNames.Len())
 	if replicas <= 0 {
 		logger.V(1).Info("Have enough replicas, no need to sync anymore")
 		return nil, nil
 	}
 
-	if len(totalCandidates) == 0 {
-		return nil, fmt.Errorf("no candidate available")
-	}
-
-	if len(totalCandidates) > int(replicas) {
-		sort.Slice(totalCandidates, func(i, j int) bool {
-			return totalCandidates[i].Score > totalCandidates[j].Score
-		})
-
-		totalCandidates = totalCandidates[0:replicas]
-	}
+	totalCandidates = mergeCandidates(totalCandidates)
+	sort.SliceStable(totalCandidates, func(i, j int) bool {
+		return totalCandidates[i].Score > totalCandidates[j].Score
+	})
 
 	for _, candidate := range totalCandidates {
-		replica := buildSyncReplication(torrent, chunk, candidate.SourceNodeName, candidate.CandidateNodeName)
+		if len(replications) >= int(replicas) {
+			break
+		}
+		// Candidates failed to reserve are skipped, the next one will be picked.
+		if status := d.RunReservePlugins(ctx, chunk, candidate.CandidateNodeName, cache); status.Code != framework.SuccessStatus {
+			continue
+		}
+
+		replica := buildSyncReplication(torrent, chunk, candidate.SourceNodeName, candidate.PeerNodeNames, candidate.CandidateNodeName)
 		replications = append(replications, replica)
 
 		// Make sure the snapshot cache is always updated.
 		cache.AddChunks([]api.ChunkTracker{
 			{ChunkName: replica.Spec.ChunkName, SizeBytes: replica.Spec.SizeBytes},
 		}, candidate.CandidateNodeName)
+		addReplication(cache, replica)
+	}
+
+	// The nodes with no source allowed to sync from, e.g. only sources across regions, download
+	// the chunk instead, the nodes with sources but failed to reserve still wait for syncing.
+	remaining := min(replicas, *torrent.Spec.Replicas-int32(len(cachedNodeNames))) - int32(len(replications))
+	if remaining > 0 {
+		excludedNodeNames := append([]string{}, cachedNodeNames...)
+		for _, candidate := range totalCandidates {
+			excludedNodeNames = append(excludedNodeNames, candidate.CandidateNodeName)
+		}
+		downloads, err := d.schedulingDownloadChunk(ctx, torrent, chunk, excludeNodeTrackers(nodeTrackers, excludedNodeNames), remaining, cache)
+		if err != nil {
+			logger.V(1).Info("no candidate available to fall back to download", "error", err)
+		}
+		replications = append(replications, downloads...)
 	}
 
+	if len(replications) == 0 {
+		return nil, fmt.Errorf("no candidate available")
+	}
 	return replications, nil
 }
 
+// mergeCandidates merges the candidates with the same target node into one, the source
+// node with the highest score will be the SourceNodeName, and the rest will be the peers,
+// so the chunk can be pulled from several sources in parallel.
+func mergeCandidates(candidates []framework.ScoreCandidate) []framework.ScoreCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	merged := []framework.ScoreCandidate{}
+	indexes := map[string]int{}
+	for _, candidate := range candidates {
+		index, ok := indexes[candidate.CandidateNodeName]
+		if !ok {
+			indexes[candidate.CandidateNodeName] = len(merged)
+			merged = append(merged, framework.ScoreCandidate{
+				SourceNodeName:    candidate.SourceNodeName,
+				CandidateNodeName: candidate.CandidateNodeName,
+				Score:             candidate.Score,
+			})
+			continue
+		}
+		if len(merged[index].PeerNodeNames)+1 < maxSyncSources {
+			merged[index].PeerNodeNames = append(merged[index].PeerNodeNames, candidate.SourceNodeName)
+		}
+	}
+	return merged
+}
+
 func (d *Dispatcher) UpdateNodeTracker(old *api.NodeTracker, new *api.NodeTracker) {
 	// Batch OPs to avoid lock races.
 	toDelete, toAdd := chunksDiff(old.Spec.Chunks, new.Spec.Chunks)
@@ -263,6 +480,46 @@ func (d *Dispatcher) DeleteNodeTracker(obj *api.NodeTracker) {
 	d.cache.DeleteChunks(obj.Spec.Chunks, obj.Name)
 }
 
+// AddReplication accounts the in-flight Replication to the nodes it occupies.
+func (d *Dispatcher) AddReplication(obj *api.Replication) {
+	if replicationFinished(obj) {
+		return
+	}
+	addReplication(d.cache, obj)
+}
+
+func (d *Dispatcher) UpdateReplication(old *api.Replication, new *api.Replication) {
+	if replicationFinished(new) {
+		d.cache.DeleteReplication(new.Name)
+		return
+	}
+	addReplication(d.cache, new)
+}
+
+func (d *Dispatcher) DeleteReplication(obj *api.Replication) {
+	d.cache.DeleteReplication(obj.Name)
+}
+
+// addReplication accounts the downloading or syncing Replication, the deletion ones are ignored.
+func addReplication(cache *cache.Cache, replication *api.Replication) {
+	if replication.Spec.Destination == nil {
+		return
+	}
+
+	var sources []string
+	// The source URI looks like remote://node@<path-to-your-file>
+	if uri := replication.Spec.Source.URI; uri != nil && strings.HasPrefix(*uri, remote) {
+		nodeName, _, _ := strings.Cut(strings.TrimPrefix(*uri, remote), "@")
+		sources = append([]string{nodeName}, replication.Spec.Source.PeerNodeNames...)
+	}
+	cache.AddReplication(replication.Name, replication.Spec.NodeName, sources)
+}
+
+func replicationFinished(replication *api.Replication) bool {
+	return apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.ReadyConditionType) ||
+		apimeta.IsStatusConditionTrue(replication.Status.Conditions, api.FailedConditionType)
+}
+
 // toDelete includes chunk in old but not in new,
 // toAdd includes chunk in new but not in old.
 func chunksDiff(old []api.ChunkTracker, new []api.ChunkTracker) (toDelete []api.ChunkTracker, toAdd []api.ChunkTracker) {
@@ -289,8 +546,45 @@ func chunkIn(chunks []api.ChunkTracker, chunk api.ChunkTracker) bool {
 	return false
 }
 
+// intersectNodeNames returns the node names both in old and new, once old is empty,
+// new will be returned directly.
+func intersectNodeNames(old []string, new []string) []string {
+	if len(old) == 0 {
+		return new
+	}
+
+	nodeNames := []string{}
+	for _, name := range new {
+		if util.SetContains(old, name) {
+			nodeNames = append(nodeNames, name)
+		}
+	}
+	return nodeNames
+}
+
+func filterNodeTrackers(nodeTrackers []api.NodeTracker, nodeNames []string) []api.NodeTracker {
+	filtered := []api.NodeTracker{}
+	for _, nt := range nodeTrackers {
+		if util.SetContains(nodeNames, nt.Name) {
+			filtered = append(filtered, nt)
+		}
+	}
+	return filtered
+}
+
+// excludeNodeTrackers is the opposite of filterNodeTrackers.
+func excludeNodeTrackers(nodeTrackers []api.NodeTracker, nodeNames []string) []api.NodeTracker {
+	excluded := []api.NodeTracker{}
+	for _, nt := range nodeTrackers {
+		if !util.SetContains(nodeNames, nt.Name) {
+			excluded = append(excluded, nt)
+		}
+	}
+	return excluded
+}
+
 func buildCreationReplication(torrent *api.Torrent, chunk framework.ChunkInfo, nodeName string) *api.Replication {
-	repoName := hubRepoName(torrent.Spec.Hub)
+	repoName := repoName(torrent)
 	generatedName := util.GenerateName(nodeName)
 	name := chunk.Name + "--" + generatedName
 
@@ -319,25 +613,44 @@ func buildCreationReplication(torrent *api.Torrent, chunk framework.ChunkInfo, n
 			NodeName:  nodeName,
 			ChunkName: chunk.Name,
 			Source: api.Target{
-				// TODO: once we support loading files from s3, we should change the logic here.
 				Hub: &api.Hub{
-					Name:   torrent.Spec.Hub.Name,
-					RepoID: torrent.Spec.Hub.RepoID,
-					// TODO: support multiple chunks for one file in the future.
+					Name:     torrent.Spec.Hub.Name,
+					RepoID:   torrent.Spec.Hub.RepoID,
 					Filename: &chunk.Path,
-					Revision: torrent.Spec.Hub.Revision,
+					Revision: ptr.To[string](commit(torrent)),
+					// Only the reference is passed, the agent reads the token from the Secret.
+					TokenSecretRef: torrent.Spec.Hub.TokenSecretRef,
 				},
 			},
+			// The snapshot is named after the revision rather than the commit, so the chunks
+			// not changed could be reused once the revision points to a new commit.
 			Destination: &api.Target{
-				URI: ptr.To[string](localhost + workspace + repoName + "/blobs/" + chunk.Name),
+				URI: ptr.To[string](localhost + workspace + repoName + "/snapshots/" + chunk.Revision + "/" + chunk.Path),
 			},
-			SizeBytes: chunk.Size,
+			SizeBytes:    chunk.Size,
+			OffsetBytes:  chunk.Offset,
+			ObjectDigest: chunk.Digest,
 		},
 	}
 }
 
-func buildSyncReplication(torrent *api.Torrent, chunk framework.ChunkInfo, sourceName string, targetName string) *api.Replication {
-	repoName := hubRepoName(torrent.Spec.Hub)
+// buildObjectStoreReplication builds the Replication downloading from the object storage
+// or the image registry, the destination refers to the snapshot path because the object
+// uri has no revision.
+func buildObjectStoreReplication(torrent *api.Torrent, chunk framework.ChunkInfo, nodeName string) *api.Replication {
+	replication := buildCreationReplication(torrent, chunk, nodeName)
+	replication.Spec.Source = api.Target{
+		URI:                 ptr.To[string](objectURI(torrent, chunk)),
+		CredentialSecretRef: torrent.Spec.CredentialSecretRef,
+	}
+	replication.Spec.Destination = &api.Target{
+		URI: ptr.To[string](localhost + workspace + repoName(torrent) + "/snapshots/" + chunk.Revision + "/" + chunk.Path),
+	}
+	return replication
+}
+
+func buildSyncReplication(torrent *api.Torrent, chunk framework.ChunkInfo, sourceName string, peerNames []string, targetName string) *api.Replication {
+	repoName := repoName(torrent)
 	generatedName := util.GenerateName(targetName)
 	name := chunk.Name + "--" + generatedName
 
@@ -366,18 +679,21 @@ func buildSyncReplication(torrent *api.Torrent, chunk framework.ChunkInfo, sourc
 			NodeName:  targetName,
 			ChunkName: chunk.Name,
 			Source: api.Target{
-				URI: ptr.To[string](remote + sourceName + "@" + workspace + repoName + "/blobs/" + chunk.Name),
+				URI:           ptr.To[string](remote + sourceName + "@" + workspace + repoName + "/blobs/" + chunk.Name),
+				PeerNodeNames: peerNames,
 			},
 			Destination: &api.Target{
 				URI: ptr.To[string](localhost + workspace + repoName + "/snapshots/" + chunk.Revision + "/" + chunk.Path),
 			},
-			SizeBytes: chunk.Size,
+			SizeBytes:    chunk.Size,
+			OffsetBytes:  chunk.Offset,
+			ObjectDigest: chunk.Digest,
 		},
 	}
 }
 
 func buildDeletionReplication(torrent *api.Torrent, chunk framework.ChunkInfo, nodeName string) *api.Replication {
-	repoName := hubRepoName(torrent.Spec.Hub)
+	repoName := repoName(torrent)
 	generatedName := util.GenerateName(nodeName)
 	name := chunk.Name + "--" + generatedName + "--" + "d"
 
@@ -414,14 +730,61 @@ func buildDeletionReplication(torrent *api.Torrent, chunk framework.ChunkInfo, n
 	}
 }
 
-func hubRepoName(hub *api.Hub) string {
-	return strings.ReplaceAll(hub.RepoID, "/", "--")
+// buildSupersededReplication builds the Replication deleting the superseded chunk, the source refers
+// to the blob rather than the snapshot, because the snapshot file may link to the new chunk already.
+func buildSupersededReplication(torrent *api.Torrent, chunkName string, nodeName string) *api.Replication {
+	replication := buildDeletionReplication(torrent, framework.ChunkInfo{Name: chunkName}, nodeName)
+	replication.Spec.Source.URI = ptr.To[string](localhost + workspace + repoName(torrent) + "/blobs/" + chunkName)
+	return replication
+}
+
+// objectURI returns the uri of the object, for images, it refers to the layer blob.
+func objectURI(torrent *api.Torrent, chunk framework.ChunkInfo) string {
+	uri := string(*torrent.Spec.URI)
+	if registry.IsImageURI(uri) {
+		if ref, err := registry.ParseReference(uri); err == nil {
+			hash, _, _, _ := cons.ParseChunkName(chunk.Name)
+			return ref.BlobURI(registry.DigestFromChunkHash(hash))
+		}
+	}
+	return objectstore.ObjectURI(uri, chunk.Path, len(torrent.Status.Repo.Objects))
+}
+
+func repoName(torrent *api.Torrent) string {
+	if torrent.Spec.URI != nil && registry.IsImageURI(string(*torrent.Spec.URI)) {
+		if ref, err := registry.ParseReference(string(*torrent.Spec.URI)); err == nil {
+			return ref.RepoName()
+		}
+	}
+	if torrent.Spec.URI != nil {
+		if store, err := objectstore.NewObjectStore(string(*torrent.Spec.URI), nil); err == nil {
+			return store.RepoName()
+		}
+		// Should not happen because the uri is validated by the webhook.
+		return strings.NewReplacer("://", "--", "/", "--").Replace(string(*torrent.Spec.URI))
+	}
+	return strings.ReplaceAll(torrent.Spec.Hub.RepoID, "/", "--")
+}
+
+// commit returns the commit the hub revision resolved to, the objects should be downloaded
+// from it, Torrents created by the older versions have no commit recorded.
+func commit(torrent *api.Torrent) string {
+	if torrent.Status.Repo != nil && torrent.Status.Repo.Commit != "" {
+		return torrent.Status.Repo.Commit
+	}
+	return *torrent.Spec.Hub.Revision
 }
 
 func revision(torrent *api.Torrent) string {
 	if torrent.Spec.Hub != nil {
 		return *torrent.Spec.Hub.Revision
 	}
+	// Different tags of the same image should not share the snapshot.
+	if torrent.Spec.URI != nil && registry.IsImageURI(string(*torrent.Spec.URI)) {
+		if ref, err := registry.ParseReference(string(*torrent.Spec.URI)); err == nil {
+			return ref.Revision()
+		}
+	}
 	// Default to "main" for URI
 	return "main"
 }
@@ -0,0 +1,470 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/config"
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/loadaware"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/topology"
+	"github.com/inftyai/manta/test/util/wrapper"
+)
+
+func TestMergeCandidates(t *testing.T) {
+	testCases := []struct {
+		name       string
+		candidates []framework.ScoreCandidate
+		want       []framework.ScoreCandidate
+	}{
+		{
+			name: "one source for each target",
+			candidates: []framework.ScoreCandidate{
+				{SourceNodeName: "node1", CandidateNodeName: "node2", Score: 10},
+				{SourceNodeName: "node1", CandidateNodeName: "node3", Score: 20},
+			},
+			want: []framework.ScoreCandidate{
+				{SourceNodeName: "node1", CandidateNodeName: "node3", Score: 20},
+				{SourceNodeName: "node1", CandidateNodeName: "node2", Score: 10},
+			},
+		},
+		{
+			name: "several sources for the same target",
+			candidates: []framework.ScoreCandidate{
+				{SourceNodeName: "node1", CandidateNodeName: "node4", Score: 10},
+				{SourceNodeName: "node2", CandidateNodeName: "node4", Score: 30},
+				{SourceNodeName: "node3", CandidateNodeName: "node4", Score: 20},
+				{SourceNodeName: "node1", CandidateNodeName: "node5", Score: 5},
+			},
+			want: []framework.ScoreCandidate{
+				{SourceNodeName: "node2", PeerNodeNames: []string{"node3", "node1"}, CandidateNodeName: "node4", Score: 30},
+				{SourceNodeName: "node1", CandidateNodeName: "node5", Score: 5},
+			},
+		},
+		{
+			name: "sources exceed the limit",
+			candidates: []framework.ScoreCandidate{
+				{SourceNodeName: "node1", CandidateNodeName: "node0", Score: 50},
+				{SourceNodeName: "node2", CandidateNodeName: "node0", Score: 40},
+				{SourceNodeName: "node3", CandidateNodeName: "node0", Score: 30},
+				{SourceNodeName: "node4", CandidateNodeName: "node0", Score: 20},
+				{SourceNodeName: "node5", CandidateNodeName: "node0", Score: 10},
+			},
+			want: []framework.ScoreCandidate{
+				{SourceNodeName: "node1", PeerNodeNames: []string{"node2", "node3", "node4"}, CandidateNodeName: "node0", Score: 50},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := mergeCandidates(tc.candidates)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected candidates (-want +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestPrepareReplicationsWithTopology(t *testing.T) {
+	chunkName := "chunk1--0001"
+	nodeTracker := func(name, zone, region string) api.NodeTracker {
+		return *wrapper.MakeNodeTracker(name).Label(corev1.LabelTopologyZone, zone).Label(corev1.LabelTopologyRegion, region).Obj()
+	}
+	source := nodeTracker("node1", "zone1", "region1")
+
+	testCases := []struct {
+		name              string
+		nodeTrackers      []api.NodeTracker
+		forbidCrossRegion bool
+		wantNodeName      string
+		wantSource        *string
+	}{
+		{
+			name:         "prefer the same zone",
+			nodeTrackers: []api.NodeTracker{source, nodeTracker("node2", "zone3", "region2"), nodeTracker("node3", "zone1", "region1")},
+			wantNodeName: "node3",
+			wantSource:   &source.Name,
+		},
+		{
+			name:         "prefer the same region",
+			nodeTrackers: []api.NodeTracker{source, nodeTracker("node2", "zone3", "region2"), nodeTracker("node3", "zone2", "region1")},
+			wantNodeName: "node3",
+			wantSource:   &source.Name,
+		},
+		{
+			name:         "sync across regions",
+			nodeTrackers: []api.NodeTracker{source, nodeTracker("node2", "zone3", "region2")},
+			wantNodeName: "node2",
+			wantSource:   &source.Name,
+		},
+		{
+			name:              "fall back to download once cross-region syncing is forbidden",
+			nodeTrackers:      []api.NodeTracker{source, nodeTracker("node2", "zone3", "region2")},
+			forbidCrossRegion: true,
+			wantNodeName:      "node2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.Default()
+			args, _ := json.Marshal(topology.Args{ForbidCrossRegion: tc.forbidCrossRegion})
+			cfg.PluginConfig = append(cfg.PluginConfig, config.PluginConfig{Name: topology.Name, Args: runtime.RawExtension{Raw: args}})
+
+			d, err := NewDispatcher(plugins.NewInTreeRegistry(), cfg)
+			if err != nil {
+				t.Fatalf("failed to create dispatcher: %v", err)
+			}
+			d.cache.AddChunks([]api.ChunkTracker{{ChunkName: chunkName, SizeBytes: 1}}, source.Name)
+
+			torrent := wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2.5-0.5B-Instruct", "").Replicas(2).Obj()
+			torrent.Spec.Hub.Revision = ptr.To[string]("main")
+			torrent.Status.Repo = &api.RepoStatus{
+				Objects: []api.ObjectStatus{
+					{Path: "LICENSE", Type: api.FileObjectType, Chunks: []api.ChunkStatus{{Name: chunkName, SizeBytes: 1, State: api.PendingTrackerState}}},
+				},
+			}
+
+			replications, _, _, err := d.PrepareReplications(context.Background(), torrent, tc.nodeTrackers)
+			if err != nil {
+				t.Fatalf("failed to prepare replications: %v", err)
+			}
+			if len(replications) != 1 {
+				t.Fatalf("unexpected replications number: %d", len(replications))
+			}
+
+			replication := replications[0]
+			if replication.Spec.NodeName != tc.wantNodeName {
+				t.Errorf("unexpected node name, want %s, got %s", tc.wantNodeName, replication.Spec.NodeName)
+			}
+			if tc.wantSource == nil {
+				if replication.Spec.Source.Hub == nil {
+					t.Errorf("replication should download from the hub")
+				}
+			} else if replication.Spec.Source.URI == nil || !strings.HasPrefix(*replication.Spec.Source.URI, remote+*tc.wantSource+"@") {
+				t.Errorf("replication should sync from %s", *tc.wantSource)
+			}
+		})
+	}
+}
+
+func TestPrepareReplicationsWithPartialFallback(t *testing.T) {
+	chunkName := "chunk1--0001"
+	nodeTracker := func(name, zone, region string) api.NodeTracker {
+		return *wrapper.MakeNodeTracker(name).Label(corev1.LabelTopologyZone, zone).Label(corev1.LabelTopologyRegion, region).Obj()
+	}
+	nodeTrackers := []api.NodeTracker{nodeTracker("node1", "zone1", "region1"), nodeTracker("node2", "zone2", "region1"), nodeTracker("node3", "zone3", "region2")}
+
+	cfg := config.Default()
+	args, _ := json.Marshal(topology.Args{ForbidCrossRegion: true})
+	cfg.PluginConfig = append(cfg.PluginConfig, config.PluginConfig{Name: topology.Name, Args: runtime.RawExtension{Raw: args}})
+	d, err := NewDispatcher(plugins.NewInTreeRegistry(), cfg)
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	d.cache.AddChunks([]api.ChunkTracker{{ChunkName: chunkName, SizeBytes: 1}}, "node1")
+
+	torrent := wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2.5-0.5B-Instruct", "").Replicas(3).Obj()
+	torrent.Status.Repo = &api.RepoStatus{
+		Objects: []api.ObjectStatus{
+			{Path: "LICENSE", Type: api.FileObjectType, Chunks: []api.ChunkStatus{{Name: chunkName, SizeBytes: 1, State: api.PendingTrackerState}}},
+		},
+	}
+
+	replications, _, _, err := d.PrepareReplications(context.Background(), torrent, nodeTrackers)
+	if err != nil {
+		t.Fatalf("failed to prepare replications: %v", err)
+	}
+	if len(replications) != 2 {
+		t.Fatalf("unexpected replications number: %d", len(replications))
+	}
+	for _, replication := range replications {
+		switch replication.Spec.NodeName {
+		case "node2":
+			if uri := replication.Spec.Source.URI; uri == nil || !strings.HasPrefix(*uri, remote+"node1@") {
+				t.Errorf("node2 should sync from node1 in the same region, got %v", replication.Spec.Source)
+			}
+		case "node3":
+			if replication.Spec.Source.Hub == nil {
+				t.Errorf("node3 should download from the hub with no source in the same region, got %v", replication.Spec.Source)
+			}
+		default:
+			t.Errorf("unexpected node name: %s", replication.Spec.NodeName)
+		}
+	}
+}
+
+func TestPrepareReplicationsWithNodeAvailability(t *testing.T) {
+	chunkName := "chunk1--0001"
+	taint := corev1.Taint{Key: "nvidia.com/gpu", Effect: corev1.TaintEffectNoSchedule}
+	targets := []api.NodeTracker{
+		*wrapper.MakeNodeTracker("node2").NodeState(false, false).Obj(),
+		*wrapper.MakeNodeTracker("node3").NodeState(true, true).Obj(),
+		*wrapper.MakeNodeTracker("node4").NodeState(true, false, taint).Obj(),
+	}
+
+	testCases := []struct {
+		name         string
+		source       api.NodeTracker
+		tolerations  []corev1.Toleration
+		wantNodeName string
+		wantSync     bool
+	}{
+		{
+			name:         "sync to the only available node",
+			source:       *wrapper.MakeNodeTracker("node1").NodeState(true, true).Obj(),
+			tolerations:  []corev1.Toleration{{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists}},
+			wantNodeName: "node4",
+			wantSync:     true,
+		},
+		{
+			name:         "fall back to download once the source is not ready",
+			source:       *wrapper.MakeNodeTracker("node1").NodeState(false, false).Obj(),
+			tolerations:  []corev1.Toleration{{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists}},
+			wantNodeName: "node4",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := NewDispatcher(plugins.NewInTreeRegistry(), config.Default())
+			if err != nil {
+				t.Fatalf("failed to create dispatcher: %v", err)
+			}
+			d.cache.AddChunks([]api.ChunkTracker{{ChunkName: chunkName, SizeBytes: 1}}, tc.source.Name)
+
+			torrent := wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2.5-0.5B-Instruct", "").Replicas(2).Obj()
+			torrent.Spec.Hub.Revision = ptr.To[string]("main")
+			torrent.Spec.Tolerations = tc.tolerations
+			torrent.Status.Repo = &api.RepoStatus{
+				Objects: []api.ObjectStatus{
+					{Path: "LICENSE", Type: api.FileObjectType, Chunks: []api.ChunkStatus{{Name: chunkName, SizeBytes: 1, State: api.PendingTrackerState}}},
+				},
+			}
+
+			replications, _, _, err := d.PrepareReplications(context.Background(), torrent, append([]api.NodeTracker{tc.source}, targets...))
+			if err != nil {
+				t.Fatalf("failed to prepare replications: %v", err)
+			}
+			if len(replications) != 1 {
+				t.Fatalf("unexpected replications number: %d", len(replications))
+			}
+
+			replication := replications[0]
+			if replication.Spec.NodeName != tc.wantNodeName {
+				t.Errorf("unexpected node name, want %s, got %s", tc.wantNodeName, replication.Spec.NodeName)
+			}
+			if tc.wantSync != (replication.Spec.Source.Hub == nil) {
+				t.Errorf("unexpected source: %v", replication.Spec.Source)
+			}
+		})
+	}
+
+	// No available nodes at all without the tolerations.
+	d, err := NewDispatcher(plugins.NewInTreeRegistry(), config.Default())
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	torrent := wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2.5-0.5B-Instruct", "").Replicas(1).Obj()
+	torrent.Spec.Hub.Revision = ptr.To[string]("main")
+	torrent.Status.Repo = &api.RepoStatus{
+		Objects: []api.ObjectStatus{
+			{Path: "LICENSE", Type: api.FileObjectType, Chunks: []api.ChunkStatus{{Name: chunkName, SizeBytes: 1, State: api.PendingTrackerState}}},
+		},
+	}
+	if _, _, _, err := d.PrepareReplications(context.Background(), torrent, targets); err == nil {
+		t.Error("expected error here")
+	}
+}
+
+func TestPrepareReplicationsWithFailedNodes(t *testing.T) {
+	d, err := NewDispatcher(plugins.NewInTreeRegistry(), config.Default())
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	// The first chunk is replicated to node1, but the second one failed.
+	d.cache.AddChunks([]api.ChunkTracker{{ChunkName: "chunk1--0002", SizeBytes: 1}}, "node1")
+
+	torrent := wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2.5-0.5B-Instruct", "").Replicas(1).Obj()
+	torrent.Spec.Hub.Revision = ptr.To[string]("main")
+	torrent.Status.Repo = &api.RepoStatus{
+		Objects: []api.ObjectStatus{
+			{
+				Path: "model.safetensors",
+				Type: api.FileObjectType,
+				Chunks: []api.ChunkStatus{
+					{Name: "chunk1--0002", SizeBytes: 1, State: api.PendingTrackerState},
+					{Name: "chunk2--0002", SizeBytes: 1, State: api.PendingTrackerState},
+				},
+				FailedNodeNames: []string{"node1"},
+			},
+		},
+	}
+	nodeTrackers := []api.NodeTracker{*wrapper.MakeNodeTracker("node1").Obj(), *wrapper.MakeNodeTracker("node2").Obj()}
+
+	replications, _, _, err := d.PrepareReplications(context.Background(), torrent, nodeTrackers)
+	if err != nil {
+		t.Fatalf("failed to prepare replications: %v", err)
+	}
+	if len(replications) != 2 {
+		t.Fatalf("unexpected replications number: %d", len(replications))
+	}
+	for _, replication := range replications {
+		if replication.Spec.NodeName != "node2" {
+			t.Errorf("replication %s should be dispatched to node2, got %s", replication.Name, replication.Spec.NodeName)
+		}
+	}
+	// The failed node could still be the sync source.
+	if uri := replications[0].Spec.Source.URI; uri == nil || !strings.HasPrefix(*uri, remote+"node1@") {
+		t.Errorf("chunk1 should be synced from node1, got %v", replications[0].Spec.Source)
+	}
+	if replications[1].Spec.Source.Hub == nil {
+		t.Errorf("chunk2 should be downloaded from the hub, got %v", replications[1].Spec.Source)
+	}
+}
+
+func TestPrepareReplicationsWithSeveralSources(t *testing.T) {
+	d, err := NewDispatcher(plugins.NewInTreeRegistry(), config.Default())
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	d.cache.AddChunks([]api.ChunkTracker{{ChunkName: "chunk1--0001", SizeBytes: 1}}, "node1")
+	d.cache.AddChunks([]api.ChunkTracker{{ChunkName: "chunk1--0001", SizeBytes: 1}}, "node2")
+
+	torrent := wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2.5-0.5B-Instruct", "").Replicas(3).Obj()
+	torrent.Spec.Hub.Revision = ptr.To[string]("main")
+	torrent.Status.Repo = &api.RepoStatus{
+		Objects: []api.ObjectStatus{
+			{Path: "LICENSE", Type: api.FileObjectType, Chunks: []api.ChunkStatus{{Name: "chunk1--0001", SizeBytes: 1, State: api.PendingTrackerState}}},
+		},
+	}
+	nodeTrackers := []api.NodeTracker{*wrapper.MakeNodeTracker("node1").Obj(), *wrapper.MakeNodeTracker("node2").Obj(), *wrapper.MakeNodeTracker("node3").Obj()}
+
+	// Each replicated node should be counted once rather than once per source.
+	replications, _, _, err := d.PrepareReplications(context.Background(), torrent, nodeTrackers)
+	if err != nil {
+		t.Fatalf("failed to prepare replications: %v", err)
+	}
+	if len(replications) != 1 || replications[0].Spec.NodeName != "node3" {
+		t.Fatalf("unexpected replications: %v", replications)
+	}
+}
+
+func TestPrepareReplicationsWithLoad(t *testing.T) {
+	cfg := config.Default()
+	args, _ := json.Marshal(loadaware.Args{MaxConcurrentDownloads: 1})
+	cfg.PluginConfig = append(cfg.PluginConfig, config.PluginConfig{Name: loadaware.Name, Args: runtime.RawExtension{Raw: args}})
+
+	d, err := NewDispatcher(plugins.NewInTreeRegistry(), cfg)
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+
+	nodeTrackers := []api.NodeTracker{*wrapper.MakeNodeTracker("node1").Obj(), *wrapper.MakeNodeTracker("node2").Obj()}
+	torrent := wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2.5-0.5B-Instruct", "").Replicas(1).Obj()
+	torrent.Spec.Hub.Revision = ptr.To[string]("main")
+	torrent.Status.Repo = &api.RepoStatus{}
+	for _, name := range []string{"chunk1--0001", "chunk2--0001", "chunk3--0001"} {
+		torrent.Status.Repo.Objects = append(torrent.Status.Repo.Objects, api.ObjectStatus{
+			Path: name, Type: api.FileObjectType, Chunks: []api.ChunkStatus{{Name: name, SizeBytes: 1, State: api.PendingTrackerState}},
+		})
+	}
+	states := func() (got []api.TrackerState) {
+		for _, obj := range torrent.Status.Repo.Objects {
+			got = append(got, obj.Chunks[0].State)
+		}
+		return got
+	}
+
+	// Each node could only receive one chunk at a time, the last object is left pending.
+	replications, statusChanged, _, err := d.PrepareReplications(context.Background(), torrent, nodeTrackers)
+	if err != nil {
+		t.Fatalf("failed to prepare replications: %v", err)
+	}
+	if !statusChanged || len(replications) != 2 || replications[0].Spec.NodeName == replications[1].Spec.NodeName {
+		t.Fatalf("unexpected replications: %v", replications)
+	}
+	if diff := cmp.Diff([]api.TrackerState{api.ReadyTrackerState, api.ReadyTrackerState, api.PendingTrackerState}, states()); diff != "" {
+		t.Errorf("unexpected chunk states (-want +got): %s", diff)
+	}
+
+	// All the nodes are busy.
+	for _, replication := range replications {
+		d.AddReplication(replication)
+	}
+	if _, _, _, err := d.PrepareReplications(context.Background(), torrent, nodeTrackers); err == nil {
+		t.Fatal("expected error here")
+	}
+
+	// One node becomes idle once the Replication is ready.
+	finished := replications[0].DeepCopy()
+	finished.Status.Conditions = []metav1.Condition{{Type: api.ReadyConditionType, Status: metav1.ConditionTrue}}
+	d.UpdateReplication(replications[0], finished)
+
+	replications2, _, _, err := d.PrepareReplications(context.Background(), torrent, nodeTrackers)
+	if err != nil {
+		t.Fatalf("failed to prepare replications: %v", err)
+	}
+	if len(replications2) != 1 || replications2[0].Spec.NodeName != finished.Spec.NodeName {
+		t.Fatalf("unexpected replications: %v", replications2)
+	}
+	if diff := cmp.Diff([]api.TrackerState{api.ReadyTrackerState, api.ReadyTrackerState, api.ReadyTrackerState}, states()); diff != "" {
+		t.Errorf("unexpected chunk states (-want +got): %s", diff)
+	}
+}
+
+func TestSupersededReplications(t *testing.T) {
+	d, err := NewDispatcher(plugins.NewInTreeRegistry(), config.Default())
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	d.cache.AddChunks([]api.ChunkTracker{{ChunkName: "chunk1--0001", SizeBytes: 1}}, "node1")
+	d.cache.AddChunks([]api.ChunkTracker{{ChunkName: "chunk1--0001", SizeBytes: 1}}, "node2")
+
+	torrent := wrapper.MakeTorrent("torrent").Hub("Huggingface", "Qwen/Qwen2.5-0.5B-Instruct", "").Obj()
+	torrent.Status.Repo = &api.RepoStatus{
+		// chunk2 is not replicated to any node.
+		SupersededChunks: []string{"chunk1--0001", "chunk2--0001"},
+	}
+
+	replications := d.SupersededReplications(context.Background(), torrent)
+	if len(replications) != 2 {
+		t.Fatalf("unexpected replications number: %d", len(replications))
+	}
+	for _, replication := range replications {
+		if replication.Spec.ChunkName != "chunk1--0001" || replication.Spec.Destination != nil {
+			t.Errorf("unexpected replication spec: %v", replication.Spec)
+		}
+		// The snapshot path may refer to the new chunk already, so reclaim the blob.
+		if uri := *replication.Spec.Source.URI; uri != localhost+workspace+repoName(torrent)+"/blobs/chunk1--0001" {
+			t.Errorf("unexpected source uri: %s", uri)
+		}
+	}
+}
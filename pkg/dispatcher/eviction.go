@@ -0,0 +1,155 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"sort"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+
+	cons "github.com/inftyai/manta/api"
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/util"
+)
+
+// objectChunks represents the chunks of the same object in one node, they're
+// evicted together because the object couldn't be served once one of them is missing.
+type objectChunks struct {
+	name           string
+	chunks         []api.ChunkTracker
+	sizeBytes      int64
+	lastAccessTime time.Time
+	pinned         bool
+}
+
+// EvictReplications will create replications to evict the least recently used chunks
+// once the total chunk size of the node exceeds the highWatermark of the size limit,
+// until it's under the lowWatermark. Pinned chunks will never be evicted.
+// This function must be idempotent or we'll create duplicated replications.
+func (d *Dispatcher) EvictReplications(nodeTracker *api.NodeTracker, pinned sets.Set[string], highWatermark, lowWatermark float64) (replications []*api.Replication, err error) {
+	var totalSize int64
+	for _, chunk := range nodeTracker.Spec.Chunks {
+		totalSize += chunk.SizeBytes
+	}
+
+	limit, err := d.diskAware.SizeLimit(*nodeTracker)
+	if err != nil {
+		return nil, err
+	}
+	sizeLimit := float64(limit)
+	if float64(totalSize) <= sizeLimit*highWatermark {
+		return nil, nil
+	}
+
+	for _, object := range lruObjects(nodeTracker.Spec.Chunks, pinned) {
+		if float64(totalSize) <= sizeLimit*lowWatermark {
+			break
+		}
+		if object.pinned {
+			continue
+		}
+
+		for _, chunk := range object.chunks {
+			replications = append(replications, buildEvictionReplication(nodeTracker, chunk))
+		}
+		totalSize -= object.sizeBytes
+	}
+	return replications, nil
+}
+
+// lruObjects groups the chunks by objects and sorts them by the last access time,
+// the least recently used one comes first.
+func lruObjects(chunks []api.ChunkTracker, pinned sets.Set[string]) []*objectChunks {
+	objects := map[string]*objectChunks{}
+	for _, chunk := range chunks {
+		name := chunk.ChunkName
+		if hash, _, _, err := cons.ParseChunkName(chunk.ChunkName); err == nil {
+			name = hash
+		}
+
+		object, ok := objects[name]
+		if !ok {
+			object = &objectChunks{name: name}
+			objects[name] = object
+		}
+
+		object.chunks = append(object.chunks, chunk)
+		object.sizeBytes += chunk.SizeBytes
+		if chunk.LastAccessTime != nil && chunk.LastAccessTime.After(object.lastAccessTime) {
+			object.lastAccessTime = chunk.LastAccessTime.Time
+		}
+		// Chunks without repo couldn't be located, they'll be evicted once reported by the agent.
+		if pinned.Has(chunk.ChunkName) || chunk.Repo == "" {
+			object.pinned = true
+		}
+	}
+
+	result := make([]*objectChunks, 0, len(objects))
+	for _, object := range objects {
+		result = append(result, object)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].lastAccessTime.Equal(result[j].lastAccessTime) {
+			return result[i].name < result[j].name
+		}
+		return result[i].lastAccessTime.Before(result[j].lastAccessTime)
+	})
+	return result
+}
+
+// buildEvictionReplication builds the Replication to delete the chunk blob, unlike
+// the deletion Replication, it's owned by the NodeTracker rather than the Torrent
+// because the chunk may not belong to any Torrent.
+func buildEvictionReplication(nodeTracker *api.NodeTracker, chunk api.ChunkTracker) *api.Replication {
+	generatedName := util.GenerateName(nodeTracker.Name)
+	name := chunk.ChunkName + "--" + generatedName + "--" + "e"
+
+	return &api.Replication{
+		TypeMeta: v1.TypeMeta{
+			Kind:       "Replication",
+			APIVersion: api.GroupVersion.String(),
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name: name,
+			OwnerReferences: []v1.OwnerReference{
+				{
+					Kind:               "NodeTracker",
+					APIVersion:         api.GroupVersion.String(),
+					Name:               nodeTracker.Name,
+					UID:                nodeTracker.UID,
+					BlockOwnerDeletion: ptr.To(true),
+					Controller:         ptr.To(true),
+				},
+			},
+			Labels: map[string]string{
+				api.NodeTrackerNameLabelKey: nodeTracker.Name,
+			},
+		},
+		Spec: api.ReplicationSpec{
+			NodeName:  nodeTracker.Name,
+			ChunkName: chunk.ChunkName,
+			Source: api.Target{
+				URI: ptr.To[string](localhost + workspace + chunk.Repo + "/blobs/" + chunk.ChunkName),
+			},
+			Destination: nil,
+			SizeBytes:   chunk.SizeBytes,
+		},
+	}
+}
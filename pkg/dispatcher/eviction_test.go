@@ -0,0 +1,139 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/config"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/diskaware"
+	"github.com/inftyai/manta/test/util/wrapper"
+)
+
+func TestEvictReplications(t *testing.T) {
+	now := time.Now()
+	chunk := func(name string, size int64, repo string, minutesAgo int) api.ChunkTracker {
+		return api.ChunkTracker{
+			ChunkName:      name,
+			SizeBytes:      size,
+			Repo:           repo,
+			LastAccessTime: &metav1.Time{Time: now.Add(-time.Duration(minutesAgo) * time.Minute)},
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		chunks     []api.ChunkTracker
+		pinned     sets.Set[string]
+		wantChunks []string
+	}{
+		{
+			name:   "under the high watermark",
+			chunks: []api.ChunkTracker{chunk("a--0001", 50, "repo", 10), chunk("b--0001", 40, "repo", 20)},
+			pinned: sets.New[string](),
+		},
+		{
+			name:       "evict the least recently used chunks until under the low watermark",
+			chunks:     []api.ChunkTracker{chunk("a--0001", 40, "repo", 10), chunk("b--0001", 30, "repo", 30), chunk("c--0001", 25, "repo", 20)},
+			pinned:     sets.New[string](),
+			wantChunks: []string{"b--0001"},
+		},
+		{
+			name:       "pinned chunks will not be evicted",
+			chunks:     []api.ChunkTracker{chunk("a--0001", 40, "repo", 10), chunk("b--0001", 30, "repo", 30), chunk("c--0001", 25, "repo", 20)},
+			pinned:     sets.New("b--0001"),
+			wantChunks: []string{"c--0001"},
+		},
+		{
+			name:       "chunks of the same object are evicted together",
+			chunks:     []api.ChunkTracker{chunk("a--0002", 40, "repo", 10), chunk("b--0001", 30, "repo", 5), chunk("a--0102", 25, "repo", 20)},
+			pinned:     sets.New[string](),
+			wantChunks: []string{"a--0002", "a--0102"},
+		},
+		{
+			name:       "chunks without repo will not be evicted",
+			chunks:     []api.ChunkTracker{chunk("a--0001", 40, "repo", 10), chunk("b--0001", 30, "", 30), chunk("c--0001", 25, "repo", 20)},
+			pinned:     sets.New[string](),
+			wantChunks: []string{"c--0001"},
+		},
+	}
+
+	d, err := NewDispatcher(plugins.NewInTreeRegistry(), config.Default())
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nodeTracker := wrapper.MakeNodeTracker("node1").SizeLimit("100").Obj()
+			nodeTracker.Spec.Chunks = tc.chunks
+
+			replications, err := d.EvictReplications(nodeTracker, tc.pinned, 0.9, 0.8)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var gotChunks []string
+			for _, replication := range replications {
+				if replication.Spec.Destination != nil || replication.Labels[api.NodeTrackerNameLabelKey] != "node1" {
+					t.Errorf("unexpected replication: %v", replication)
+				}
+				if *replication.Spec.Source.URI != localhost+workspace+"repo/blobs/"+replication.Spec.ChunkName {
+					t.Errorf("unexpected source uri: %s", *replication.Spec.Source.URI)
+				}
+				gotChunks = append(gotChunks, replication.Spec.ChunkName)
+			}
+
+			if diff := cmp.Diff(tc.wantChunks, gotChunks); diff != "" {
+				t.Errorf("unexpected evicted chunks, diff: %v", diff)
+			}
+		})
+	}
+}
+
+func TestEvictReplicationsWithSizeLimit(t *testing.T) {
+	cfg := config.Default()
+	cfg.PluginConfig = append(cfg.PluginConfig, config.PluginConfig{Name: diskaware.Name, Args: runtime.RawExtension{Raw: []byte(`{"defaultSizeLimit":"100"}`)}})
+	d, err := NewDispatcher(plugins.NewInTreeRegistry(), cfg)
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+
+	// The configured default size limit is used once not set.
+	nodeTracker := wrapper.MakeNodeTracker("node1").Chunk("a--0001", 95).Obj()
+	nodeTracker.Spec.Chunks[0].Repo = "repo"
+	replications, err := d.EvictReplications(nodeTracker, sets.New[string](), 0.9, 0.8)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(replications) != 1 {
+		t.Errorf("unexpected replications number: %d", len(replications))
+	}
+
+	nodeTracker = wrapper.MakeNodeTracker("node1").SizeLimit("unknown").Chunk("a--0001", 95).Obj()
+	if _, err := d.EvictReplications(nodeTracker, sets.New[string](), 0.9, 0.8); err == nil {
+		t.Error("expected error with the invalid size limit")
+	}
+}
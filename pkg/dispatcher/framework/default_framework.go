@@ -18,49 +18,136 @@ package framework
 
 import (
 	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	api "github.com/inftyai/manta/api/v1alpha1"
 	"github.com/inftyai/manta/pkg/dispatcher/cache"
-	"sigs.k8s.io/controller-runtime/pkg/log"
+	"github.com/inftyai/manta/pkg/dispatcher/config"
 )
 
 var _ Framework = &DefaultFramework{}
 
 type DefaultFramework struct {
-	registry Registry
+	// Plugins run in the configured order.
+	preFilterPlugins  []PreFilterPlugin
+	filterPlugins     []FilterPlugin
+	postFilterPlugins []PostFilterPlugin
+	scorePlugins      []weightedScorePlugin
+	reservePlugins    []ReservePlugin
 }
 
-func (df *DefaultFramework) RegisterPlugins(fns []RegisterFunc) error {
-	if df.registry == nil {
-		df.registry = make(Registry)
+type weightedScorePlugin struct {
+	ScorePlugin
+	weight int32
+}
+
+// NewFramework initializes the plugins enabled in the configuration from the registry,
+// plugins enabled at several extension points share the same instance.
+func NewFramework(registry Registry, cfg *config.DispatcherConfiguration) (*DefaultFramework, error) {
+	args := map[string]*runtime.RawExtension{}
+	for i := range cfg.PluginConfig {
+		args[cfg.PluginConfig[i].Name] = &cfg.PluginConfig[i].Args
 	}
 
-	for _, fn := range fns {
-		if err := df.registry.Register(fn); err != nil {
-			return err
+	plugins := map[string]Plugin{}
+	initPlugin := func(name string) (Plugin, error) {
+		if plugin, ok := plugins[name]; ok {
+			return plugin, nil
+		}
+		fn, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("plugin %s not found in registry", name)
+		}
+		plugin, err := fn(args[name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize plugin %s: %v", name, err)
 		}
+		plugins[name] = plugin
+		return plugin, nil
 	}
 
-	return nil
+	df := &DefaultFramework{}
+	var err error
+	if df.preFilterPlugins, err = enablePlugins[PreFilterPlugin](cfg.Plugins.PreFilter, "preFilter", initPlugin); err != nil {
+		return nil, err
+	}
+	if df.filterPlugins, err = enablePlugins[FilterPlugin](cfg.Plugins.Filter, "filter", initPlugin); err != nil {
+		return nil, err
+	}
+	if df.postFilterPlugins, err = enablePlugins[PostFilterPlugin](cfg.Plugins.PostFilter, "postFilter", initPlugin); err != nil {
+		return nil, err
+	}
+	if df.reservePlugins, err = enablePlugins[ReservePlugin](cfg.Plugins.Reserve, "reserve", initPlugin); err != nil {
+		return nil, err
+	}
+
+	scorePlugins, err := enablePlugins[ScorePlugin](cfg.Plugins.Score, "score", initPlugin)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range cfg.Plugins.Score.Enabled {
+		weight := int32(1)
+		if p.Weight != nil {
+			weight = *p.Weight
+		}
+		df.scorePlugins = append(df.scorePlugins, weightedScorePlugin{ScorePlugin: scorePlugins[i], weight: weight})
+	}
+
+	for name := range args {
+		if _, ok := plugins[name]; !ok {
+			return nil, fmt.Errorf("plugin %s is configured but not enabled", name)
+		}
+	}
+	return df, nil
+}
+
+// enablePlugins initializes the plugins of the extension point in the configured order.
+func enablePlugins[T Plugin](set config.PluginSet, extensionPoint string, initPlugin func(string) (Plugin, error)) ([]T, error) {
+	plugins := make([]T, 0, len(set.Enabled))
+	for _, p := range set.Enabled {
+		plugin, err := initPlugin(p.Name)
+		if err != nil {
+			return nil, err
+		}
+		extended, ok := plugin.(T)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s doesn't extend %s", p.Name, extensionPoint)
+		}
+		plugins = append(plugins, extended)
+	}
+	return plugins, nil
+}
+
+func (df *DefaultFramework) RunPreFilterPlugins(ctx context.Context, chunk ChunkInfo, cache *cache.Cache) Status {
+	logger := log.FromContext(ctx)
+
+	for _, plugin := range df.preFilterPlugins {
+		status := plugin.PreFilter(ctx, chunk, cache)
+		if status.Code != SuccessStatus {
+			logger.Info("prefilter failed", "plugin", plugin.Name(), "file", chunk.Path, "chunk", chunk.Name)
+			return status
+		}
+	}
+	return Status{Code: SuccessStatus}
 }
 
 func (df *DefaultFramework) RunFilterPlugins(ctx context.Context, chunk ChunkInfo, nodeInfo *NodeInfo, nodeTrackers []api.NodeTracker, cache *cache.Cache) (candidates []Candidate) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	var status Status
-
 	logger := log.FromContext(ctx)
 
 	// TODO: consider performance issue once thousands of nodeTrackers in the cluster.
 	for _, nt := range nodeTrackers {
-		for _, plugin := range df.registry {
-			if p, ok := plugin.(FilterPlugin); ok {
-				status = p.Filter(ctx, chunk, nodeInfo, nt, cache)
-				if status.Code != SuccessStatus {
-					logger.Info("filter out plugin", "plugin", plugin.Name(), "node", nt.Name, "file", chunk.Path, "chunk", chunk.Name)
-					break
-				}
+		status := Status{Code: SuccessStatus}
+		for _, plugin := range df.filterPlugins {
+			status = plugin.Filter(ctx, chunk, nodeInfo, nt, cache)
+			if status.Code != SuccessStatus {
+				logger.Info("filter out plugin", "plugin", plugin.Name(), "node", nt.Name, "file", chunk.Path, "chunk", chunk.Name)
+				break
 			}
 		}
 		if status.Code == SuccessStatus {
@@ -70,6 +157,8 @@ func (df *DefaultFramework) RunFilterPlugins(ctx context.Context, chunk ChunkInf
 
 	return candidates
 }
+
+// RunScorePlugins sums up the scores of all the plugins multiplied by their weights.
 func (df *DefaultFramework) RunScorePlugins(ctx context.Context, chunk ChunkInfo, nodeInfo *NodeInfo, candidates []Candidate, cache *cache.Cache) []Candidate {
 	logger := log.FromContext(ctx)
 	ctx, cancel := context.WithCancel(ctx)
@@ -78,13 +167,11 @@ func (df *DefaultFramework) RunScorePlugins(ctx context.Context, chunk ChunkInfo
 	for i, nt := range candidates {
 		var totalScore float32
 
-		for _, plugin := range df.registry {
-			if p, ok := plugin.(ScorePlugin); ok {
-				score := p.Score(ctx, chunk, nodeInfo, nt.Node, cache)
+		for _, plugin := range df.scorePlugins {
+			score := plugin.Score(ctx, chunk, nodeInfo, nt.Node, cache)
 
-				logger.V(10).Info("calculate plugin score", "plugin", plugin.Name(), "node", nt.Node.Name, "file", chunk.Path, "chunk", chunk.Name, "score", score)
-				totalScore += standardScore(score)
-			}
+			logger.V(10).Info("calculate plugin score", "plugin", plugin.Name(), "node", nt.Node.Name, "file", chunk.Path, "chunk", chunk.Name, "score", score, "weight", plugin.weight)
+			totalScore += float32(plugin.weight) * standardScore(score)
 		}
 		candidates[i].Score = totalScore
 	}
@@ -92,6 +179,40 @@ func (df *DefaultFramework) RunScorePlugins(ctx context.Context, chunk ChunkInfo
 	return candidates
 }
 
+func (df *DefaultFramework) RunPostFilterPlugins(ctx context.Context, chunk ChunkInfo, nodeInfo *NodeInfo, nodeTrackers []api.NodeTracker, cache *cache.Cache) []Candidate {
+	logger := log.FromContext(ctx)
+
+	for _, plugin := range df.postFilterPlugins {
+		candidates, status := plugin.PostFilter(ctx, chunk, nodeInfo, nodeTrackers, cache)
+		if status.Code == SuccessStatus && len(candidates) > 0 {
+			logger.Info("candidates nominated by postfilter", "plugin", plugin.Name(), "file", chunk.Path, "chunk", chunk.Name)
+			return candidates
+		}
+	}
+	return nil
+}
+
+func (df *DefaultFramework) RunReservePlugins(ctx context.Context, chunk ChunkInfo, nodeName string, cache *cache.Cache) Status {
+	logger := log.FromContext(ctx)
+
+	for _, plugin := range df.reservePlugins {
+		status := plugin.Reserve(ctx, chunk, nodeName, cache)
+		if status.Code != SuccessStatus {
+			logger.Info("reserve failed", "plugin", plugin.Name(), "node", nodeName, "chunk", chunk.Name)
+			df.RunUnreservePlugins(ctx, chunk, nodeName, cache)
+			return status
+		}
+	}
+	return Status{Code: SuccessStatus}
+}
+
+// RunUnreservePlugins runs the plugins in the reverse order of reserving.
+func (df *DefaultFramework) RunUnreservePlugins(ctx context.Context, chunk ChunkInfo, nodeName string, cache *cache.Cache) {
+	for i := len(df.reservePlugins) - 1; i >= 0; i-- {
+		df.reservePlugins[i].Unreserve(ctx, chunk, nodeName, cache)
+	}
+}
+
 func standardScore(score float32) float32 {
 	if score < 0 {
 		return 0
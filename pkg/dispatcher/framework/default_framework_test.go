@@ -0,0 +1,289 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/cache"
+	"github.com/inftyai/manta/pkg/dispatcher/config"
+)
+
+// fakePlugin filters out the nodes in the blacklist and scores all the nodes with the same score,
+// the chunk named with the plugin name is rejected in prefilter, nodes in the blacklist are
+// nominated in postfilter but failed to reserve.
+type fakePlugin struct {
+	name      string
+	score     float32
+	blacklist []string
+	// calls records the plugin names in the order called.
+	calls *[]string
+}
+
+func (p *fakePlugin) Name() string {
+	return p.name
+}
+
+func (p *fakePlugin) PreFilter(_ context.Context, chunk ChunkInfo, _ *cache.Cache) Status {
+	*p.calls = append(*p.calls, p.name)
+	if chunk.Name == p.name {
+		return Status{Code: UnschedulableStatus}
+	}
+	return Status{Code: SuccessStatus}
+}
+
+func (p *fakePlugin) Filter(_ context.Context, _ ChunkInfo, _ *NodeInfo, nt api.NodeTracker, _ *cache.Cache) Status {
+	*p.calls = append(*p.calls, p.name)
+	for _, name := range p.blacklist {
+		if name == nt.Name {
+			return Status{Code: UnschedulableStatus}
+		}
+	}
+	return Status{Code: SuccessStatus}
+}
+
+func (p *fakePlugin) Score(_ context.Context, _ ChunkInfo, _ *NodeInfo, _ api.NodeTracker, _ *cache.Cache) float32 {
+	*p.calls = append(*p.calls, p.name)
+	return p.score
+}
+
+func (p *fakePlugin) PostFilter(_ context.Context, _ ChunkInfo, _ *NodeInfo, _ []api.NodeTracker, _ *cache.Cache) ([]Candidate, Status) {
+	*p.calls = append(*p.calls, p.name)
+	if len(p.blacklist) == 0 {
+		return nil, Status{Code: UnschedulableStatus}
+	}
+	candidates := []Candidate{}
+	for _, name := range p.blacklist {
+		candidates = append(candidates, Candidate{Node: api.NodeTracker{ObjectMeta: metav1.ObjectMeta{Name: name}}})
+	}
+	return candidates, Status{Code: SuccessStatus}
+}
+
+func (p *fakePlugin) Reserve(_ context.Context, _ ChunkInfo, nodeName string, _ *cache.Cache) Status {
+	*p.calls = append(*p.calls, "Reserve"+p.name)
+	for _, name := range p.blacklist {
+		if name == nodeName {
+			return Status{Code: UnschedulableStatus}
+		}
+	}
+	return Status{Code: SuccessStatus}
+}
+
+func (p *fakePlugin) Unreserve(_ context.Context, _ ChunkInfo, _ string, _ *cache.Cache) {
+	*p.calls = append(*p.calls, "Unreserve"+p.name)
+}
+
+// namedPlugin extends no extension point.
+type namedPlugin struct{}
+
+func (p *namedPlugin) Name() string {
+	return "Named"
+}
+
+func newRegistry(calls *[]string) Registry {
+	fakeFunc := func(name string, score float32) RegisterFunc {
+		return func(args *runtime.RawExtension) (Plugin, error) {
+			plugin := &fakePlugin{name: name, score: score, calls: calls}
+			if args != nil {
+				if err := json.Unmarshal(args.Raw, &plugin.blacklist); err != nil {
+					return nil, err
+				}
+			}
+			return plugin, nil
+		}
+	}
+	return Registry{
+		"A": fakeFunc("A", 10),
+		"B": fakeFunc("B", 20),
+		"Named": func(_ *runtime.RawExtension) (Plugin, error) {
+			return &namedPlugin{}, nil
+		},
+	}
+}
+
+func TestNewFramework(t *testing.T) {
+	nodeTrackers := []api.NodeTracker{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+	}
+
+	testCases := []struct {
+		name           string
+		plugins        config.Plugins
+		pluginConfig   []config.PluginConfig
+		wantError      bool
+		wantFilterCall []string
+		wantScoreCall  []string
+		wantCandidates []Candidate
+	}{
+		{
+			name: "plugins run in the configured order with weights",
+			plugins: config.Plugins{
+				Filter: config.PluginSet{Enabled: []config.Plugin{{Name: "B"}, {Name: "A"}}},
+				Score:  config.PluginSet{Enabled: []config.Plugin{{Name: "A", Weight: ptr.To[int32](3)}, {Name: "B"}}},
+			},
+			pluginConfig: []config.PluginConfig{
+				{Name: "A", Args: runtime.RawExtension{Raw: []byte(`["node2"]`)}},
+			},
+			wantFilterCall: []string{"B", "A", "B", "A"},
+			wantScoreCall:  []string{"A", "B"},
+			wantCandidates: []Candidate{{Node: nodeTrackers[0], Score: 50}},
+		},
+		{
+			name:           "no plugins enabled",
+			wantCandidates: []Candidate{{Node: nodeTrackers[0]}, {Node: nodeTrackers[1]}},
+		},
+		{
+			name: "plugin not found",
+			plugins: config.Plugins{
+				Filter: config.PluginSet{Enabled: []config.Plugin{{Name: "C"}}},
+			},
+			wantError: true,
+		},
+		{
+			name: "plugin doesn't extend the extension point",
+			plugins: config.Plugins{
+				Score: config.PluginSet{Enabled: []config.Plugin{{Name: "Named"}}},
+			},
+			wantError: true,
+		},
+		{
+			name: "plugin configured but not enabled",
+			plugins: config.Plugins{
+				Filter: config.PluginSet{Enabled: []config.Plugin{{Name: "A"}}},
+			},
+			pluginConfig: []config.PluginConfig{
+				{Name: "B", Args: runtime.RawExtension{Raw: []byte(`[]`)}},
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := []string{}
+			fwk, err := NewFramework(newRegistry(&calls), &config.DispatcherConfiguration{Plugins: tc.plugins, PluginConfig: tc.pluginConfig})
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("expected error here")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ctx := context.Background()
+			candidates := fwk.RunFilterPlugins(ctx, ChunkInfo{}, nil, nodeTrackers, cache.NewCache())
+			if diff := cmp.Diff(tc.wantFilterCall, calls); len(tc.wantFilterCall) > 0 && diff != "" {
+				t.Errorf("unexpected filter calls (-want +got): %s", diff)
+			}
+
+			calls = calls[:0]
+			candidates = fwk.RunScorePlugins(ctx, ChunkInfo{}, nil, candidates, cache.NewCache())
+			if diff := cmp.Diff(tc.wantScoreCall, calls); len(tc.wantScoreCall) > 0 && diff != "" {
+				t.Errorf("unexpected score calls (-want +got): %s", diff)
+			}
+			if diff := cmp.Diff(tc.wantCandidates, candidates); diff != "" {
+				t.Errorf("unexpected candidates (-want +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestExtensionPoints(t *testing.T) {
+	nodeTrackers := []api.NodeTracker{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+	}
+
+	calls := []string{}
+	fwk, err := NewFramework(newRegistry(&calls), &config.DispatcherConfiguration{
+		Plugins: config.Plugins{
+			PreFilter:  config.PluginSet{Enabled: []config.Plugin{{Name: "A"}, {Name: "B"}}},
+			PostFilter: config.PluginSet{Enabled: []config.Plugin{{Name: "A"}, {Name: "B"}}},
+			Reserve:    config.PluginSet{Enabled: []config.Plugin{{Name: "A"}, {Name: "B"}}},
+		},
+		PluginConfig: []config.PluginConfig{
+			{Name: "B", Args: runtime.RawExtension{Raw: []byte(`["node2"]`)}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	testCases := []struct {
+		name      string
+		run       func() any
+		want      any
+		wantCalls []string
+	}{
+		{
+			name:      "prefilter succeeded",
+			run:       func() any { return fwk.RunPreFilterPlugins(ctx, ChunkInfo{Name: "chunk"}, cache.NewCache()) },
+			want:      Status{Code: SuccessStatus},
+			wantCalls: []string{"A", "B"},
+		},
+		{
+			name:      "prefilter failed at the first plugin",
+			run:       func() any { return fwk.RunPreFilterPlugins(ctx, ChunkInfo{Name: "A"}, cache.NewCache()) },
+			want:      Status{Code: UnschedulableStatus},
+			wantCalls: []string{"A"},
+		},
+		{
+			name: "postfilter returns the candidates of the first plugin nominated",
+			run: func() any {
+				return fwk.RunPostFilterPlugins(ctx, ChunkInfo{}, nil, nodeTrackers, cache.NewCache())
+			},
+			want:      []Candidate{{Node: api.NodeTracker{ObjectMeta: metav1.ObjectMeta{Name: "node2"}}}},
+			wantCalls: []string{"A", "B"},
+		},
+		{
+			name:      "reserve succeeded",
+			run:       func() any { return fwk.RunReservePlugins(ctx, ChunkInfo{}, "node1", cache.NewCache()) },
+			want:      Status{Code: SuccessStatus},
+			wantCalls: []string{"ReserveA", "ReserveB"},
+		},
+		{
+			name:      "reserve failed and unreserved in the reverse order",
+			run:       func() any { return fwk.RunReservePlugins(ctx, ChunkInfo{}, "node2", cache.NewCache()) },
+			want:      Status{Code: UnschedulableStatus},
+			wantCalls: []string{"ReserveA", "ReserveB", "UnreserveB", "UnreserveA"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = calls[:0]
+			got := tc.run()
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected result (-want +got): %s", diff)
+			}
+			if diff := cmp.Diff(tc.wantCalls, calls); diff != "" {
+				t.Errorf("unexpected calls (-want +got): %s", diff)
+			}
+		})
+	}
+}
@@ -19,6 +19,8 @@ package framework
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
+
 	api "github.com/inftyai/manta/api/v1alpha1"
 	"github.com/inftyai/manta/pkg/dispatcher/cache"
 )
@@ -48,15 +50,25 @@ type Sync interface {
 }
 
 type ChunkInfo struct {
-	Name         string
-	Size         int64
-	Path         string
-	Revision     string
+	Name string
+	Size int64
+	// Offset represents the offset of the chunk in the object.
+	Offset   int64
+	Path     string
+	Revision string
+	// Digest represents the digest of the object the chunk belongs to.
+	Digest       string
 	NodeSelector map[string]string
+	NodeAffinity *corev1.NodeAffinity
+	Tolerations  []corev1.Toleration
 }
 
 type NodeInfo struct {
 	Name string
+	// Labels of the node, mirrored from the Node, e.g. the topology labels.
+	Labels map[string]string
+	// State of the node, mirrored from the Node, nil means unknown.
+	State *api.NodeState
 }
 
 // Candidate will be used as the result of Filter extension point and input/output of Score extension point.
@@ -72,6 +84,9 @@ type ScoreCandidate struct {
 	// SourceNodeName represents the the source node name in syncing tasks.
 	// It's empty once in downloading tasks.
 	SourceNodeName string
+	// PeerNodeNames represents the other source nodes in syncing tasks, the chunk
+	// will be pulled from them together with the SourceNodeName.
+	PeerNodeNames []string
 	// CandidateNodeName represents the target node name.
 	CandidateNodeName string
 	// Score for candidate node.
@@ -80,8 +95,9 @@ type ScoreCandidate struct {
 
 // Framework represents the algo about how to pick the candidates among all the peers.
 type Framework interface {
-	// RegisterPlugins will register the plugins to run.
-	RegisterPlugins([]RegisterFunc) error
+	// RunPreFilterPlugins runs once per chunk before filtering, the plugins could precompute
+	// the state into the cache, once failed, the chunk is regarded as unschedulable.
+	RunPreFilterPlugins(context.Context, ChunkInfo, *cache.Cache) Status
 	// RunFilterPlugins will filter out unsatisfied peers.
 	// NodeInfo refers to the source node in syncing tasks, it must not be nil in syncing,
 	// on the contrary, it must be nil in downloading tasks.
@@ -90,6 +106,14 @@ type Framework interface {
 	// NodeInfo refers to the source node in syncing tasks, it must not be nil in syncing,
 	// on the contrary, it must be nil in downloading tasks.
 	RunScorePlugins(context.Context, ChunkInfo, *NodeInfo, []Candidate, *cache.Cache) []Candidate
+	// RunPostFilterPlugins runs once no peers pass the filters, it returns the candidates
+	// nominated by the first plugin succeeded, or nil once none of them succeeded.
+	RunPostFilterPlugins(context.Context, ChunkInfo, *NodeInfo, []api.NodeTracker, *cache.Cache) []Candidate
+	// RunReservePlugins runs once the chunk is dispatched to the node, once failed, all
+	// the plugins will be unreserved and the node should not be used.
+	RunReservePlugins(context.Context, ChunkInfo, string, *cache.Cache) Status
+	// RunUnreservePlugins reverts the reservation, e.g. the dispatching is failed at last.
+	RunUnreservePlugins(context.Context, ChunkInfo, string, *cache.Cache)
 }
 
 // Plugin is the parent type for all the framework plugins.
@@ -100,7 +124,8 @@ type Plugin interface {
 
 type PreFilterPlugin interface {
 	Plugin
-	// PreFilter helps to do some computing before calling Filter plugins.
+	// PreFilter helps to do some computing before calling Filter plugins,
+	// the result could be stored into the cache with Store.
 	PreFilter(context.Context, ChunkInfo, *cache.Cache) Status
 }
 
@@ -118,3 +143,20 @@ type ScorePlugin interface {
 	// Once NodeInfo is nil, it's a download task, otherwise it's a sync task.
 	Score(context.Context, ChunkInfo, *NodeInfo, api.NodeTracker, *cache.Cache) float32
 }
+
+type PostFilterPlugin interface {
+	Plugin
+	// PostFilter is called once no nodes pass the filters, it could make room for the chunk,
+	// e.g. evicting or preempting, and nominate the candidates with a success status.
+	// Once NodeInfo is nil, it's a download task, otherwise it's a sync task.
+	PostFilter(context.Context, ChunkInfo, *NodeInfo, []api.NodeTracker, *cache.Cache) ([]Candidate, Status)
+}
+
+type ReservePlugin interface {
+	Plugin
+	// Reserve accounts for the chunk just dispatched to the node.
+	Reserve(context.Context, ChunkInfo, string, *cache.Cache) Status
+	// Unreserve reverts the Reserve, it must be idempotent and may be called even
+	// the Reserve of this plugin is not called.
+	Unreserve(context.Context, ChunkInfo, string, *cache.Cache)
+}
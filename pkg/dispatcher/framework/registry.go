@@ -18,28 +18,25 @@ package framework
 
 import (
 	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
-type RegisterFunc = func() (Plugin, error)
+// RegisterFunc initializes the plugin with the arguments configured in the
+// DispatcherConfiguration, args is nil once not configured.
+type RegisterFunc = func(args *runtime.RawExtension) (Plugin, error)
 
-// Registry is a collection of all available plugins. The framework uses a
-// registry to enable and initialize configured plugins.
-type Registry map[string]Plugin
+// Registry is a collection of all available plugins indexed by the plugin name.
+// The framework uses a registry to enable and initialize configured plugins.
+type Registry map[string]RegisterFunc
 
 // Register adds a new plugin to the registry. If a plugin with the same name
 // exists, it returns an error.
-func (r Registry) Register(fn RegisterFunc) error {
-	plugin, err := fn()
-	if err != nil {
-		return err
-	}
-
-	name := plugin.Name()
+func (r Registry) Register(name string, fn RegisterFunc) error {
 	if _, ok := r[name]; ok {
 		return fmt.Errorf("a plugin named %v already exists", name)
 	}
-
-	r[name] = plugin
+	r[name] = fn
 	return nil
 }
 
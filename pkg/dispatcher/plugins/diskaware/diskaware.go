@@ -18,37 +18,62 @@ package diskaware
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
 	api "github.com/inftyai/manta/api/v1alpha1"
 	"github.com/inftyai/manta/pkg/dispatcher/cache"
 	"github.com/inftyai/manta/pkg/dispatcher/framework"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 var _ framework.FilterPlugin = &DiskAware{}
 var _ framework.ScorePlugin = &DiskAware{}
 
 const (
+	Name = "DiskAware"
+
 	// The default memory size is 100Gi.
 	defaultSizeLimit = "100Gi"
 )
 
-type DiskAware struct{}
+// Args is the arguments of the DiskAware plugin.
+type Args struct {
+	// DefaultSizeLimit is the size limit of the nodes without sizeLimit set
+	// when dispatching chunks, default to 100Gi.
+	DefaultSizeLimit string `json:"defaultSizeLimit,omitempty"`
+}
+
+type DiskAware struct {
+	defaultSizeLimit int64
+}
+
+func New(args *runtime.RawExtension) (framework.Plugin, error) {
+	pluginArgs := Args{DefaultSizeLimit: defaultSizeLimit}
+	if args != nil && len(args.Raw) > 0 {
+		if err := json.Unmarshal(args.Raw, &pluginArgs); err != nil {
+			return nil, err
+		}
+	}
 
-func New() (framework.Plugin, error) {
-	return &DiskAware{}, nil
+	limit, err := resource.ParseQuantity(pluginArgs.DefaultSizeLimit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid defaultSizeLimit: %v", err)
+	}
+	return &DiskAware{defaultSizeLimit: limit.Value()}, nil
 }
 
 func (ds *DiskAware) Name() string {
-	return "DiskAware"
+	return Name
 }
 
 func (ds *DiskAware) Filter(ctx context.Context, chunk framework.ChunkInfo, _ *framework.NodeInfo, nodeTracker api.NodeTracker, cache *cache.Cache) framework.Status {
 	nodeName := nodeTracker.Name
 	totalSize := cache.NodeTotalSizeBytes(nodeName)
 
-	sizeLimit := sizeLimit(nodeTracker)
-	if totalSize+chunk.Size > sizeLimit {
+	sizeLimit, err := ds.SizeLimit(nodeTracker)
+	if err != nil || totalSize+chunk.Size > sizeLimit {
 		return framework.Status{Code: framework.UnschedulableStatus}
 	}
 
@@ -66,17 +91,22 @@ func (ds *DiskAware) Score(ctx context.Context, chunkInfo framework.ChunkInfo, _
 		totalSize = loadValue.(int64)
 	}
 
-	sizeLimit := sizeLimit(nodeTracker)
+	sizeLimit, err := ds.SizeLimit(nodeTracker)
+	// Should not happen because the node is filtered out already.
+	if err != nil {
+		return 0
+	}
 	return (1 - float32(totalSize+chunkInfo.Size)/float32(sizeLimit)) * 100
 }
 
-func sizeLimit(nt api.NodeTracker) int64 {
-	limit := defaultSizeLimit
-	if nt.Spec.SizeLimit != nil {
-		limit = *nt.Spec.SizeLimit
+// SizeLimit returns the size limit of the chunks in bytes, the defaultSizeLimit is used once not set.
+func (ds *DiskAware) SizeLimit(nt api.NodeTracker) (int64, error) {
+	if nt.Spec.SizeLimit == nil {
+		return ds.defaultSizeLimit, nil
 	}
-
-	// TODO: we'll validate the value in the webhooks to make sure no panic here.
-	value := resource.MustParse(limit)
-	return value.Value()
+	limit, err := resource.ParseQuantity(*nt.Spec.SizeLimit)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sizeLimit of NodeTracker %s: %v", nt.Name, err)
+	}
+	return limit.Value(), nil
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/inftyai/manta/pkg/dispatcher/cache"
 	"github.com/inftyai/manta/pkg/dispatcher/framework"
 	"github.com/inftyai/manta/test/util/wrapper"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestFilter(t *testing.T) {
@@ -46,6 +47,16 @@ func TestFilter(t *testing.T) {
 			cache:       func() *cache.Cache { return cache.NewCache().Snapshot() },
 			wantStatus:  framework.Status{Code: framework.SuccessStatus},
 		},
+		{
+			name: "invalid size limit",
+			chunk: framework.ChunkInfo{
+				Name: "chunk1",
+				Size: 512,
+			},
+			nodeTracker: *wrapper.MakeNodeTracker("node1").SizeLimit("unknown").Obj(),
+			cache:       func() *cache.Cache { return cache.NewCache().Snapshot() },
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+		},
 		{
 			name: "small chunk size with not empty cache",
 			chunk: framework.ChunkInfo{
@@ -93,7 +104,7 @@ func TestFilter(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			plugin, err := New()
+			plugin, err := New(nil)
 			if err != nil {
 				t.Errorf("failed to construct plugin: %v", err)
 			}
@@ -153,7 +164,7 @@ func TestScore(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			plugin, err := New()
+			plugin, err := New(nil)
 			if err != nil {
 				t.Errorf("failed to construct plugin: %v", err)
 			}
@@ -167,3 +178,50 @@ func TestScore(t *testing.T) {
 		})
 	}
 }
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name          string
+		args          *runtime.RawExtension
+		wantError     bool
+		wantSizeLimit int64
+	}{
+		{
+			name:          "no args",
+			wantSizeLimit: 100 * 1024 * 1024 * 1024,
+		},
+		{
+			name:          "default size limit set",
+			args:          &runtime.RawExtension{Raw: []byte(`{"defaultSizeLimit":"10Gi"}`)},
+			wantSizeLimit: 10 * 1024 * 1024 * 1024,
+		},
+		{
+			name:      "invalid default size limit",
+			args:      &runtime.RawExtension{Raw: []byte(`{"defaultSizeLimit":"unknown"}`)},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plugin, err := New(tc.args)
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("expected error here")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to construct plugin: %v", err)
+			}
+
+			gotSizeLimit, err := plugin.(*DiskAware).SizeLimit(*wrapper.MakeNodeTracker("node1").Obj())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotSizeLimit != tc.wantSizeLimit {
+				t.Errorf("unexpected size limit, want %d, got %d", tc.wantSizeLimit, gotSizeLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadaware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/cache"
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ framework.FilterPlugin = &LoadAware{}
+var _ framework.ScorePlugin = &LoadAware{}
+
+const (
+	Name = "LoadAware"
+)
+
+// Args is the arguments of the LoadAware plugin.
+type Args struct {
+	// MaxConcurrentDownloads is the maximum number of in-flight Replications downloading
+	// or syncing to one node, 0 means unlimited.
+	MaxConcurrentDownloads int32 `json:"maxConcurrentDownloads,omitempty"`
+	// MaxConcurrentUploads is the maximum number of in-flight Replications syncing from
+	// one node, 0 means unlimited.
+	MaxConcurrentUploads int32 `json:"maxConcurrentUploads,omitempty"`
+}
+
+// LoadAware avoids the hot-spot nodes based on the in-flight Replications, nodes over the
+// concurrency caps are filtered out, and less loaded nodes are preferred.
+type LoadAware struct {
+	maxConcurrentDownloads int32
+	maxConcurrentUploads   int32
+}
+
+func New(args *runtime.RawExtension) (framework.Plugin, error) {
+	pluginArgs := Args{}
+	if args != nil && len(args.Raw) > 0 {
+		if err := json.Unmarshal(args.Raw, &pluginArgs); err != nil {
+			return nil, err
+		}
+	}
+
+	if pluginArgs.MaxConcurrentDownloads < 0 || pluginArgs.MaxConcurrentUploads < 0 {
+		return nil, fmt.Errorf("maxConcurrentDownloads and maxConcurrentUploads must not be negative")
+	}
+	return &LoadAware{
+		maxConcurrentDownloads: pluginArgs.MaxConcurrentDownloads,
+		maxConcurrentUploads:   pluginArgs.MaxConcurrentUploads,
+	}, nil
+}
+
+func (la *LoadAware) Name() string {
+	return Name
+}
+
+func (la *LoadAware) Filter(ctx context.Context, _ framework.ChunkInfo, nodeInfo *framework.NodeInfo, nodeTracker api.NodeTracker, cache *cache.Cache) framework.Status {
+	if la.maxConcurrentDownloads > 0 && cache.NodeLoad(nodeTracker.Name).Destination >= la.maxConcurrentDownloads {
+		return framework.Status{Code: framework.UnschedulableStatus}
+	}
+	// The source node is too busy to serve one more sync.
+	if nodeInfo != nil && la.maxConcurrentUploads > 0 && cache.NodeLoad(nodeInfo.Name).Source >= la.maxConcurrentUploads {
+		return framework.Status{Code: framework.UnschedulableStatus}
+	}
+	return framework.Status{Code: framework.SuccessStatus}
+}
+
+// Score is ranged in (0, 100], the more in-flight Replications of the target node and
+// the source node, the lower the score.
+func (la *LoadAware) Score(ctx context.Context, _ framework.ChunkInfo, nodeInfo *framework.NodeInfo, nodeTracker api.NodeTracker, cache *cache.Cache) float32 {
+	load := cache.NodeLoad(nodeTracker.Name)
+	total := load.Destination + load.Source
+	if nodeInfo != nil {
+		total += cache.NodeLoad(nodeInfo.Name).Source
+	}
+	return 100 / float32(1+total)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadaware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/cache"
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestLoadAware(t *testing.T) {
+	// node1 is receiving two chunks and node2 is serving one chunk to node1.
+	newCache := func() *cache.Cache {
+		c := cache.NewCache()
+		c.AddReplication("replication1", "node1", nil)
+		c.AddReplication("replication2", "node1", []string{"node2"})
+		return c.Snapshot()
+	}
+	node := func(name string) api.NodeTracker {
+		return api.NodeTracker{ObjectMeta: v1.ObjectMeta{Name: name}}
+	}
+
+	testCases := []struct {
+		name        string
+		args        *runtime.RawExtension
+		nodeInfo    *framework.NodeInfo
+		nodeTracker api.NodeTracker
+		wantStatus  framework.Status
+		wantScore   float32
+	}{
+		{
+			name:        "unlimited",
+			nodeTracker: node("node1"),
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+			wantScore:   100.0 / 3,
+		},
+		{
+			name:        "download under the cap",
+			args:        &runtime.RawExtension{Raw: []byte(`{"maxConcurrentDownloads": 1}`)},
+			nodeTracker: node("node3"),
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+			wantScore:   100,
+		},
+		{
+			name:        "download over the cap",
+			args:        &runtime.RawExtension{Raw: []byte(`{"maxConcurrentDownloads": 2}`)},
+			nodeTracker: node("node1"),
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+			wantScore:   100.0 / 3,
+		},
+		{
+			name:        "sync from the source under the cap",
+			args:        &runtime.RawExtension{Raw: []byte(`{"maxConcurrentUploads": 2}`)},
+			nodeInfo:    &framework.NodeInfo{Name: "node2"},
+			nodeTracker: node("node3"),
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+			wantScore:   50,
+		},
+		{
+			name:        "sync from the source over the cap",
+			args:        &runtime.RawExtension{Raw: []byte(`{"maxConcurrentUploads": 1}`)},
+			nodeInfo:    &framework.NodeInfo{Name: "node2"},
+			nodeTracker: node("node3"),
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+			wantScore:   50,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			plugin, err := New(tc.args)
+			if err != nil {
+				t.Fatalf("failed to construct plugin: %v", err)
+			}
+			la := plugin.(*LoadAware)
+
+			c := newCache()
+			gotStatus := la.Filter(ctx, framework.ChunkInfo{}, tc.nodeInfo, tc.nodeTracker, c)
+			if diff := cmp.Diff(tc.wantStatus, gotStatus); diff != "" {
+				t.Errorf("unexpected status, diff: %v", diff)
+			}
+			gotScore := la.Score(ctx, framework.ChunkInfo{}, tc.nodeInfo, tc.nodeTracker, c)
+			if gotScore != tc.wantScore {
+				t.Errorf("unexpected score, want %v, got %v", tc.wantScore, gotScore)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&runtime.RawExtension{Raw: []byte(`{"maxConcurrentDownloads": -1}`)}); err == nil {
+		t.Error("expected error here")
+	}
+}
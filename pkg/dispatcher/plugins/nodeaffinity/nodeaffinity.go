@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeaffinity
+
+import (
+	"context"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/cache"
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	"github.com/inftyai/manta/pkg/util"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ framework.PreFilterPlugin = &NodeAffinity{}
+var _ framework.FilterPlugin = &NodeAffinity{}
+var _ framework.ScorePlugin = &NodeAffinity{}
+
+const (
+	Name = "NodeAffinity"
+
+	// stateKey is the key of the parsed affinity stored in the cache.
+	stateKey = Name + "/affinity"
+)
+
+// NodeAffinity filters the nodes with the required terms and scores them with
+// the preferred terms.
+type NodeAffinity struct{}
+
+type affinity struct {
+	required  []*util.NodeSelectorTerm
+	preferred []util.PreferredSchedulingTerm
+}
+
+func New(_ *runtime.RawExtension) (framework.Plugin, error) {
+	return &NodeAffinity{}, nil
+}
+
+func (na *NodeAffinity) Name() string {
+	return Name
+}
+
+// PreFilter parses the affinity once per chunk rather than once per node.
+func (na *NodeAffinity) PreFilter(ctx context.Context, chunkInfo framework.ChunkInfo, cache *cache.Cache) framework.Status {
+	parsed, errs := parse(chunkInfo)
+	// Should not happen because the affinity is validated by the webhook.
+	if len(errs) > 0 {
+		return framework.Status{Code: framework.UnschedulableStatus}
+	}
+	cache.Store(stateKey, parsed)
+	return framework.Status{Code: framework.SuccessStatus}
+}
+
+func (na *NodeAffinity) Filter(ctx context.Context, chunkInfo framework.ChunkInfo, _ *framework.NodeInfo, nodeTracker api.NodeTracker, cache *cache.Cache) framework.Status {
+	parsed, ok := load(chunkInfo, cache)
+	if !ok {
+		return framework.Status{Code: framework.UnschedulableStatus}
+	}
+
+	// Nil means no requirement, nodes matching any of the terms are satisfied.
+	if parsed.required == nil {
+		return framework.Status{Code: framework.SuccessStatus}
+	}
+	for _, term := range parsed.required {
+		if term.Matches(nodeTracker.Name, nodeTracker.Labels) {
+			return framework.Status{Code: framework.SuccessStatus}
+		}
+	}
+	return framework.Status{Code: framework.UnschedulableStatus}
+}
+
+// Score is the proportion of the weights of the matched preferred terms.
+func (na *NodeAffinity) Score(ctx context.Context, chunkInfo framework.ChunkInfo, _ *framework.NodeInfo, nodeTracker api.NodeTracker, cache *cache.Cache) float32 {
+	parsed, ok := load(chunkInfo, cache)
+	if !ok || len(parsed.preferred) == 0 {
+		return framework.MinScore
+	}
+
+	var matched, total int32
+	for _, term := range parsed.preferred {
+		total += term.Weight
+		if term.Matches(nodeTracker.Name, nodeTracker.Labels) {
+			matched += term.Weight
+		}
+	}
+	return float32(matched) * framework.MaxScore / float32(total)
+}
+
+// load gets the affinity parsed in PreFilter, or parses it directly once PreFilter is not enabled.
+func load(chunkInfo framework.ChunkInfo, cache *cache.Cache) (*affinity, bool) {
+	if cache != nil {
+		if parsed, ok := cache.Load(stateKey).(*affinity); ok {
+			return parsed, true
+		}
+	}
+	parsed, errs := parse(chunkInfo)
+	return parsed, len(errs) == 0
+}
+
+func parse(chunkInfo framework.ChunkInfo) (*affinity, field.ErrorList) {
+	required, preferred, errs := util.ParseNodeAffinity(chunkInfo.NodeAffinity, field.NewPath("spec", "nodeAffinity"))
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &affinity{required: required, preferred: preferred}, nil
+}
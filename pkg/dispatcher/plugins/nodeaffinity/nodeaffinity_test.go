@@ -0,0 +1,138 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeaffinity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/cache"
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeAffinity(t *testing.T) {
+	// GPU type In [A100,H100] and pool NotIn [spot], prefer H100 and then the larger memory.
+	affinity := &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "gpu", Operator: corev1.NodeSelectorOpIn, Values: []string{"A100", "H100"}},
+						{Key: "pool", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"spot"}},
+					},
+				},
+			},
+		},
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+			{
+				Weight: 60,
+				Preference: corev1.NodeSelectorTerm{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "gpu", Operator: corev1.NodeSelectorOpIn, Values: []string{"H100"}}},
+				},
+			},
+			{
+				Weight: 40,
+				Preference: corev1.NodeSelectorTerm{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "gpu-memory", Operator: corev1.NodeSelectorOpGt, Values: []string{"40"}}},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		affinity   *corev1.NodeAffinity
+		labels     map[string]string
+		preFilter  bool
+		wantStatus framework.Status
+		wantScore  float32
+	}{
+		{
+			name:       "no affinity",
+			labels:     map[string]string{"gpu": "T4"},
+			wantStatus: framework.Status{Code: framework.SuccessStatus},
+			wantScore:  0,
+		},
+		{
+			name:       "all preferred terms matched",
+			affinity:   affinity,
+			labels:     map[string]string{"gpu": "H100", "gpu-memory": "80"},
+			wantStatus: framework.Status{Code: framework.SuccessStatus},
+			wantScore:  100,
+		},
+		{
+			name:       "part of preferred terms matched with preFilter",
+			affinity:   affinity,
+			labels:     map[string]string{"gpu": "A100", "gpu-memory": "80", "pool": "reserved"},
+			preFilter:  true,
+			wantStatus: framework.Status{Code: framework.SuccessStatus},
+			wantScore:  40,
+		},
+		{
+			name:       "gpu type not matched",
+			affinity:   affinity,
+			labels:     map[string]string{"gpu": "T4"},
+			wantStatus: framework.Status{Code: framework.UnschedulableStatus},
+			wantScore:  0,
+		},
+		{
+			name:       "spot pool not matched with preFilter",
+			affinity:   affinity,
+			labels:     map[string]string{"gpu": "H100", "pool": "spot"},
+			preFilter:  true,
+			wantStatus: framework.Status{Code: framework.UnschedulableStatus},
+			wantScore:  60,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			plugin, err := New(nil)
+			if err != nil {
+				t.Fatalf("failed to construct plugin: %v", err)
+			}
+			na := plugin.(*NodeAffinity)
+
+			chunk := framework.ChunkInfo{Name: "chunk1", NodeAffinity: tc.affinity}
+			nodeTracker := api.NodeTracker{ObjectMeta: v1.ObjectMeta{Name: "node1", Labels: tc.labels}}
+
+			var c *cache.Cache
+			if tc.preFilter {
+				c = cache.NewCache().Snapshot()
+				if status := na.PreFilter(ctx, chunk, c); status.Code != framework.SuccessStatus {
+					t.Errorf("unexpected preFilter status: %v", status)
+				}
+			}
+
+			gotStatus := na.Filter(ctx, chunk, nil, nodeTracker, c)
+			if diff := cmp.Diff(tc.wantStatus, gotStatus); diff != "" {
+				t.Errorf("unexpected status, diff: %v", diff)
+			}
+			gotScore := na.Score(ctx, chunk, nil, nodeTracker, c)
+			if gotScore != tc.wantScore {
+				t.Errorf("unexpected score, want %v, got %v", tc.wantScore, gotScore)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeavailability
+
+import (
+	"context"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/cache"
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ framework.FilterPlugin = &NodeAvailability{}
+
+const (
+	Name = "NodeAvailability"
+)
+
+// NodeAvailability filters out the nodes not Ready, cordoned or with untolerated NoSchedule
+// or NoExecute taints as the targets, and the nodes not Ready or with untolerated NoExecute
+// taints as the sync sources, cordoned nodes could still serve the chunks they already have.
+// Nodes with unknown state are regarded as available.
+type NodeAvailability struct{}
+
+func New(_ *runtime.RawExtension) (framework.Plugin, error) {
+	return &NodeAvailability{}, nil
+}
+
+func (na *NodeAvailability) Name() string {
+	return Name
+}
+
+func (na *NodeAvailability) Filter(ctx context.Context, chunkInfo framework.ChunkInfo, nodeInfo *framework.NodeInfo, nodeTracker api.NodeTracker, _ *cache.Cache) framework.Status {
+	if nodeInfo != nil && !sourceAvailable(nodeInfo.State, chunkInfo.Tolerations) {
+		return framework.Status{Code: framework.UnschedulableStatus}
+	}
+	if !targetAvailable(nodeTracker.Status.Node, chunkInfo.Tolerations) {
+		return framework.Status{Code: framework.UnschedulableStatus}
+	}
+	return framework.Status{Code: framework.SuccessStatus}
+}
+
+func sourceAvailable(state *api.NodeState, tolerations []corev1.Toleration) bool {
+	if state == nil {
+		return true
+	}
+	if !state.Ready {
+		return false
+	}
+	return tolerated(state.Taints, tolerations, corev1.TaintEffectNoExecute)
+}
+
+func targetAvailable(state *api.NodeState, tolerations []corev1.Toleration) bool {
+	if state == nil {
+		return true
+	}
+	if !state.Ready {
+		return false
+	}
+	// Cordoned nodes are regarded as tainted with node.kubernetes.io/unschedulable:NoSchedule,
+	// the same as the kube-scheduler, so they could be tolerated as well.
+	taints := state.Taints
+	if state.Unschedulable {
+		taints = append(taints[:len(taints):len(taints)], corev1.Taint{
+			Key:    corev1.TaintNodeUnschedulable,
+			Effect: corev1.TaintEffectNoSchedule,
+		})
+	}
+	return tolerated(taints, tolerations, corev1.TaintEffectNoSchedule, corev1.TaintEffectNoExecute)
+}
+
+// tolerated returns true if all the taints with the given effects are tolerated.
+func tolerated(taints []corev1.Taint, tolerations []corev1.Toleration, effects ...corev1.TaintEffect) bool {
+	for i := range taints {
+		taint := &taints[i]
+		if !hasEffect(taint.Effect, effects) {
+			continue
+		}
+		if !toleratesTaint(tolerations, taint) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasEffect(effect corev1.TaintEffect, effects []corev1.TaintEffect) bool {
+	for _, e := range effects {
+		if e == effect {
+			return true
+		}
+	}
+	return false
+}
+
+func toleratesTaint(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeavailability
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	"github.com/inftyai/manta/test/util/wrapper"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestFilter(t *testing.T) {
+	gpuTaint := corev1.Taint{Key: "nvidia.com/gpu", Effect: corev1.TaintEffectNoSchedule}
+	evictTaint := corev1.Taint{Key: "maintenance", Effect: corev1.TaintEffectNoExecute}
+	preferTaint := corev1.Taint{Key: "pool", Value: "spot", Effect: corev1.TaintEffectPreferNoSchedule}
+
+	gpuToleration := corev1.Toleration{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists}
+	cordonToleration := corev1.Toleration{Key: corev1.TaintNodeUnschedulable, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+
+	testCases := []struct {
+		name        string
+		tolerations []corev1.Toleration
+		nodeInfo    *framework.NodeInfo
+		nodeTracker api.NodeTracker
+		wantStatus  framework.Status
+	}{
+		{
+			name:        "unknown node state",
+			nodeTracker: *wrapper.MakeNodeTracker("node1").Obj(),
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+		},
+		{
+			name:        "ready node",
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, false, preferTaint).Obj(),
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+		},
+		{
+			name:        "not ready node",
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(false, false).Obj(),
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+		},
+		{
+			name:        "cordoned node",
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, true).Obj(),
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+		},
+		{
+			name:        "cordoned node tolerated",
+			tolerations: []corev1.Toleration{cordonToleration},
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, true).Obj(),
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+		},
+		{
+			name:        "tainted node",
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, false, gpuTaint).Obj(),
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+		},
+		{
+			name:        "tainted node tolerated",
+			tolerations: []corev1.Toleration{gpuToleration},
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, false, gpuTaint).Obj(),
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+		},
+		{
+			name:        "tainted node partially tolerated",
+			tolerations: []corev1.Toleration{gpuToleration},
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, false, gpuTaint, evictTaint).Obj(),
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+		},
+		{
+			name:        "cordoned source",
+			nodeInfo:    &framework.NodeInfo{Name: "node2", State: &api.NodeState{Ready: true, Unschedulable: true, Taints: []corev1.Taint{gpuTaint}}},
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, false).Obj(),
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+		},
+		{
+			name:        "not ready source",
+			nodeInfo:    &framework.NodeInfo{Name: "node2", State: &api.NodeState{Ready: false}},
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, false).Obj(),
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+		},
+		{
+			name:        "source tainted with NoExecute",
+			nodeInfo:    &framework.NodeInfo{Name: "node2", State: &api.NodeState{Ready: true, Taints: []corev1.Taint{evictTaint}}},
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, false).Obj(),
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+		},
+		{
+			name:        "source tainted with NoExecute tolerated",
+			tolerations: []corev1.Toleration{{Key: "maintenance", Operator: corev1.TolerationOpExists}},
+			nodeInfo:    &framework.NodeInfo{Name: "node2", State: &api.NodeState{Ready: true, Taints: []corev1.Taint{evictTaint}}},
+			nodeTracker: *wrapper.MakeNodeTracker("node1").NodeState(true, false).Obj(),
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			plugin, err := New(nil)
+			if err != nil {
+				t.Fatalf("failed to construct plugin: %v", err)
+			}
+
+			chunk := framework.ChunkInfo{Name: "chunk1", Tolerations: tc.tolerations}
+			status := plugin.(*NodeAvailability).Filter(ctx, chunk, tc.nodeInfo, tc.nodeTracker, nil)
+			if diff := cmp.Diff(tc.wantStatus, status); diff != "" {
+				t.Errorf("unexpected status (-want +got): %s", diff)
+			}
+		})
+	}
+}
@@ -22,28 +22,49 @@ import (
 	api "github.com/inftyai/manta/api/v1alpha1"
 	"github.com/inftyai/manta/pkg/dispatcher/cache"
 	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+var _ framework.PreFilterPlugin = &NodeSelector{}
 var _ framework.FilterPlugin = &NodeSelector{}
 
+const (
+	Name = "NodeSelector"
+
+	// selectorKey is the key of the selector stored in the cache, node names can't contain
+	// the slash so it will not conflict with the node level states.
+	selectorKey = Name + "/selector"
+)
+
 type NodeSelector struct{}
 
-func New() (framework.Plugin, error) {
+func New(_ *runtime.RawExtension) (framework.Plugin, error) {
 	return &NodeSelector{}, nil
 }
 
 func (ns *NodeSelector) Name() string {
-	return "NodeSelector"
+	return Name
+}
+
+// PreFilter builds the selector once per chunk rather than once per node.
+func (ns *NodeSelector) PreFilter(ctx context.Context, chunkInfo framework.ChunkInfo, cache *cache.Cache) framework.Status {
+	cache.Store(selectorKey, labels.SelectorFromSet(chunkInfo.NodeSelector))
+	return framework.Status{Code: framework.SuccessStatus}
 }
 
 func (ns *NodeSelector) Filter(ctx context.Context, chunkInfo framework.ChunkInfo, _ *framework.NodeInfo, nodeTracker api.NodeTracker, cache *cache.Cache) framework.Status {
-	// In a big cluster, this is serious maybe we should have a preFilter extension point.
-	for k, v := range chunkInfo.NodeSelector {
-		value, ok := nodeTracker.Labels[k]
-		if !ok || value != v {
-			return framework.Status{Code: framework.UnschedulableStatus}
-		}
+	var selector labels.Selector
+	if cache != nil {
+		selector, _ = cache.Load(selectorKey).(labels.Selector)
+	}
+	// PreFilter is not enabled.
+	if selector == nil {
+		selector = labels.SelectorFromSet(chunkInfo.NodeSelector)
 	}
 
+	if !selector.Matches(labels.Set(nodeTracker.Labels)) {
+		return framework.Status{Code: framework.UnschedulableStatus}
+	}
 	return framework.Status{Code: framework.SuccessStatus}
 }
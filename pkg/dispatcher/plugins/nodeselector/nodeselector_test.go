@@ -22,6 +22,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/cache"
 	"github.com/inftyai/manta/pkg/dispatcher/framework"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -31,6 +32,7 @@ func TestFilter(t *testing.T) {
 		name        string
 		chunk       framework.ChunkInfo
 		nodeTracker api.NodeTracker
+		preFilter   bool
 		wantStatus  framework.Status
 	}{
 		{
@@ -59,6 +61,47 @@ func TestFilter(t *testing.T) {
 			},
 			wantStatus: framework.Status{Code: framework.UnschedulableStatus},
 		},
+		{
+			name: "nodeSelector matched with preFilter",
+			chunk: framework.ChunkInfo{
+				Name:         "chunk1",
+				NodeSelector: map[string]string{"zone": "zone1"},
+			},
+			nodeTracker: api.NodeTracker{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{"zone": "zone1", "region": "region1"},
+				},
+			},
+			preFilter:  true,
+			wantStatus: framework.Status{Code: framework.SuccessStatus},
+		},
+		{
+			name: "nodeSelector not match with preFilter",
+			chunk: framework.ChunkInfo{
+				Name:         "chunk1",
+				NodeSelector: map[string]string{"zone": "zone1", "region": "region1"},
+			},
+			nodeTracker: api.NodeTracker{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{"zone": "zone1"},
+				},
+			},
+			preFilter:  true,
+			wantStatus: framework.Status{Code: framework.UnschedulableStatus},
+		},
+		{
+			name: "empty nodeSelector with preFilter",
+			chunk: framework.ChunkInfo{
+				Name: "chunk1",
+			},
+			nodeTracker: api.NodeTracker{
+				ObjectMeta: v1.ObjectMeta{
+					Labels: map[string]string{"zone": "zone1"},
+				},
+			},
+			preFilter:  true,
+			wantStatus: framework.Status{Code: framework.SuccessStatus},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -66,14 +109,22 @@ func TestFilter(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			plugin, err := New()
+			plugin, err := New(nil)
 			if err != nil {
 				t.Errorf("failed to construct plugin: %v", err)
 			}
 
 			ns := plugin.(*NodeSelector)
 
-			gotStatus := ns.Filter(ctx, tc.chunk, nil, tc.nodeTracker, nil)
+			var c *cache.Cache
+			if tc.preFilter {
+				c = cache.NewCache().Snapshot()
+				if status := ns.PreFilter(ctx, tc.chunk, c); status.Code != framework.SuccessStatus {
+					t.Errorf("unexpected preFilter status: %v", status)
+				}
+			}
+
+			gotStatus := ns.Filter(ctx, tc.chunk, nil, tc.nodeTracker, c)
 			if diff := cmp.Diff(gotStatus, tc.wantStatus); diff != "" {
 				t.Errorf("unexpected status, diff: %v", diff)
 			}
@@ -0,0 +1,39 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/diskaware"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/loadaware"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/nodeaffinity"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/nodeavailability"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/nodeselector"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins/topology"
+)
+
+// NewInTreeRegistry returns the registry of all the in-tree plugins.
+func NewInTreeRegistry() framework.Registry {
+	return framework.Registry{
+		nodeavailability.Name: nodeavailability.New,
+		nodeselector.Name:     nodeselector.New,
+		nodeaffinity.Name:     nodeaffinity.New,
+		diskaware.Name:        diskaware.New,
+		topology.Name:         topology.New,
+		loadaware.Name:        loadaware.New,
+	}
+}
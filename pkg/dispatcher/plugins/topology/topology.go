@@ -0,0 +1,99 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"context"
+	"encoding/json"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/cache"
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ framework.FilterPlugin = &Topology{}
+var _ framework.ScorePlugin = &Topology{}
+
+const (
+	Name = "Topology"
+
+	sameZoneScore   = 100
+	sameRegionScore = 50
+)
+
+// Args is the arguments of the Topology plugin.
+type Args struct {
+	// ForbidCrossRegion forbids syncing chunks across regions, the chunk will be downloaded
+	// from the hub instead once no sources are in the same region with the targets.
+	// Nodes without the region label are regarded as in the same region with any other nodes.
+	ForbidCrossRegion bool `json:"forbidCrossRegion,omitempty"`
+}
+
+// Topology prefers the sync sources in the same zone with the target, then the same region,
+// based on the well-known topology labels mirrored from the nodes.
+type Topology struct {
+	forbidCrossRegion bool
+}
+
+func New(args *runtime.RawExtension) (framework.Plugin, error) {
+	pluginArgs := Args{}
+	if args != nil && len(args.Raw) > 0 {
+		if err := json.Unmarshal(args.Raw, &pluginArgs); err != nil {
+			return nil, err
+		}
+	}
+	return &Topology{forbidCrossRegion: pluginArgs.ForbidCrossRegion}, nil
+}
+
+func (t *Topology) Name() string {
+	return Name
+}
+
+func (t *Topology) Filter(ctx context.Context, _ framework.ChunkInfo, nodeInfo *framework.NodeInfo, nodeTracker api.NodeTracker, _ *cache.Cache) framework.Status {
+	// Downloading from the hub has nothing to do with the topology.
+	if nodeInfo == nil || !t.forbidCrossRegion {
+		return framework.Status{Code: framework.SuccessStatus}
+	}
+
+	sourceRegion, targetRegion := nodeInfo.Labels[corev1.LabelTopologyRegion], nodeTracker.Labels[corev1.LabelTopologyRegion]
+	if sourceRegion != "" && targetRegion != "" && sourceRegion != targetRegion {
+		return framework.Status{Code: framework.UnschedulableStatus}
+	}
+	return framework.Status{Code: framework.SuccessStatus}
+}
+
+func (t *Topology) Score(ctx context.Context, _ framework.ChunkInfo, nodeInfo *framework.NodeInfo, nodeTracker api.NodeTracker, _ *cache.Cache) float32 {
+	if nodeInfo == nil {
+		return 0
+	}
+
+	if sameTopology(corev1.LabelTopologyZone, nodeInfo.Labels, nodeTracker.Labels) {
+		return sameZoneScore
+	}
+	if sameTopology(corev1.LabelTopologyRegion, nodeInfo.Labels, nodeTracker.Labels) {
+		return sameRegionScore
+	}
+	return 0
+}
+
+// sameTopology returns true only when both nodes have the same non-empty value of the label key.
+func sameTopology(key string, source, target map[string]string) bool {
+	value := source[key]
+	return value != "" && value == target[key]
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package topology
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/dispatcher/framework"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestTopology(t *testing.T) {
+	labels := func(zone, region string) map[string]string {
+		return map[string]string{corev1.LabelTopologyZone: zone, corev1.LabelTopologyRegion: region}
+	}
+
+	testCases := []struct {
+		name        string
+		args        *runtime.RawExtension
+		nodeInfo    *framework.NodeInfo
+		nodeTracker api.NodeTracker
+		wantStatus  framework.Status
+		wantScore   float32
+	}{
+		{
+			name:        "download task",
+			args:        &runtime.RawExtension{Raw: []byte(`{"forbidCrossRegion": true}`)},
+			nodeTracker: api.NodeTracker{ObjectMeta: v1.ObjectMeta{Labels: labels("zone1", "region1")}},
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+			wantScore:   0,
+		},
+		{
+			name:        "same zone",
+			nodeInfo:    &framework.NodeInfo{Name: "node1", Labels: labels("zone1", "region1")},
+			nodeTracker: api.NodeTracker{ObjectMeta: v1.ObjectMeta{Labels: labels("zone1", "region1")}},
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+			wantScore:   sameZoneScore,
+		},
+		{
+			name:        "same region",
+			nodeInfo:    &framework.NodeInfo{Name: "node1", Labels: labels("zone1", "region1")},
+			nodeTracker: api.NodeTracker{ObjectMeta: v1.ObjectMeta{Labels: labels("zone2", "region1")}},
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+			wantScore:   sameRegionScore,
+		},
+		{
+			name:        "cross region",
+			nodeInfo:    &framework.NodeInfo{Name: "node1", Labels: labels("zone1", "region1")},
+			nodeTracker: api.NodeTracker{ObjectMeta: v1.ObjectMeta{Labels: labels("zone2", "region2")}},
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+			wantScore:   0,
+		},
+		{
+			name:        "cross region forbidden",
+			args:        &runtime.RawExtension{Raw: []byte(`{"forbidCrossRegion": true}`)},
+			nodeInfo:    &framework.NodeInfo{Name: "node1", Labels: labels("zone1", "region1")},
+			nodeTracker: api.NodeTracker{ObjectMeta: v1.ObjectMeta{Labels: labels("zone2", "region2")}},
+			wantStatus:  framework.Status{Code: framework.UnschedulableStatus},
+			wantScore:   0,
+		},
+		{
+			name:        "no topology labels",
+			args:        &runtime.RawExtension{Raw: []byte(`{"forbidCrossRegion": true}`)},
+			nodeInfo:    &framework.NodeInfo{Name: "node1"},
+			nodeTracker: api.NodeTracker{ObjectMeta: v1.ObjectMeta{Labels: labels("zone2", "region2")}},
+			wantStatus:  framework.Status{Code: framework.SuccessStatus},
+			wantScore:   0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			plugin, err := New(tc.args)
+			if err != nil {
+				t.Fatalf("failed to construct plugin: %v", err)
+			}
+			topology := plugin.(*Topology)
+
+			gotStatus := topology.Filter(ctx, framework.ChunkInfo{}, tc.nodeInfo, tc.nodeTracker, nil)
+			if diff := cmp.Diff(tc.wantStatus, gotStatus); diff != "" {
+				t.Errorf("unexpected status, diff: %v", diff)
+			}
+			gotScore := topology.Score(ctx, framework.ChunkInfo{}, tc.nodeInfo, tc.nodeTracker, nil)
+			if gotScore != tc.wantScore {
+				t.Errorf("unexpected score, want %v, got %v", tc.wantScore, gotScore)
+			}
+		})
+	}
+}
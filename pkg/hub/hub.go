@@ -0,0 +1,93 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"fmt"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+)
+
+const (
+	// SHA256Algorithm represents the sha256 checksum of the content.
+	SHA256Algorithm = "sha256"
+	// GitSHA1Algorithm represents the git blob object id, which is the sha1 checksum of
+	// the content prefixed with the git blob header "blob <size>\x00".
+	GitSHA1Algorithm = "gitsha1"
+
+	// TokenKey is the key of the token in the Secret referred by the Hub.TokenSecretRef.
+	TokenKey = "token"
+)
+
+// ObjectBody represents the object info listed from the model hub.
+type ObjectBody struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+	// LFS is set once the object is stored in git lfs, the oid is the sha256 of the content.
+	LFS *LFSBody `json:"lfs,omitempty"`
+	// Digest represents the digest of the object content, formatted as <algorithm>:<hex>,
+	// e.g. sha256:<hex> or gitsha1:<hex>, empty means the content couldn't be verified.
+	Digest string `json:"digest,omitempty"`
+}
+
+// LFSBody represents the git lfs pointer of the object.
+type LFSBody struct {
+	Oid  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// Hub is the abstraction of a model hub, it's shared by both the controller plane,
+// which lists the repo objects, and the agent, which downloads the objects.
+type Hub interface {
+	// Name returns the name of the hub, which is the same as the Hub.Name in Torrent.
+	Name() string
+	// ListRepoObjects lists all the files of the repo in the revision recursively, the files
+	// in the subfolders are named with the relative path, e.g. onnx/model.onnx, directories
+	// are not included. The object type should be converted to the api.ObjectType.
+	ListRepoObjects(repoID string, revision string) ([]*ObjectBody, error)
+	// ResolveRevision resolves the revision, e.g. a branch or a tag, to the commit it points to,
+	// hubs not able to resolve the revision should return the revision as it is.
+	ResolveRevision(repoID string, revision string) (string, error)
+	// ResolveURL returns the url to download the file of the repo in the revision.
+	ResolveURL(repoID string, revision string, path string) string
+	// Token returns the token to access the hub, empty means anonymous access.
+	Token() string
+}
+
+// NewHub returns the hub by name, the name is the Hub.Name in Torrent. The token in the
+// credentials of the Secret, if any, takes precedence over the one read from envs.
+func NewHub(name string, credentials map[string][]byte) (Hub, error) {
+	token := string(credentials[TokenKey])
+
+	switch name {
+	case api.HUGGINGFACE_MODEL_HUB:
+		hub := NewHuggingface()
+		if token != "" {
+			hub.token = token
+		}
+		return hub, nil
+	case api.MODELSCOPE_MODEL_HUB:
+		hub := NewModelScope()
+		if token != "" {
+			hub.token = token
+		}
+		return hub, nil
+	}
+	return nil, fmt.Errorf("unsupported model hub: %s", name)
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+)
+
+const (
+	huggingfaceEndpoint = "https://huggingface.co"
+)
+
+var _ Hub = &Huggingface{}
+
+// Huggingface is the Hub implementation of https://huggingface.co.
+type Huggingface struct {
+	endpoint string
+	token    string
+}
+
+// NewHuggingface returns the Huggingface hub, the endpoint can be replaced with
+// a mirror via HF_ENDPOINT env, the token is read from HF_TOKEN or HUGGING_FACE_HUB_TOKEN env.
+func NewHuggingface() *Huggingface {
+	hub := &Huggingface{endpoint: huggingfaceEndpoint}
+	if endpoint := os.Getenv("HF_ENDPOINT"); endpoint != "" {
+		hub.endpoint = endpoint
+	}
+	if token := os.Getenv("HF_TOKEN"); token != "" {
+		hub.token = token
+	} else if token := os.Getenv("HUGGING_FACE_HUB_TOKEN"); token != "" {
+		hub.token = token
+	}
+	return hub
+}
+
+func (h *Huggingface) Name() string {
+	return api.HUGGINGFACE_MODEL_HUB
+}
+
+// ListRepoObjects lists the files of the repo recursively, following the pagination of the Link header.
+func (h *Huggingface) ListRepoObjects(repoID string, revision string) (bodies []*ObjectBody, err error) {
+	// Example: "https://huggingface.co/api/models/Qwen/Qwen2.5-72B-Instruct/tree/main?recursive=true"
+	pageURL := fmt.Sprintf("%s/api/models/%s/tree/%s?recursive=true", h.endpoint, repoID, url.PathEscape(revision))
+
+	for pageURL != "" {
+		var objects []*ObjectBody
+		objects, pageURL, err = h.listPage(pageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range objects {
+			// Directories are listed as well, but they're created along with the files.
+			if obj.Type != string(api.FileObjectType) {
+				continue
+			}
+			// The oid of lfs files refers to the pointer file rather than the content.
+			if obj.LFS != nil {
+				obj.Size = obj.LFS.Size
+				obj.Digest = SHA256Algorithm + ":" + obj.LFS.Oid
+			} else {
+				obj.Digest = GitSHA1Algorithm + ":" + obj.Oid
+			}
+			bodies = append(bodies, obj)
+		}
+	}
+	return bodies, nil
+}
+
+// listPage lists one page of the repo tree, and returns the url of the next page, empty means the last page.
+func (h *Huggingface) listPage(pageURL string) (objects []*ObjectBody, nextURL string, err error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if h.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", h.token))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to get repo files: status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := json.Unmarshal(body, &objects); err != nil {
+		return nil, "", err
+	}
+
+	nextURL, err = nextPage(resp)
+	if err != nil {
+		return nil, "", err
+	}
+	return objects, nextURL, nil
+}
+
+// nextPage returns the url of the next page from the Link header, which looks like:
+// <https://huggingface.co/api/models/Qwen/Qwen2.5-72B-Instruct/tree/main?recursive=true&cursor=xxx>; rel="next"
+func nextPage(resp *http.Response) (string, error) {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		segments := strings.Split(link, ";")
+		if len(segments) < 2 {
+			continue
+		}
+		for _, param := range segments[1:] {
+			if strings.ReplaceAll(strings.TrimSpace(param), " ", "") != `rel="next"` {
+				continue
+			}
+			target := strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			// The url could be relative to the current page.
+			next, err := resp.Request.URL.Parse(target)
+			if err != nil {
+				return "", err
+			}
+			return next.String(), nil
+		}
+	}
+	return "", nil
+}
+
+func (h *Huggingface) ResolveRevision(repoID string, revision string) (string, error) {
+	// Example: "https://huggingface.co/api/models/Qwen/Qwen2.5-72B-Instruct/revision/main"
+	revisionURL := fmt.Sprintf("%s/api/models/%s/revision/%s", h.endpoint, repoID, url.PathEscape(revision))
+
+	req, err := http.NewRequest("GET", revisionURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if h.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", h.token))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve revision %s: status code %d", revision, resp.StatusCode)
+	}
+
+	info := struct {
+		Sha string `json:"sha"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", err
+	}
+	if info.Sha == "" {
+		return "", fmt.Errorf("failed to resolve revision %s: empty commit", revision)
+	}
+	return info.Sha, nil
+}
+
+func (h *Huggingface) ResolveURL(repoID string, revision string, path string) string {
+	// Example: "https://huggingface.co/Qwen/Qwen2.5-72B-Instruct/resolve/main/model-00031-of-00037.safetensors"
+	return fmt.Sprintf("%s/%s/resolve/%s/%s", h.endpoint, repoID, revision, path)
+}
+
+func (h *Huggingface) Token() string {
+	return h.token
+}
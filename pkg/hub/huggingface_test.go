@@ -0,0 +1,164 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestListRepoFiles(t *testing.T) {
+	testCases := []struct {
+		name     string
+		repoID   string
+		revision string
+		wantErr  bool
+	}{
+		{
+			name:     "right repo",
+			repoID:   "Qwen/Qwen2-7B-Instruct",
+			revision: "main",
+		},
+		{
+			name:     "non-existence repo",
+			repoID:   "QQwen/Qwen2-7B-Instruct",
+			wantErr:  true,
+			revision: " main",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects, err := NewHuggingface().ListRepoObjects(tc.repoID, tc.revision)
+			if err != nil && !tc.wantErr {
+				t.Fatalf("unexpected err: %v", err)
+			}
+
+			if err == nil && tc.wantErr {
+				t.Fatal("no error returned")
+			}
+
+			if err == nil && len(objects) == 0 {
+				t.Fatal("empty objects")
+			}
+		})
+	}
+}
+
+func TestHuggingfaceListRepoObjects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/models/Qwen/Qwen2-7B-Instruct/tree/main" || r.URL.Query().Get("recursive") != "true" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Header().Set("Link", `<http://`+r.Host+`/api/models/Qwen/Qwen2-7B-Instruct/tree/main?recursive=true&cursor=page2>; rel="next"`)
+			_, _ = w.Write([]byte(`[
+				{"type":"file","oid":"4f1b5d9a","size":663,"path":"config.json"},
+				{"type":"directory","oid":"1a2b3c4d","size":0,"path":"onnx"}
+			]`))
+		case "page2":
+			_, _ = w.Write([]byte(`[
+				{"type":"file","oid":"9c2d6e1b","size":135,"path":"onnx/model.onnx","lfs":{"oid":"c5d86a5f","size":3945441440,"pointerSize":135}},
+				{"type":"file","oid":"7e8f9a0b","size":42,"path":"onnx/tokenizer/vocab.txt"}
+			]`))
+		default:
+			http.Error(w, "bad cursor", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	hub := &Huggingface{endpoint: server.URL}
+
+	objects, err := hub.ListRepoObjects("Qwen/Qwen2-7B-Instruct", "main")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := []*ObjectBody{
+		{Path: "config.json", Type: "file", Oid: "4f1b5d9a", Size: 663, Digest: "gitsha1:4f1b5d9a"},
+		{Path: "onnx/model.onnx", Type: "file", Oid: "9c2d6e1b", Size: 3945441440, LFS: &LFSBody{Oid: "c5d86a5f", Size: 3945441440}, Digest: "sha256:c5d86a5f"},
+		{Path: "onnx/tokenizer/vocab.txt", Type: "file", Oid: "7e8f9a0b", Size: 42, Digest: "gitsha1:7e8f9a0b"},
+	}
+	if diff := cmp.Diff(want, objects); diff != "" {
+		t.Errorf("unexpected objects, diff %v", diff)
+	}
+
+	if _, err := hub.ListRepoObjects("Qwen/Qwen2-7B-Instruct", "unknown"); err == nil {
+		t.Error("expected error here")
+	}
+}
+
+func TestHuggingfaceResolveRevision(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/models/Qwen/Qwen2-7B-Instruct/revision/main":
+			_, _ = w.Write([]byte(`{"id":"Qwen/Qwen2-7B-Instruct","sha":"f2826a00ceef68f0f2b946d945ecc0477ce4450c"}`))
+		case "/api/models/Qwen/Qwen2-7B-Instruct/revision/refs%2Fpr%2F1":
+			_, _ = w.Write([]byte(`{"id":"Qwen/Qwen2-7B-Instruct","sha":"0d4b76e1efeb5eb6f6b5e757c79870472e04bd3a"}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name       string
+		revision   string
+		wantCommit string
+		wantErr    bool
+	}{
+		{
+			name:       "branch",
+			revision:   "main",
+			wantCommit: "f2826a00ceef68f0f2b946d945ecc0477ce4450c",
+		},
+		{
+			name:       "pull request ref",
+			revision:   "refs/pr/1",
+			wantCommit: "0d4b76e1efeb5eb6f6b5e757c79870472e04bd3a",
+		},
+		{
+			name:     "non-existence revision",
+			revision: "unknown",
+			wantErr:  true,
+		},
+	}
+
+	hub := &Huggingface{endpoint: server.URL}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			commit, err := hub.ResolveRevision("Qwen/Qwen2-7B-Instruct", tc.revision)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("no error returned")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if commit != tc.wantCommit {
+				t.Errorf("unexpected commit, want %s, got %s", tc.wantCommit, commit)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+)
+
+const (
+	modelScopeEndpoint = "https://www.modelscope.cn"
+)
+
+var _ Hub = &ModelScope{}
+
+// ModelScope is the Hub implementation of https://www.modelscope.cn.
+type ModelScope struct {
+	endpoint string
+	token    string
+}
+
+// NewModelScope returns the ModelScope hub, the endpoint can be replaced via MODELSCOPE_ENDPOINT env,
+// the token is read from MODELSCOPE_API_TOKEN env.
+func NewModelScope() *ModelScope {
+	hub := &ModelScope{endpoint: modelScopeEndpoint}
+	if endpoint := os.Getenv("MODELSCOPE_ENDPOINT"); endpoint != "" {
+		hub.endpoint = endpoint
+	}
+	if token := os.Getenv("MODELSCOPE_API_TOKEN"); token != "" {
+		hub.token = token
+	}
+	return hub
+}
+
+type modelScopeFile struct {
+	// Id is the git object id.
+	Id     string `json:"Id"`
+	Path   string `json:"Path"`
+	Type   string `json:"Type"`
+	Size   int64  `json:"Size"`
+	Sha256 string `json:"Sha256"`
+}
+
+type modelScopeResponse struct {
+	Code    int    `json:"Code"`
+	Message string `json:"Message"`
+	Success bool   `json:"Success"`
+	Data    struct {
+		Files []modelScopeFile `json:"Files"`
+	} `json:"Data"`
+}
+
+func (m *ModelScope) Name() string {
+	return api.MODELSCOPE_MODEL_HUB
+}
+
+func (m *ModelScope) ListRepoObjects(repoID string, revision string) (bodies []*ObjectBody, err error) {
+	// Example: "https://www.modelscope.cn/api/v1/models/Qwen/Qwen2.5-72B-Instruct/repo/files?Revision=master&Recursive=true"
+	listURL := fmt.Sprintf("%s/api/v1/models/%s/repo/files?Revision=%s&Recursive=true", m.endpoint, repoID, url.QueryEscape(revision))
+
+	req, err := http.NewRequest("GET", listURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if m.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", m.token))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get repo files: status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	info := modelScopeResponse{}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, err
+	}
+	if !info.Success {
+		return nil, fmt.Errorf("failed to get repo files: %s", info.Message)
+	}
+
+	for _, file := range info.Data.Files {
+		// Directories are listed as well, but they're created along with the files.
+		if file.Type == "tree" {
+			continue
+		}
+		obj := &ObjectBody{
+			Path: file.Path,
+			Type: string(api.FileObjectType),
+			Oid:  file.Sha256,
+			Size: file.Size,
+		}
+		// Small files are not tracked by LFS, they may have no sha256.
+		if obj.Oid == "" {
+			obj.Oid = file.Id
+			obj.Digest = GitSHA1Algorithm + ":" + file.Id
+		} else {
+			obj.Digest = SHA256Algorithm + ":" + file.Sha256
+		}
+		bodies = append(bodies, obj)
+	}
+	return bodies, nil
+}
+
+// ResolveRevision returns the revision as it is, because ModelScope doesn't expose
+// the commits of the branches and tags.
+func (m *ModelScope) ResolveRevision(repoID string, revision string) (string, error) {
+	return revision, nil
+}
+
+func (m *ModelScope) ResolveURL(repoID string, revision string, path string) string {
+	// Example: "https://www.modelscope.cn/api/v1/models/Qwen/Qwen2.5-72B-Instruct/repo?Revision=master&FilePath=config.json"
+	return fmt.Sprintf("%s/api/v1/models/%s/repo?Revision=%s&FilePath=%s", m.endpoint, repoID, url.QueryEscape(revision), url.QueryEscape(path))
+}
+
+func (m *ModelScope) Token() string {
+	return m.token
+}
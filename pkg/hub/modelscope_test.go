@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestModelScopeListRepoObjects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/models/Qwen/Qwen2-7B-Instruct/repo/files" || r.URL.Query().Get("Revision") != "master" || r.URL.Query().Get("Recursive") != "true" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Code":200,"Success":true,"Data":{"Files":[
+			{"Id":"4f1b5d9a","Path":"config.json","Type":"blob","Size":663,"Sha256":""},
+			{"Id":"9c2d6e1b","Path":"model-00001-of-00004.safetensors","Type":"blob","Size":3945441440,"Sha256":"c5d86a5f"},
+			{"Id":"1a2b3c4d","Path":"onnx","Type":"tree","Size":0},
+			{"Id":"5e6f7a8b","Path":"onnx/model.onnx","Type":"blob","Size":1024,"Sha256":"d4e5f6a7"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	hub := &ModelScope{endpoint: server.URL}
+
+	objects, err := hub.ListRepoObjects("Qwen/Qwen2-7B-Instruct", "master")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := []*ObjectBody{
+		{Path: "config.json", Type: "file", Oid: "4f1b5d9a", Size: 663, Digest: "gitsha1:4f1b5d9a"},
+		{Path: "model-00001-of-00004.safetensors", Type: "file", Oid: "c5d86a5f", Size: 3945441440, Digest: "sha256:c5d86a5f"},
+		{Path: "onnx/model.onnx", Type: "file", Oid: "d4e5f6a7", Size: 1024, Digest: "sha256:d4e5f6a7"},
+	}
+	if diff := cmp.Diff(want, objects); diff != "" {
+		t.Errorf("unexpected objects, diff %v", diff)
+	}
+
+	if _, err := hub.ListRepoObjects("Qwen/Qwen2-7B-Instruct", "main"); err == nil {
+		t.Error("expected error here")
+	}
+}
+
+func TestNewHub(t *testing.T) {
+	testCases := []struct {
+		name        string
+		hubName     string
+		credentials map[string][]byte
+		wantURL     string
+		wantToken   string
+		wantError   bool
+	}{
+		{
+			name:      "huggingface",
+			hubName:   "Huggingface",
+			wantURL:   "https://huggingface.co/Qwen/Qwen2-7B-Instruct/resolve/main/config.json",
+			wantToken: "env-token",
+		},
+		{
+			name:        "huggingface with token in secret",
+			hubName:     "Huggingface",
+			credentials: map[string][]byte{TokenKey: []byte("secret-token")},
+			wantURL:     "https://huggingface.co/Qwen/Qwen2-7B-Instruct/resolve/main/config.json",
+			wantToken:   "secret-token",
+		},
+		{
+			name:        "huggingface with secret missing the token",
+			hubName:     "Huggingface",
+			credentials: map[string][]byte{"HF_TOKEN": []byte("secret-token")},
+			wantURL:     "https://huggingface.co/Qwen/Qwen2-7B-Instruct/resolve/main/config.json",
+			wantToken:   "env-token",
+		},
+		{
+			name:      "modelScope",
+			hubName:   "ModelScope",
+			wantURL:   "https://www.modelscope.cn/api/v1/models/Qwen/Qwen2-7B-Instruct/repo?Revision=main&FilePath=config.json",
+			wantToken: "env-token",
+		},
+		{
+			name:        "modelScope with token in secret",
+			hubName:     "ModelScope",
+			credentials: map[string][]byte{TokenKey: []byte("secret-token")},
+			wantURL:     "https://www.modelscope.cn/api/v1/models/Qwen/Qwen2-7B-Instruct/repo?Revision=main&FilePath=config.json",
+			wantToken:   "secret-token",
+		},
+		{
+			name:      "unknown hub",
+			hubName:   "unknown",
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("HF_ENDPOINT", "")
+			t.Setenv("MODELSCOPE_ENDPOINT", "")
+			t.Setenv("HF_TOKEN", "env-token")
+			t.Setenv("MODELSCOPE_API_TOKEN", "env-token")
+
+			hub, err := NewHub(tc.hubName, tc.credentials)
+			if tc.wantError {
+				if err == nil {
+					t.Error("expected error here")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if hub.Name() != tc.hubName {
+				t.Errorf("unexpected hub name, want %s, got %s", tc.hubName, hub.Name())
+			}
+			if url := hub.ResolveURL("Qwen/Qwen2-7B-Instruct", "main", "config.json"); url != tc.wantURL {
+				t.Errorf("unexpected url, want %s, got %s", tc.wantURL, url)
+			}
+			if token := hub.Token(); token != tc.wantToken {
+				t.Errorf("unexpected token, want %s, got %s", tc.wantToken, token)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/inftyai/manta/pkg/hub"
+)
+
+const (
+	S3Scheme  = "s3"
+	OSSScheme = "oss"
+
+	// The keys of the credential Secret, they're the same with the aws cli envs.
+	AccessKeyIDKey     = "AWS_ACCESS_KEY_ID"
+	SecretAccessKeyKey = "AWS_SECRET_ACCESS_KEY"
+	RegionKey          = "AWS_REGION"
+	EndpointKey        = "AWS_ENDPOINT_URL"
+
+	defaultRegion = "us-east-1"
+	// presignExpires should be long enough to start the download.
+	presignExpires = time.Hour
+)
+
+// IsObjectStoreURI returns true if the uri refers to an object storage, like s3:// or oss://.
+func IsObjectStoreURI(uri string) bool {
+	return strings.HasPrefix(uri, S3Scheme+"://") || strings.HasPrefix(uri, OSSScheme+"://")
+}
+
+// ObjectStore is a client of the S3-compatible object storage, the requests are
+// signed with AWS signature version 4 via query parameters, so the urls can be
+// downloaded directly without any other headers.
+type ObjectStore struct {
+	scheme string
+	bucket string
+	// prefix is the object key once the uri refers to an object, or the key prefix
+	// without trailing slash once the uri refers to a directory.
+	prefix string
+	// directory means the uri ends with a slash, so it never refers to an object.
+	directory bool
+	// endpoint is formatted as <scheme>://<host>, like https://s3.us-east-1.amazonaws.com.
+	endpoint string
+	// pathStyle means the bucket is part of the url path rather than the host.
+	pathStyle bool
+
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+
+	// expires is the valid duration of the presigned urls.
+	expires time.Duration
+	// now is replaceable for testing.
+	now func() time.Time
+}
+
+// NewObjectStore parses the uri, which looks like:
+//   - s3://<bucket>/<path-to-your-files>
+//   - oss://<bucket>.<endpoint>/<path-to-your-files>
+//
+// and constructs the client with the credentials in the Secret, once credentials
+// is empty, the objects will be accessed anonymously.
+func NewObjectStore(uri string, credentials map[string][]byte) (*ObjectStore, error) {
+	if !IsObjectStoreURI(uri) {
+		return nil, fmt.Errorf("unsupported object store uri: %s", uri)
+	}
+
+	splits := strings.SplitN(uri, "://", 2)
+	scheme := splits[0]
+	host, prefix, _ := strings.Cut(splits[1], "/")
+
+	store := &ObjectStore{
+		scheme:          scheme,
+		bucket:          host,
+		prefix:          strings.Trim(prefix, "/"),
+		directory:       strings.HasSuffix(prefix, "/"),
+		region:          string(credentials[RegionKey]),
+		accessKeyID:     string(credentials[AccessKeyIDKey]),
+		secretAccessKey: string(credentials[SecretAccessKeyKey]),
+		expires:         presignExpires,
+		now:             time.Now,
+	}
+
+	if scheme == OSSScheme {
+		bucket, endpoint, found := strings.Cut(host, ".")
+		if !found || bucket == "" || endpoint == "" {
+			return nil, fmt.Errorf("unexpected oss uri, should be oss://<bucket>.<endpoint>/<path>: %s", uri)
+		}
+		store.bucket = bucket
+		store.endpoint = "https://" + endpoint
+		if store.region == "" {
+			// e.g. oss-cn-hangzhou.aliyuncs.com refers to region cn-hangzhou.
+			store.region = strings.TrimPrefix(strings.Split(endpoint, ".")[0], "oss-")
+		}
+	}
+
+	if store.bucket == "" {
+		return nil, fmt.Errorf("bucket is required in uri: %s", uri)
+	}
+	if store.region == "" {
+		store.region = defaultRegion
+	}
+	if store.endpoint == "" {
+		store.endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", store.region)
+	}
+
+	// Customized endpoints, like the self-hosted S3-compatible servers, mostly only support path style.
+	if endpoint := string(credentials[EndpointKey]); endpoint != "" {
+		store.endpoint = strings.TrimSuffix(endpoint, "/")
+		store.pathStyle = true
+	}
+
+	return store, nil
+}
+
+// ObjectURI returns the uri of the object listed from the uri, the uri refers to the object
+// itself once it's the only object and named after the last segment of the uri, otherwise,
+// the object path is relative to the uri, see ListObjects.
+func ObjectURI(uri string, objectPath string, objectNumber int) string {
+	_, key, _ := strings.Cut(strings.SplitN(uri, "://", 2)[1], "/")
+	if objectNumber == 1 && key != "" && !strings.HasSuffix(key, "/") && path.Base(key) == objectPath {
+		return uri
+	}
+	return strings.TrimSuffix(uri, "/") + "/" + objectPath
+}
+
+// RepoName returns the name of the local repo, like s3--<bucket>--<path-to-your-files>.
+func (s *ObjectStore) RepoName() string {
+	name := s.scheme + "--" + s.bucket
+	if s.prefix != "" {
+		name += "--" + strings.ReplaceAll(s.prefix, "/", "--")
+	}
+	return name
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+// ListObjects lists all the objects under the prefix recursively, the object path
+// is relative to the prefix. The ETag is regarded as the object hash.
+// Once nothing under the prefix, the uri refers to the object itself, which is named
+// after the last segment of the uri, see ObjectURI. It errors once nothing matched.
+func (s *ObjectStore) ListObjects() (bodies []*hub.ObjectBody, err error) {
+	prefix := s.prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	bodies, err = s.listPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.prefix != "" && !s.directory {
+		name := path.Base(s.prefix)
+		// The only object named after the directory is indistinguishable from the uri referring to the object.
+		if len(bodies) == 1 && bodies[0].Path == name {
+			return nil, fmt.Errorf("key %s refers to both the object and the directory, end the uri with a slash to refer to the directory", s.prefix)
+		}
+		if len(bodies) == 0 {
+			// Objects sharing the same prefix, e.g. model.safetensors.index.json, are skipped.
+			objects, err := s.listPrefix(s.prefix)
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range objects {
+				if obj.Path == "" {
+					obj.Path = name
+					bodies = append(bodies, obj)
+				}
+			}
+		}
+	}
+
+	if len(bodies) == 0 {
+		return nil, fmt.Errorf("no objects found with key %q in bucket %s", s.prefix, s.bucket)
+	}
+	return bodies, nil
+}
+
+// listPrefix lists all the objects with the key prefix, the object path is relative to the prefix.
+func (s *ObjectStore) listPrefix(prefix string) (bodies []*hub.ObjectBody, err error) {
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		result, err := s.listObjects(query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, content := range result.Contents {
+			// Skip the directory markers.
+			if strings.HasSuffix(content.Key, "/") {
+				continue
+			}
+			bodies = append(bodies, &hub.ObjectBody{
+				Path: strings.TrimPrefix(content.Key, prefix),
+				Type: "file",
+				Oid:  strings.ToLower(strings.Trim(content.ETag, `"`)),
+				Size: content.Size,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	return bodies, nil
+}
+
+func (s *ObjectStore) listObjects(query url.Values) (*listBucketResult, error) {
+	resp, err := http.Get(s.signURL(http.MethodGet, "", query))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list objects: status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &listBucketResult{}
+	if err := xml.Unmarshal(body, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ResolveURL returns the presigned url to download the object, the path is relative
+// to the prefix, empty path refers to the prefix itself.
+func (s *ObjectStore) ResolveURL(objectPath string) string {
+	key := s.prefix
+	if objectPath != "" {
+		key = path.Join(s.prefix, objectPath)
+	}
+	return s.signURL(http.MethodGet, key, url.Values{})
+}
+
+// signURL returns the url signed with AWS signature version 4 via query parameters,
+// see https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func (s *ObjectStore) signURL(method string, key string, query url.Values) string {
+	endpoint, _ := url.Parse(s.endpoint)
+	host := endpoint.Host
+	urlPath := "/" + key
+	if s.pathStyle {
+		urlPath = "/" + s.bucket
+		if key != "" {
+			urlPath += "/" + key
+		}
+	} else {
+		host = s.bucket + "." + host
+	}
+
+	if s.accessKeyID != "" {
+		now := s.now().UTC()
+		date := now.Format("20060102")
+		scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, s.region)
+
+		query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+		query.Set("X-Amz-Credential", s.accessKeyID+"/"+scope)
+		query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+		query.Set("X-Amz-Expires", fmt.Sprintf("%d", int64(s.expires.Seconds())))
+		query.Set("X-Amz-SignedHeaders", "host")
+
+		canonicalRequest := strings.Join([]string{
+			method,
+			escapePath(urlPath),
+			canonicalQuery(query),
+			"host:" + host + "\n",
+			"host",
+			"UNSIGNED-PAYLOAD",
+		}, "\n")
+
+		stringToSign := strings.Join([]string{
+			"AWS4-HMAC-SHA256",
+			now.Format("20060102T150405Z"),
+			scope,
+			hexSHA256([]byte(canonicalRequest)),
+		}, "\n")
+
+		signingKey := hmacSHA256([]byte("AWS4"+s.secretAccessKey), date)
+		signingKey = hmacSHA256(signingKey, s.region)
+		signingKey = hmacSHA256(signingKey, "s3")
+		signingKey = hmacSHA256(signingKey, "aws4_request")
+
+		query.Set("X-Amz-Signature", fmt.Sprintf("%x", hmacSHA256(signingKey, stringToSign)))
+	}
+
+	signedURL := endpoint.Scheme + "://" + host + escapePath(urlPath)
+	if len(query) > 0 {
+		signedURL += "?" + canonicalQuery(query)
+	}
+	return signedURL
+}
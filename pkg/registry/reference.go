@@ -0,0 +1,123 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ImageScheme = "img"
+
+	dockerHubRegistry = "docker.io"
+	// dockerHubEndpoint is the real address of the docker hub registry.
+	dockerHubEndpoint = "registry-1.docker.io"
+	defaultTag        = "latest"
+)
+
+// IsImageURI returns true if the uri refers to an OCI image or artifact, like img://nginx:1.14.2.
+func IsImageURI(uri string) bool {
+	return strings.HasPrefix(uri, ImageScheme+"://")
+}
+
+// Reference represents an image reference, like registry.example.com/models/qwen:v1
+// or registry.example.com/models/qwen@sha256:<hex>.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses the uri, which looks like:
+//   - img://nginx:1.14.2, which refers to docker.io/library/nginx:1.14.2
+//   - img://<registry>/<repository>:<tag>
+//   - img://<registry>/<repository>@<digest>
+func ParseReference(uri string) (*Reference, error) {
+	if !IsImageURI(uri) {
+		return nil, fmt.Errorf("unsupported image uri: %s", uri)
+	}
+
+	name := strings.TrimPrefix(uri, ImageScheme+"://")
+	ref := &Reference{}
+
+	if before, after, found := strings.Cut(name, "@"); found {
+		name, ref.Digest = before, after
+		if !strings.Contains(ref.Digest, ":") {
+			return nil, fmt.Errorf("unexpected digest in image uri: %s", uri)
+		}
+	}
+
+	// The tag is after the last colon, but the colon should not be a port of the registry.
+	if pos := strings.LastIndex(name, ":"); pos > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:pos], name[pos+1:]
+	}
+
+	// The first component is the registry only when it looks like a host.
+	if registry, repository, found := strings.Cut(name, "/"); found &&
+		(strings.ContainsAny(registry, ".:") || registry == "localhost") {
+		ref.Registry, ref.Repository = registry, repository
+	} else {
+		ref.Registry, ref.Repository = dockerHubRegistry, name
+	}
+
+	if ref.Registry == dockerHubRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	if ref.Repository == "" {
+		return nil, fmt.Errorf("repository is required in image uri: %s", uri)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+	return ref, nil
+}
+
+// Reference returns the tag or the digest of the image, digest takes precedence.
+func (r *Reference) Reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// Revision returns the reference in the format suitable for a directory name.
+func (r *Reference) Revision() string {
+	return strings.ReplaceAll(r.Reference(), ":", "-")
+}
+
+// RepoName returns the name of the local repo, like img--<registry>--<repository>.
+func (r *Reference) RepoName() string {
+	return strings.NewReplacer("/", "--", ":", "-").Replace(ImageScheme + "--" + r.Registry + "--" + r.Repository)
+}
+
+// BlobURI returns the uri refers to the blob of the repository, like img://<registry>/<repository>@<digest>.
+func (r *Reference) BlobURI(digest string) string {
+	return fmt.Sprintf("%s://%s/%s@%s", ImageScheme, r.Registry, r.Repository, digest)
+}
+
+// ChunkHash converts the digest to the hash of the chunk name, e.g. sha256:<hex> to sha256-<hex>,
+// because colon is not allowed in the object names.
+func ChunkHash(digest string) string {
+	return strings.Replace(digest, ":", "-", 1)
+}
+
+// DigestFromChunkHash is the reverse of ChunkHash.
+func DigestFromChunkHash(hash string) string {
+	return strings.Replace(hash, "-", ":", 1)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseReference(t *testing.T) {
+	testCases := []struct {
+		name         string
+		uri          string
+		want         *Reference
+		wantRepoName string
+		wantRevision string
+		wantError    bool
+	}{
+		{
+			name:         "docker hub official image",
+			uri:          "img://nginx:1.14.2",
+			want:         &Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.14.2"},
+			wantRepoName: "img--docker.io--library--nginx",
+			wantRevision: "1.14.2",
+		},
+		{
+			name:         "default tag",
+			uri:          "img://inftyai/qwen2",
+			want:         &Reference{Registry: "docker.io", Repository: "inftyai/qwen2", Tag: "latest"},
+			wantRepoName: "img--docker.io--inftyai--qwen2",
+			wantRevision: "latest",
+		},
+		{
+			name:         "registry with port",
+			uri:          "img://localhost:5000/models/qwen2:v1",
+			want:         &Reference{Registry: "localhost:5000", Repository: "models/qwen2", Tag: "v1"},
+			wantRepoName: "img--localhost-5000--models--qwen2",
+			wantRevision: "v1",
+		},
+		{
+			name:         "digest",
+			uri:          "img://registry.example.com/models/qwen2@sha256:abcd",
+			want:         &Reference{Registry: "registry.example.com", Repository: "models/qwen2", Digest: "sha256:abcd"},
+			wantRepoName: "img--registry.example.com--models--qwen2",
+			wantRevision: "sha256-abcd",
+		},
+		{
+			name:      "malformed digest",
+			uri:       "img://registry.example.com/models/qwen2@abcd",
+			wantError: true,
+		},
+		{
+			name:      "not an image",
+			uri:       "s3://models/qwen2",
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, err := ParseReference(tc.uri)
+			if tc.wantError {
+				if err == nil {
+					t.Error("expected error here")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, ref); diff != "" {
+				t.Errorf("unexpected reference, diff %v", diff)
+			}
+			if ref.RepoName() != tc.wantRepoName {
+				t.Errorf("unexpected repo name, want %s, got %s", tc.wantRepoName, ref.RepoName())
+			}
+			if ref.Revision() != tc.wantRevision {
+				t.Errorf("unexpected revision, want %s, got %s", tc.wantRevision, ref.Revision())
+			}
+		})
+	}
+}
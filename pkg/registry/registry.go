@@ -0,0 +1,347 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/inftyai/manta/pkg/hub"
+)
+
+const (
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex       = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	// titleAnnotation is set by tools like oras to record the file name of the layer.
+	titleAnnotation = "org.opencontainers.image.title"
+)
+
+var challengeParamRegexp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform,omitempty"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+	// Manifests is only for image index or manifest list.
+	Manifests []descriptor `json:"manifests"`
+}
+
+// Registry is a client of the OCI distribution API, it supports both anonymous access
+// and the basic or bearer token authentication.
+type Registry struct {
+	ref *Reference
+	// endpoint is formatted as <scheme>://<host>.
+	endpoint string
+
+	username string
+	password string
+	// authorization is the cached Authorization header.
+	authorization string
+}
+
+// NewRegistry parses the image uri and constructs the client with the credentials,
+// which is the data of a Secret in type kubernetes.io/dockerconfigjson. Once credentials
+// is empty, the registry will be accessed anonymously.
+func NewRegistry(uri string, credentials map[string][]byte) (*Registry, error) {
+	ref, err := ParseReference(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	host := ref.Registry
+	if host == dockerHubRegistry {
+		host = dockerHubEndpoint
+	}
+	// Follow the docker convention, local registries are accessed with plain http.
+	scheme := "https"
+	if hostname := strings.Split(host, ":")[0]; hostname == "localhost" || hostname == "127.0.0.1" {
+		scheme = "http"
+	}
+
+	registry := &Registry{ref: ref, endpoint: scheme + "://" + host}
+	if data := credentials[corev1.DockerConfigJsonKey]; len(data) > 0 {
+		if registry.username, registry.password, err = lookupAuth(data, ref.Registry); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Reference returns the parsed image reference.
+func (r *Registry) Reference() *Reference {
+	return r.ref
+}
+
+// ListObjects resolves the manifest of the image, each layer is regarded as an object,
+// the object path is the title annotation of the layer or the digest if not set.
+func (r *Registry) ListObjects() (bodies []*hub.ObjectBody, err error) {
+	m, err := r.fetchManifest(r.ref.Reference())
+	if err != nil {
+		return nil, err
+	}
+
+	if m.MediaType == mediaTypeOCIIndex || m.MediaType == mediaTypeDockerList {
+		digest, err := selectManifest(m.Manifests)
+		if err != nil {
+			return nil, err
+		}
+		if m, err = r.fetchManifest(digest); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, layer := range m.Layers {
+		path := layer.Annotations[titleAnnotation]
+		if path == "" {
+			path = ChunkHash(layer.Digest)
+		}
+		bodies = append(bodies, &hub.ObjectBody{
+			Path:   path,
+			Type:   "file",
+			Oid:    ChunkHash(layer.Digest),
+			Size:   layer.Size,
+			Digest: layer.Digest,
+		})
+	}
+	return bodies, nil
+}
+
+// ResolveBlob returns the url of the blob and the Authorization header to download it,
+// the header is empty for anonymous access.
+func (r *Registry) ResolveBlob(digest string) (blobURL string, authorization string, err error) {
+	blobURL = fmt.Sprintf("%s/v2/%s/blobs/%s", r.endpoint, r.ref.Repository, digest)
+
+	// Make a request to finish the authentication.
+	resp, err := r.do(http.MethodHead, blobURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to get blob %s: status code %d", digest, resp.StatusCode)
+	}
+	return blobURL, r.authorization, nil
+}
+
+func (r *Registry) fetchManifest(reference string) (*manifest, error) {
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", r.endpoint, r.ref.Repository, reference)
+	header := http.Header{}
+	header.Set("Accept", strings.Join([]string{mediaTypeOCIManifest, mediaTypeOCIIndex, mediaTypeDockerManifest, mediaTypeDockerList}, ", "))
+
+	resp, err := r.do(http.MethodGet, manifestURL, header)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get manifest %s: status code %d", reference, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(body, m); err != nil {
+		return nil, err
+	}
+	if m.MediaType == "" {
+		m.MediaType = strings.Split(resp.Header.Get("Content-Type"), ";")[0]
+	}
+	return m, nil
+}
+
+// selectManifest prefers the linux/amd64 manifest, otherwise the first one, artifacts
+// with model weights are mostly platform independent.
+func selectManifest(manifests []descriptor) (string, error) {
+	if len(manifests) == 0 {
+		return "", fmt.Errorf("empty image index")
+	}
+	for _, m := range manifests {
+		if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture == "amd64" {
+			return m.Digest, nil
+		}
+	}
+	return manifests[0].Digest, nil
+}
+
+// do sends the request, once unauthorized, it will authenticate following the challenge
+// and retry once.
+func (r *Registry) do(method string, requestURL string, header http.Header) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequest(method, requestURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		if r.authorization != "" {
+			req.Header.Set("Authorization", r.authorization)
+		}
+		return http.DefaultClient.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	_ = resp.Body.Close()
+	if err := r.authenticate(challenge); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// authenticate resolves the Authorization header following the challenge, see
+// https://distribution.github.io/distribution/spec/auth/token/
+func (r *Registry) authenticate(challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if r.username == "" {
+			return fmt.Errorf("credentials are required by registry %s", r.ref.Registry)
+		}
+		r.authorization = "Basic " + basicAuth(r.username, r.password)
+		return nil
+	case "bearer":
+		values := map[string]string{}
+		for _, match := range challengeParamRegexp.FindAllStringSubmatch(params, -1) {
+			values[match[1]] = match[2]
+		}
+		if values["realm"] == "" {
+			return fmt.Errorf("unexpected challenge from registry %s: %s", r.ref.Registry, challenge)
+		}
+
+		query := url.Values{}
+		if values["service"] != "" {
+			query.Set("service", values["service"])
+		}
+		scope := values["scope"]
+		if scope == "" {
+			scope = fmt.Sprintf("repository:%s:pull", r.ref.Repository)
+		}
+		query.Set("scope", scope)
+
+		req, err := http.NewRequest(http.MethodGet, values["realm"]+"?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		if r.username != "" {
+			req.SetBasicAuth(r.username, r.password)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to get token from %s: status code %d", values["realm"], resp.StatusCode)
+		}
+
+		token := struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return err
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		r.authorization = "Bearer " + token.Token
+		return nil
+	}
+	return fmt.Errorf("unsupported challenge from registry %s: %s", r.ref.Registry, challenge)
+}
+
+// lookupAuth finds the username and password of the registry in the docker config.
+func lookupAuth(dockerConfig []byte, registry string) (username string, password string, err error) {
+	config := struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}{}
+	if err := json.Unmarshal(dockerConfig, &config); err != nil {
+		return "", "", err
+	}
+
+	candidates := []string{registry, "https://" + registry, "http://" + registry}
+	if registry == dockerHubRegistry {
+		candidates = append(candidates, "https://index.docker.io/v1/", "index.docker.io", dockerHubEndpoint)
+	}
+
+	for _, candidate := range candidates {
+		auth, ok := config.Auths[candidate]
+		if !ok {
+			continue
+		}
+		if auth.Username != "" {
+			return auth.Username, auth.Password, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", err
+		}
+		username, password, _ := strings.Cut(string(decoded), ":")
+		return username, password, nil
+	}
+	// No credentials for the registry, access anonymously.
+	return "", "", nil
+}
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/inftyai/manta/pkg/hub"
+)
+
+// newFakeRegistry returns a minimal registry with token authentication, the image
+// index refers to an artifact with two layers.
+func newFakeRegistry(username, password string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if user, pass, _ := r.BasicAuth(); user != username || pass != password {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:models/qwen2:pull" {
+				http.Error(w, "unexpected scope", http.StatusBadRequest)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"token":"fake-token"}`)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer fake-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake-registry"`, server.URL))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/models/qwen2/manifests/v1":
+			w.Header().Set("Content-Type", mediaTypeOCIIndex)
+			_, _ = fmt.Fprint(w, `{"manifests":[
+				{"digest":"sha256:arm","platform":{"os":"linux","architecture":"arm64"}},
+				{"digest":"sha256:amd","platform":{"os":"linux","architecture":"amd64"}}
+			]}`)
+		case "/v2/models/qwen2/manifests/sha256:amd":
+			_, _ = fmt.Fprintf(w, `{"mediaType":"%s","layers":[
+				{"digest":"sha256:1111","size":663,"annotations":{"org.opencontainers.image.title":"config.json"}},
+				{"digest":"sha256:2222","size":4096}
+			]}`, mediaTypeOCIManifest)
+		case "/v2/models/qwen2/blobs/sha256:1111":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestListObjects(t *testing.T) {
+	server := newFakeRegistry("", "")
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	reg, err := NewRegistry("img://"+host+"/models/qwen2:v1", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	objects, err := reg.ListObjects()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []*hub.ObjectBody{
+		{Path: "config.json", Type: "file", Oid: "sha256-1111", Size: 663, Digest: "sha256:1111"},
+		{Path: "sha256-2222", Type: "file", Oid: "sha256-2222", Size: 4096, Digest: "sha256:2222"},
+	}
+	if diff := cmp.Diff(want, objects); diff != "" {
+		t.Errorf("unexpected objects, diff %v", diff)
+	}
+}
+
+func TestResolveBlobWithCredentials(t *testing.T) {
+	server := newFakeRegistry("inftyai", "secret")
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	auth := base64.StdEncoding.EncodeToString([]byte("inftyai:secret"))
+	credentials := map[string][]byte{
+		corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths":{"%s":{"auth":"%s"}}}`, host, auth)),
+	}
+
+	// Anonymous access should be rejected.
+	reg, err := NewRegistry("img://"+host+"/models/qwen2@sha256:1111", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := reg.ResolveBlob("sha256:1111"); err == nil {
+		t.Error("expected error here")
+	}
+
+	reg, err = NewRegistry("img://"+host+"/models/qwen2@sha256:1111", credentials)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	url, authorization, err := reg.ResolveBlob(reg.Reference().Digest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != server.URL+"/v2/models/qwen2/blobs/sha256:1111" {
+		t.Errorf("unexpected blob url: %s", url)
+	}
+	if authorization != "Bearer fake-token" {
+		t.Errorf("unexpected authorization: %s", authorization)
+	}
+}
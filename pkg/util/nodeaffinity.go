@@ -0,0 +1,184 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// nodeNameField is the only field supported in matchFields, the same as Kubernetes.
+	nodeNameField = "metadata.name"
+)
+
+// NodeSelectorTerm is the parsed corev1.NodeSelectorTerm, the requirements are ANDed.
+type NodeSelectorTerm struct {
+	labelSelector labels.Selector
+	// nodeNames with the key refers to the node name and the value refers to whether
+	// the node name should be equal or not.
+	nodeNames map[string]bool
+}
+
+// PreferredSchedulingTerm is the parsed corev1.PreferredSchedulingTerm.
+type PreferredSchedulingTerm struct {
+	*NodeSelectorTerm
+	Weight int32
+}
+
+// ParseNodeSelectorTerm parses the term, an empty term matches no nodes.
+func ParseNodeSelectorTerm(term corev1.NodeSelectorTerm, path *field.Path) (*NodeSelectorTerm, field.ErrorList) {
+	var allErrs field.ErrorList
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return &NodeSelectorTerm{labelSelector: labels.Nothing()}, nil
+	}
+
+	parsed := &NodeSelectorTerm{labelSelector: labels.NewSelector(), nodeNames: map[string]bool{}}
+	for i, expr := range term.MatchExpressions {
+		exprPath := path.Child("matchExpressions").Index(i)
+		op, err := selectionOperator(expr.Operator)
+		if err != nil {
+			allErrs = append(allErrs, field.NotSupported(exprPath.Child("operator"), expr.Operator, supportedOperators))
+			continue
+		}
+		requirement, err := labels.NewRequirement(expr.Key, op, expr.Values, field.WithPath(exprPath))
+		if err != nil {
+			allErrs = append(allErrs, fieldErrors(err, exprPath)...)
+			continue
+		}
+		parsed.labelSelector = parsed.labelSelector.Add(*requirement)
+	}
+
+	for i, req := range term.MatchFields {
+		fieldPath := path.Child("matchFields").Index(i)
+		if req.Key != nodeNameField {
+			allErrs = append(allErrs, field.NotSupported(fieldPath.Child("key"), req.Key, []string{nodeNameField}))
+			continue
+		}
+		if req.Operator != corev1.NodeSelectorOpIn && req.Operator != corev1.NodeSelectorOpNotIn {
+			allErrs = append(allErrs, field.NotSupported(fieldPath.Child("operator"), req.Operator, []string{string(corev1.NodeSelectorOpIn), string(corev1.NodeSelectorOpNotIn)}))
+			continue
+		}
+		if len(req.Values) != 1 {
+			allErrs = append(allErrs, field.Required(fieldPath.Child("values"), "must be only one value when `operator` is 'In' or 'NotIn' for node field selector"))
+			continue
+		}
+		equal := req.Operator == corev1.NodeSelectorOpIn
+		// Requirements are ANDed, so the conflicted ones match nothing.
+		if existing, ok := parsed.nodeNames[req.Values[0]]; ok && existing != equal {
+			parsed.labelSelector = labels.Nothing()
+		}
+		parsed.nodeNames[req.Values[0]] = equal
+	}
+
+	if len(allErrs) > 0 {
+		return nil, allErrs
+	}
+	return parsed, nil
+}
+
+// Matches returns true once the node satisfies all the requirements of the term.
+func (t *NodeSelectorTerm) Matches(nodeName string, nodeLabels map[string]string) bool {
+	for name, equal := range t.nodeNames {
+		if (name == nodeName) != equal {
+			return false
+		}
+	}
+	return t.labelSelector.Matches(labels.Set(nodeLabels))
+}
+
+// ParseNodeAffinity parses the required terms and the preferred terms of the affinity.
+func ParseNodeAffinity(affinity *corev1.NodeAffinity, path *field.Path) (required []*NodeSelectorTerm, preferred []PreferredSchedulingTerm, allErrs field.ErrorList) {
+	if affinity == nil {
+		return nil, nil, nil
+	}
+
+	if selector := affinity.RequiredDuringSchedulingIgnoredDuringExecution; selector != nil {
+		requiredPath := path.Child("requiredDuringSchedulingIgnoredDuringExecution")
+		if len(selector.NodeSelectorTerms) == 0 {
+			allErrs = append(allErrs, field.Required(requiredPath.Child("nodeSelectorTerms"), "must have at least one node selector term"))
+		}
+		for i, term := range selector.NodeSelectorTerms {
+			parsed, errs := ParseNodeSelectorTerm(term, requiredPath.Child("nodeSelectorTerms").Index(i))
+			allErrs = append(allErrs, errs...)
+			required = append(required, parsed)
+		}
+	}
+
+	for i, term := range affinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		preferredPath := path.Child("preferredDuringSchedulingIgnoredDuringExecution").Index(i)
+		if term.Weight < 1 || term.Weight > 100 {
+			allErrs = append(allErrs, field.Invalid(preferredPath.Child("weight"), term.Weight, "must be in the range 1-100"))
+		}
+		parsed, errs := ParseNodeSelectorTerm(term.Preference, preferredPath.Child("preference"))
+		allErrs = append(allErrs, errs...)
+		preferred = append(preferred, PreferredSchedulingTerm{NodeSelectorTerm: parsed, Weight: term.Weight})
+	}
+
+	if len(allErrs) > 0 {
+		return nil, nil, allErrs
+	}
+	return required, preferred, nil
+}
+
+// fieldErrors unwraps the aggregated field errors returned by labels.NewRequirement.
+func fieldErrors(err error, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	var agg utilerrors.Aggregate
+	if !errors.As(err, &agg) {
+		return field.ErrorList{field.Invalid(path, nil, err.Error())}
+	}
+	for _, e := range agg.Errors() {
+		var fieldErr *field.Error
+		if errors.As(e, &fieldErr) {
+			allErrs = append(allErrs, fieldErr)
+		} else {
+			allErrs = append(allErrs, field.Invalid(path, nil, e.Error()))
+		}
+	}
+	return allErrs
+}
+
+var supportedOperators = []string{
+	string(corev1.NodeSelectorOpIn), string(corev1.NodeSelectorOpNotIn),
+	string(corev1.NodeSelectorOpExists), string(corev1.NodeSelectorOpDoesNotExist),
+	string(corev1.NodeSelectorOpGt), string(corev1.NodeSelectorOpLt),
+}
+
+func selectionOperator(op corev1.NodeSelectorOperator) (selection.Operator, error) {
+	switch op {
+	case corev1.NodeSelectorOpIn:
+		return selection.In, nil
+	case corev1.NodeSelectorOpNotIn:
+		return selection.NotIn, nil
+	case corev1.NodeSelectorOpExists:
+		return selection.Exists, nil
+	case corev1.NodeSelectorOpDoesNotExist:
+		return selection.DoesNotExist, nil
+	case corev1.NodeSelectorOpGt:
+		return selection.GreaterThan, nil
+	case corev1.NodeSelectorOpLt:
+		return selection.LessThan, nil
+	}
+	return "", fmt.Errorf("unsupported operator %q", op)
+}
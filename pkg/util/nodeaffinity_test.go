@@ -0,0 +1,198 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestParseNodeAffinity(t *testing.T) {
+	expr := func(key string, op corev1.NodeSelectorOperator, values ...string) corev1.NodeSelectorRequirement {
+		return corev1.NodeSelectorRequirement{Key: key, Operator: op, Values: values}
+	}
+	required := func(terms ...corev1.NodeSelectorTerm) *corev1.NodeAffinity {
+		return &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms}}
+	}
+
+	testCases := []struct {
+		name      string
+		affinity  *corev1.NodeAffinity
+		wantError bool
+	}{
+		{
+			name: "nil affinity",
+		},
+		{
+			name: "valid affinity",
+			affinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchExpressions: []corev1.NodeSelectorRequirement{
+								expr("gpu", corev1.NodeSelectorOpIn, "A100", "H100"),
+								expr("gpu-memory", corev1.NodeSelectorOpGt, "40"),
+							},
+							MatchFields: []corev1.NodeSelectorRequirement{expr("metadata.name", corev1.NodeSelectorOpNotIn, "node1")},
+						},
+					},
+				},
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+					{Weight: 10, Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{expr("pool", corev1.NodeSelectorOpExists)}}},
+				},
+			},
+		},
+		{
+			name:      "no required terms",
+			affinity:  required(),
+			wantError: true,
+		},
+		{
+			name:      "unsupported operator",
+			affinity:  required(corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{expr("gpu", "Equals", "A100")}}),
+			wantError: true,
+		},
+		{
+			name:      "values required with In",
+			affinity:  required(corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{expr("gpu", corev1.NodeSelectorOpIn)}}),
+			wantError: true,
+		},
+		{
+			name:      "values forbidden with Exists",
+			affinity:  required(corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{expr("gpu", corev1.NodeSelectorOpExists, "A100")}}),
+			wantError: true,
+		},
+		{
+			name:      "non-integer with Gt",
+			affinity:  required(corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{expr("gpu-memory", corev1.NodeSelectorOpGt, "large")}}),
+			wantError: true,
+		},
+		{
+			name:      "invalid label key",
+			affinity:  required(corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{expr("gpu type", corev1.NodeSelectorOpExists)}}),
+			wantError: true,
+		},
+		{
+			name:      "unsupported field",
+			affinity:  required(corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{expr("metadata.namespace", corev1.NodeSelectorOpIn, "default")}}),
+			wantError: true,
+		},
+		{
+			name:      "multiple values of field",
+			affinity:  required(corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{expr("metadata.name", corev1.NodeSelectorOpIn, "node1", "node2")}}),
+			wantError: true,
+		},
+		{
+			name: "weight out of range",
+			affinity: &corev1.NodeAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+					{Weight: 101, Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{expr("pool", corev1.NodeSelectorOpExists)}}},
+				},
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, errs := ParseNodeAffinity(tc.affinity, field.NewPath("nodeAffinity"))
+			if tc.wantError != (len(errs) > 0) {
+				t.Errorf("unexpected errors: %v", errs)
+			}
+		})
+	}
+}
+
+func TestNodeSelectorTermMatches(t *testing.T) {
+	nodeLabels := map[string]string{"gpu": "A100", "pool": "spot", "gpu-memory": "80"}
+
+	testCases := []struct {
+		name     string
+		term     corev1.NodeSelectorTerm
+		nodeName string
+		want     bool
+	}{
+		{
+			name:     "empty term matches nothing",
+			nodeName: "node1",
+			want:     false,
+		},
+		{
+			name: "all expressions matched",
+			term: corev1.NodeSelectorTerm{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "gpu", Operator: corev1.NodeSelectorOpIn, Values: []string{"A100", "H100"}},
+					{Key: "gpu-memory", Operator: corev1.NodeSelectorOpGt, Values: []string{"40"}},
+				},
+			},
+			nodeName: "node1",
+			want:     true,
+		},
+		{
+			name: "one of expressions not matched",
+			term: corev1.NodeSelectorTerm{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{Key: "gpu", Operator: corev1.NodeSelectorOpIn, Values: []string{"A100", "H100"}},
+					{Key: "pool", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"spot"}},
+				},
+			},
+			nodeName: "node1",
+			want:     false,
+		},
+		{
+			name: "node name matched",
+			term: corev1.NodeSelectorTerm{
+				MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node1"}}},
+			},
+			nodeName: "node1",
+			want:     true,
+		},
+		{
+			name: "node name not matched",
+			term: corev1.NodeSelectorTerm{
+				MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"node1"}}},
+			},
+			nodeName: "node1",
+			want:     false,
+		},
+		{
+			name: "conflicted node names",
+			term: corev1.NodeSelectorTerm{
+				MatchFields: []corev1.NodeSelectorRequirement{
+					{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node1"}},
+					{Key: "metadata.name", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"node1"}},
+				},
+			},
+			nodeName: "node1",
+			want:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			term, errs := ParseNodeSelectorTerm(tc.term, field.NewPath("term"))
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			if got := term.Matches(tc.nodeName, nodeLabels); got != tc.want {
+				t.Errorf("unexpected result, want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
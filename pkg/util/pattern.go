@@ -0,0 +1,106 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// MatchPatterns returns whether the path is included by the patterns, the same as the allow_patterns
+// and ignore_patterns of huggingface_hub. The path is included once it matches any of the allowPatterns,
+// or the allowPatterns is empty, and it matches none of the ignorePatterns. The patterns follow the
+// fnmatch syntax, so * matches / as well, and the patterns ending with / match the whole directory.
+func MatchPatterns(path string, allowPatterns, ignorePatterns []string) bool {
+	if len(allowPatterns) > 0 && !matchAny(path, allowPatterns) {
+		return false
+	}
+	return !matchAny(path, ignorePatterns)
+}
+
+func matchAny(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		// Invalid patterns are rejected by the webhook, regard them as not matched.
+		if re, err := patternRegexp(pattern); err == nil && re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// patternRegexp translates the fnmatch pattern to the regular expression, see fnmatch.translate of python.
+func patternRegexp(pattern string) (*regexp.Regexp, error) {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "*"
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			j := i + 1
+			if j < len(pattern) && pattern[j] == '!' {
+				j++
+			}
+			if j < len(pattern) && pattern[j] == ']' {
+				j++
+			}
+			for j < len(pattern) && pattern[j] != ']' {
+				j++
+			}
+			// No closing bracket, regard it as a literal.
+			if j >= len(pattern) {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := strings.ReplaceAll(pattern[i+1:j], `\`, `\\`)
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			} else if strings.HasPrefix(class, "^") {
+				class = `\` + class
+			}
+			expr.WriteString("[" + class + "]")
+			i = j
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// ValidatePatterns validates the fnmatch patterns.
+func ValidatePatterns(patterns []string, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for i, pattern := range patterns {
+		if pattern == "" {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), pattern, "pattern must not be empty"))
+			continue
+		}
+		if _, err := patternRegexp(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), pattern, err.Error()))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestMatchPatterns(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		allowPatterns  []string
+		ignorePatterns []string
+		want           bool
+	}{
+		{
+			name: "no patterns",
+			path: "model.safetensors",
+			want: true,
+		},
+		{
+			name:          "allowed by extension",
+			path:          "model-00001-of-00002.safetensors",
+			allowPatterns: []string{"*.json", "*.safetensors"},
+			want:          true,
+		},
+		{
+			name:          "not allowed",
+			path:          "pytorch_model.bin",
+			allowPatterns: []string{"*.json", "*.safetensors"},
+			want:          false,
+		},
+		{
+			name:          "wildcard matches the separator",
+			path:          "onnx/model.onnx",
+			allowPatterns: []string{"*.onnx"},
+			want:          true,
+		},
+		{
+			name:          "directory pattern",
+			path:          "onnx/model.onnx",
+			allowPatterns: []string{"onnx/"},
+			want:          true,
+		},
+		{
+			name:           "ignored",
+			path:           "model.bin",
+			ignorePatterns: []string{"*.bin", "*.onnx"},
+			want:           false,
+		},
+		{
+			name:           "ignored after allowed",
+			path:           "qwen2-0_5b-instruct-q2_k.gguf",
+			allowPatterns:  []string{"*.gguf"},
+			ignorePatterns: []string{"*q2_k*"},
+			want:           false,
+		},
+		{
+			name:          "single character and character class",
+			path:          "model-00001-of-00002.safetensors",
+			allowPatterns: []string{"model-0000[12]-of-0000?.safetensors"},
+			want:          true,
+		},
+		{
+			name:          "negative character class",
+			path:          "model-00001-of-00002.safetensors",
+			allowPatterns: []string{"model-0000[!12]-of-00002.safetensors"},
+			want:          false,
+		},
+		{
+			name:          "unclosed bracket as literal",
+			path:          "model[1.bin",
+			allowPatterns: []string{"model[*"},
+			want:          true,
+		},
+		{
+			name:          "dot is not a wildcard",
+			path:          "modelXbin",
+			allowPatterns: []string{"model.bin"},
+			want:          false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := MatchPatterns(tc.path, tc.allowPatterns, tc.ignorePatterns); got != tc.want {
+				t.Errorf("unexpected result, want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	testCases := []struct {
+		name      string
+		patterns  []string
+		wantError bool
+	}{
+		{
+			name:     "valid patterns",
+			patterns: []string{"*.safetensors", "onnx/", "model-[0-9]*.bin", "[!._]*"},
+		},
+		{
+			name:      "empty pattern",
+			patterns:  []string{"*.json", ""},
+			wantError: true,
+		},
+		{
+			name:      "invalid character range",
+			patterns:  []string{"model-[9-0].bin"},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := ValidatePatterns(tc.patterns, field.NewPath("spec", "hub", "allowPatterns"))
+			if tc.wantError != (len(errs) > 0) {
+				t.Errorf("unexpected errors: %v", errs)
+			}
+		})
+	}
+}
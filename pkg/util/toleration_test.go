@@ -0,0 +1,85 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+)
+
+func TestValidateTolerations(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tolerations []corev1.Toleration
+		wantError   bool
+	}{
+		{
+			name: "no tolerations",
+		},
+		{
+			name: "valid tolerations",
+			tolerations: []corev1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+				{Key: "pool", Value: "spot"},
+				{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: ptr.To[int64](300)},
+				{Operator: corev1.TolerationOpExists},
+			},
+		},
+		{
+			name:        "empty key with Equal",
+			tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpEqual, Value: "spot"}},
+			wantError:   true,
+		},
+		{
+			name:        "invalid key",
+			tolerations: []corev1.Toleration{{Key: "gpu type", Operator: corev1.TolerationOpExists}},
+			wantError:   true,
+		},
+		{
+			name:        "value with Exists",
+			tolerations: []corev1.Toleration{{Key: "pool", Operator: corev1.TolerationOpExists, Value: "spot"}},
+			wantError:   true,
+		},
+		{
+			name:        "unsupported operator",
+			tolerations: []corev1.Toleration{{Key: "pool", Operator: "In", Value: "spot"}},
+			wantError:   true,
+		},
+		{
+			name:        "unsupported effect",
+			tolerations: []corev1.Toleration{{Key: "pool", Value: "spot", Effect: "NoDispatch"}},
+			wantError:   true,
+		},
+		{
+			name:        "tolerationSeconds without NoExecute",
+			tolerations: []corev1.Toleration{{Key: "pool", Value: "spot", Effect: corev1.TaintEffectNoSchedule, TolerationSeconds: ptr.To[int64](300)}},
+			wantError:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := ValidateTolerations(tc.tolerations, field.NewPath("tolerations"))
+			if tc.wantError != (len(errs) > 0) {
+				t.Errorf("unexpected errors: %v", errs)
+			}
+		})
+	}
+}
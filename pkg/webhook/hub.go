@@ -0,0 +1,36 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"k8s.io/utils/ptr"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+)
+
+// defaultHub defaults the revision to the default branch of the hub, which couldn't be
+// defaulted in the CRD because ModelScope repos use master rather than main.
+func defaultHub(hub *api.Hub) {
+	if hub == nil || hub.Revision != nil {
+		return
+	}
+	if hub.Name != nil && *hub.Name == api.MODELSCOPE_MODEL_HUB {
+		hub.Revision = ptr.To[string](api.MODELSCOPE_DEFAULT_REVISION)
+		return
+	}
+	hub.Revision = ptr.To[string](api.HUGGINGFACE_DEFAULT_REVISION)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+
+	"k8s.io/utils/ptr"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+)
+
+func TestDefaultHub(t *testing.T) {
+	testCases := []struct {
+		name         string
+		hub          *api.Hub
+		wantRevision string
+	}{
+		{
+			name:         "huggingface",
+			hub:          &api.Hub{Name: ptr.To[string](api.HUGGINGFACE_MODEL_HUB), RepoID: "Qwen/Qwen2-7B-Instruct"},
+			wantRevision: "main",
+		},
+		{
+			name:         "modelscope",
+			hub:          &api.Hub{Name: ptr.To[string](api.MODELSCOPE_MODEL_HUB), RepoID: "Qwen/Qwen2-7B-Instruct"},
+			wantRevision: "master",
+		},
+		{
+			name:         "revision specified",
+			hub:          &api.Hub{Name: ptr.To[string](api.MODELSCOPE_MODEL_HUB), RepoID: "Qwen/Qwen2-7B-Instruct", Revision: ptr.To[string]("v1.0")},
+			wantRevision: "v1.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defaultHub(tc.hub)
+			if *tc.hub.Revision != tc.wantRevision {
+				t.Errorf("unexpected revision, want %s, got %s", tc.wantRevision, *tc.hub.Revision)
+			}
+		})
+	}
+}
@@ -20,23 +20,30 @@ import (
 	"context"
 	"strings"
 
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/objectstore"
+	"github.com/inftyai/manta/pkg/registry"
 )
 
-type ReplicationWebhook struct{}
+type ReplicationWebhook struct {
+	// client is used to review whether the requester is allowed to access the referred Secrets.
+	client client.Client
+}
 
 // SetupTorrentWebhook will setup the manager to manage the webhooks
 func SetupReplicationWebhook(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(&api.Replication{}).
 		WithDefaulter(&ReplicationWebhook{}).
-		WithValidator(&ReplicationWebhook{}).
+		WithValidator(&ReplicationWebhook{client: mgr.GetClient()}).
 		Complete()
 }
 
@@ -46,6 +53,8 @@ var _ webhook.CustomDefaulter = &ReplicationWebhook{}
 
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (w *ReplicationWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	replication := obj.(*api.Replication)
+	defaultHub(replication.Spec.Source.Hub)
 	return nil
 }
 
@@ -56,12 +65,27 @@ var _ webhook.CustomValidator = &ReplicationWebhook{}
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (w *ReplicationWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
 	allErrs := w.generateValidate(obj)
+	replication := obj.(*api.Replication)
+	if replication.Spec.Source.Hub != nil {
+		allErrs = append(allErrs, validateSecretAccess(ctx, w.client, replication.Spec.Source.Hub.TokenSecretRef, field.NewPath("spec", "source", "hub", "tokenSecretRef"))...)
+	}
+	allErrs = append(allErrs, validateSecretAccess(ctx, w.client, replication.Spec.Source.CredentialSecretRef, field.NewPath("spec", "source", "credentialSecretRef"))...)
 	return nil, allErrs.ToAggregate()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (w *ReplicationWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old := oldObj.(*api.Replication)
+	new := newObj.(*api.Replication)
+
 	allErrs := w.generateValidate(newObj)
+	// Only review the changed reference, or the Replication couldn't be updated by others, e.g. the agents.
+	if new.Spec.Source.Hub != nil && (old.Spec.Source.Hub == nil || !apiequality.Semantic.DeepEqual(old.Spec.Source.Hub.TokenSecretRef, new.Spec.Source.Hub.TokenSecretRef)) {
+		allErrs = append(allErrs, validateSecretAccess(ctx, w.client, new.Spec.Source.Hub.TokenSecretRef, field.NewPath("spec", "source", "hub", "tokenSecretRef"))...)
+	}
+	if !apiequality.Semantic.DeepEqual(old.Spec.Source.CredentialSecretRef, new.Spec.Source.CredentialSecretRef) {
+		allErrs = append(allErrs, validateSecretAccess(ctx, w.client, new.Spec.Source.CredentialSecretRef, field.NewPath("spec", "source", "credentialSecretRef"))...)
+	}
 	return nil, allErrs.ToAggregate()
 }
 
@@ -87,6 +111,21 @@ func (w *ReplicationWebhook) generateValidate(obj runtime.Object) field.ErrorLis
 		if splits[0] == "localhost" && replication.Spec.Destination != nil {
 			allErrs = append(allErrs, field.Forbidden(specPath.Child("destination"), "destination must be nil once source is localhost"))
 		}
+		if objectstore.IsObjectStoreURI(*replication.Spec.Source.URI) || registry.IsImageURI(*replication.Spec.Source.URI) {
+			if replication.Spec.Destination == nil || replication.Spec.Destination.URI == nil ||
+				!strings.HasPrefix(*replication.Spec.Destination.URI, "localhost://") {
+				allErrs = append(allErrs, field.Forbidden(specPath.Child("destination.uri"), "destination.uri must be localhost once source is object storage or image"))
+			}
+		}
+	}
+	allErrs = append(allErrs, validateSecretNamespace(replication.Spec.Source.CredentialSecretRef, specPath.Child("source", "credentialSecretRef"))...)
+	if replication.Spec.Source.Hub != nil {
+		allErrs = append(allErrs, validateSecretNamespace(replication.Spec.Source.Hub.TokenSecretRef, specPath.Child("source", "hub", "tokenSecretRef"))...)
+	}
+	if len(replication.Spec.Source.PeerNodeNames) > 0 {
+		if replication.Spec.Source.URI == nil || !strings.HasPrefix(*replication.Spec.Source.URI, api.URI_REMOTE+"://") {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("source.peerNodeNames"), "peerNodeNames only works with remote source.uri"))
+		}
 	}
 	if replication.Spec.Source.Hub != nil {
 		if replication.Spec.Destination == nil || replication.Spec.Destination.URI == nil {
@@ -0,0 +1,88 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	defaults "github.com/inftyai/manta/pkg"
+)
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// validateSecretAccess reviews whether the requester is allowed to get the referred Secret,
+// otherwise, anyone able to create Torrents or Replications could make use of any Secret via
+// the controller and the agents, which are allowed to get all the Secrets in the Manta namespace.
+func validateSecretAccess(ctx context.Context, c client.Client, ref *corev1.SecretReference, path *field.Path) field.ErrorList {
+	if ref == nil || ref.Namespace == "" || ref.Name == "" {
+		return nil
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(req.UserInfo.Extra))
+	for k, v := range req.UserInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			Groups: req.UserInfo.Groups,
+			UID:    req.UserInfo.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: ref.Namespace,
+				Verb:      "get",
+				Resource:  "secrets",
+				Name:      ref.Name,
+			},
+		},
+	}
+	if err := c.Create(ctx, review); err != nil {
+		return field.ErrorList{field.InternalError(path, err)}
+	}
+	if !review.Status.Allowed {
+		return field.ErrorList{field.Forbidden(path, fmt.Sprintf("user %s is not allowed to get secret %s/%s", req.UserInfo.Username, ref.Namespace, ref.Name))}
+	}
+	return nil
+}
+
+// defaultSecretNamespace defaults the namespace of the referred Secret to the Manta namespace.
+func defaultSecretNamespace(ref *corev1.SecretReference) {
+	if ref != nil && ref.Namespace == "" {
+		ref.Namespace = defaults.MANTA_NAMESPACE
+	}
+}
+
+// validateSecretNamespace validates the referred Secret lives in the Manta namespace, the only
+// namespace the controller and the agents are allowed to get Secrets from.
+func validateSecretNamespace(ref *corev1.SecretReference, path *field.Path) field.ErrorList {
+	if ref == nil || ref.Namespace == defaults.MANTA_NAMESPACE {
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(path.Child("namespace"), ref.Namespace, []string{defaults.MANTA_NAMESPACE})}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/test/util/wrapper"
+)
+
+func TestValidateSecretAccess(t *testing.T) {
+	// Only alice is allowed to get the Secret default/hf-token.
+	c := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			review := obj.(*authorizationv1.SubjectAccessReview)
+			attrs := review.Spec.ResourceAttributes
+			review.Status.Allowed = review.Spec.User == "alice" && attrs.Verb == "get" && attrs.Resource == "secrets" &&
+				attrs.Namespace == "default" && attrs.Name == "hf-token"
+			return nil
+		},
+	}).Build()
+
+	testCases := []struct {
+		name     string
+		user     string
+		ref      *corev1.SecretReference
+		wantErrs int
+	}{
+		{
+			name: "no secret referred",
+			user: "bob",
+		},
+		{
+			name: "allowed user",
+			user: "alice",
+			ref:  &corev1.SecretReference{Namespace: "default", Name: "hf-token"},
+		},
+		{
+			name:     "disallowed user",
+			user:     "bob",
+			ref:      &corev1.SecretReference{Namespace: "default", Name: "hf-token"},
+			wantErrs: 1,
+		},
+		{
+			name:     "disallowed secret",
+			user:     "alice",
+			ref:      &corev1.SecretReference{Namespace: "kube-system", Name: "hf-token"},
+			wantErrs: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					UserInfo: authenticationv1.UserInfo{Username: tc.user},
+				},
+			})
+			errs := validateSecretAccess(ctx, c, tc.ref, field.NewPath("spec", "hub", "tokenSecretRef"))
+			if len(errs) != tc.wantErrs {
+				t.Errorf("unexpected errors, want %d, got %v", tc.wantErrs, errs)
+			}
+		})
+	}
+}
+
+func TestValidateCredentialSecretAccess(t *testing.T) {
+	// Only alice is allowed to get the Secret manta-system/s3-credentials.
+	c := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			review := obj.(*authorizationv1.SubjectAccessReview)
+			attrs := review.Spec.ResourceAttributes
+			review.Status.Allowed = review.Spec.User == "alice" && attrs.Namespace == "manta-system" && attrs.Name == "s3-credentials"
+			return nil
+		},
+	}).Build()
+	torrentWebhook := &TorrentWebhook{client: c}
+	replicationWebhook := &ReplicationWebhook{client: c}
+
+	torrent := func(secretName string) *api.Torrent {
+		return wrapper.MakeTorrent("torrent").URI("s3://models/Qwen/Qwen2-7B-Instruct").CredentialSecretRef("manta-system", secretName).Preheat(true).Obj()
+	}
+	replication := func(secretName string) *api.Replication {
+		replication := wrapper.MakeReplication("replication").SourceOfURI("s3://models/Qwen/Qwen2-7B-Instruct/config.json").DestinationOfURI("localhost:///workspace/models/config.json").Obj()
+		replication.Spec.Source.CredentialSecretRef = &corev1.SecretReference{Namespace: "manta-system", Name: secretName}
+		return replication
+	}
+
+	testCases := []struct {
+		name    string
+		user    string
+		oldName string
+		newName string
+		wantErr bool
+	}{
+		{
+			name:    "allowed user creates",
+			user:    "alice",
+			newName: "s3-credentials",
+		},
+		{
+			name:    "disallowed user creates",
+			user:    "bob",
+			newName: "s3-credentials",
+			wantErr: true,
+		},
+		{
+			name:    "disallowed secret",
+			user:    "alice",
+			newName: "oss-credentials",
+			wantErr: true,
+		},
+		{
+			name:    "disallowed user updates with the secret unchanged",
+			user:    "bob",
+			oldName: "s3-credentials",
+			newName: "s3-credentials",
+		},
+		{
+			name:    "disallowed user changes the secret",
+			user:    "bob",
+			oldName: "oss-credentials",
+			newName: "s3-credentials",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					UserInfo: authenticationv1.UserInfo{Username: tc.user},
+				},
+			})
+
+			var torrentErr, replicationErr error
+			if tc.oldName == "" {
+				_, torrentErr = torrentWebhook.ValidateCreate(ctx, torrent(tc.newName))
+				_, replicationErr = replicationWebhook.ValidateCreate(ctx, replication(tc.newName))
+			} else {
+				_, torrentErr = torrentWebhook.ValidateUpdate(ctx, torrent(tc.oldName), torrent(tc.newName))
+				_, replicationErr = replicationWebhook.ValidateUpdate(ctx, replication(tc.oldName), replication(tc.newName))
+			}
+			if (torrentErr != nil) != tc.wantErr {
+				t.Errorf("unexpected torrent error, want error: %v, got %v", tc.wantErr, torrentErr)
+			}
+			if (replicationErr != nil) != tc.wantErr {
+				t.Errorf("unexpected replication error, want error: %v, got %v", tc.wantErr, replicationErr)
+			}
+		})
+	}
+}
+
+func TestSecretNamespace(t *testing.T) {
+	testCases := []struct {
+		name          string
+		ref           *corev1.SecretReference
+		wantNamespace string
+		wantErrs      int
+	}{
+		{
+			name: "no secret referred",
+		},
+		{
+			name:          "default to the manta namespace",
+			ref:           &corev1.SecretReference{Name: "s3-credentials"},
+			wantNamespace: "manta-system",
+		},
+		{
+			name:          "manta namespace",
+			ref:           &corev1.SecretReference{Namespace: "manta-system", Name: "s3-credentials"},
+			wantNamespace: "manta-system",
+		},
+		{
+			name:          "other namespace",
+			ref:           &corev1.SecretReference{Namespace: "default", Name: "s3-credentials"},
+			wantNamespace: "default",
+			wantErrs:      1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defaultSecretNamespace(tc.ref)
+			if tc.ref != nil && tc.ref.Namespace != tc.wantNamespace {
+				t.Errorf("unexpected namespace, want %s, got %s", tc.wantNamespace, tc.ref.Namespace)
+			}
+			if errs := validateSecretNamespace(tc.ref, field.NewPath("spec", "credentialSecretRef")); len(errs) != tc.wantErrs {
+				t.Errorf("unexpected errors, want %d, got %v", tc.wantErrs, errs)
+			}
+		})
+	}
+}
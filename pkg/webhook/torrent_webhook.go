@@ -20,23 +20,31 @@ import (
 	"context"
 	"time"
 
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	api "github.com/inftyai/manta/api/v1alpha1"
+	"github.com/inftyai/manta/pkg/objectstore"
+	"github.com/inftyai/manta/pkg/registry"
+	"github.com/inftyai/manta/pkg/util"
 )
 
-type TorrentWebhook struct{}
+type TorrentWebhook struct {
+	// client is used to review whether the requester is allowed to access the referred Secrets.
+	client client.Client
+}
 
 // SetupTorrentWebhook will setup the manager to manage the webhooks
 func SetupTorrentWebhook(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(&api.Torrent{}).
 		WithDefaulter(&TorrentWebhook{}).
-		WithValidator(&TorrentWebhook{}).
+		WithValidator(&TorrentWebhook{client: mgr.GetClient()}).
 		Complete()
 }
 
@@ -46,6 +54,12 @@ var _ webhook.CustomDefaulter = &TorrentWebhook{}
 
 // Default implements webhook.Defaulter so a webhook will be registered for the type
 func (w *TorrentWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	torrent := obj.(*api.Torrent)
+	defaultSecretNamespace(torrent.Spec.CredentialSecretRef)
+	if torrent.Spec.Hub != nil {
+		defaultHub(torrent.Spec.Hub)
+		defaultSecretNamespace(torrent.Spec.Hub.TokenSecretRef)
+	}
 	return nil
 }
 
@@ -56,6 +70,11 @@ var _ webhook.CustomValidator = &TorrentWebhook{}
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (w *TorrentWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
 	allErrs := w.generateValidate(obj)
+	torrent := obj.(*api.Torrent)
+	if torrent.Spec.Hub != nil {
+		allErrs = append(allErrs, validateSecretAccess(ctx, w.client, torrent.Spec.Hub.TokenSecretRef, field.NewPath("spec", "hub", "tokenSecretRef"))...)
+	}
+	allErrs = append(allErrs, validateSecretAccess(ctx, w.client, torrent.Spec.CredentialSecretRef, field.NewPath("spec", "credentialSecretRef"))...)
 	return nil, allErrs.ToAggregate()
 }
 
@@ -70,6 +89,13 @@ func (w *TorrentWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runt
 		allErrs = append(allErrs, field.Forbidden(specPath.Child("preheat"), "preheat can only be transitioned from false to true"))
 	}
 	allErrs = append(allErrs, w.generateValidate(newObj)...)
+	// Only review the changed reference, or the Torrent couldn't be updated by others, e.g. the controller.
+	if new.Spec.Hub != nil && (old.Spec.Hub == nil || !apiequality.Semantic.DeepEqual(old.Spec.Hub.TokenSecretRef, new.Spec.Hub.TokenSecretRef)) {
+		allErrs = append(allErrs, validateSecretAccess(ctx, w.client, new.Spec.Hub.TokenSecretRef, specPath.Child("hub", "tokenSecretRef"))...)
+	}
+	if !apiequality.Semantic.DeepEqual(old.Spec.CredentialSecretRef, new.Spec.CredentialSecretRef) {
+		allErrs = append(allErrs, validateSecretAccess(ctx, w.client, new.Spec.CredentialSecretRef, specPath.Child("credentialSecretRef"))...)
+	}
 	return nil, allErrs.ToAggregate()
 }
 
@@ -83,13 +109,51 @@ func (w *TorrentWebhook) generateValidate(obj runtime.Object) field.ErrorList {
 	specPath := field.NewPath("spec")
 
 	var allErrs field.ErrorList
-	if torrent.Spec.Hub == nil {
-		allErrs = append(allErrs, field.Forbidden(specPath.Child("hub"), "hub can't be null"))
+	if torrent.Spec.Hub == nil && torrent.Spec.URI == nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("hub"), "hub and uri can't be both null"))
+	}
+	if torrent.Spec.Hub != nil && torrent.Spec.URI != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("uri"), "hub and uri are exclusive"))
+	}
+	if torrent.Spec.URI != nil {
+		var err error
+		if registry.IsImageURI(string(*torrent.Spec.URI)) {
+			_, err = registry.ParseReference(string(*torrent.Spec.URI))
+		} else {
+			_, err = objectstore.NewObjectStore(string(*torrent.Spec.URI), nil)
+		}
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("uri"), *torrent.Spec.URI, err.Error()))
+		}
+	}
+	if torrent.Spec.CredentialSecretRef != nil && torrent.Spec.URI == nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("credentialSecretRef"), "credentialSecretRef only works with uri"))
+	}
+	allErrs = append(allErrs, validateSecretNamespace(torrent.Spec.CredentialSecretRef, specPath.Child("credentialSecretRef"))...)
+	if torrent.Spec.Hub != nil {
+		hubPath := specPath.Child("hub")
+		if torrent.Spec.Hub.Filename != nil && (len(torrent.Spec.Hub.AllowPatterns) > 0 || len(torrent.Spec.Hub.IgnorePatterns) > 0) {
+			allErrs = append(allErrs, field.Forbidden(hubPath.Child("filename"), "filename and patterns are exclusive"))
+		}
+		allErrs = append(allErrs, util.ValidatePatterns(torrent.Spec.Hub.AllowPatterns, hubPath.Child("allowPatterns"))...)
+		allErrs = append(allErrs, util.ValidatePatterns(torrent.Spec.Hub.IgnorePatterns, hubPath.Child("ignorePatterns"))...)
+		if ref := torrent.Spec.Hub.TokenSecretRef; ref != nil && ref.Name == "" {
+			allErrs = append(allErrs, field.Required(hubPath.Child("tokenSecretRef", "name"), "name is required"))
+		}
+		allErrs = append(allErrs, validateSecretNamespace(torrent.Spec.Hub.TokenSecretRef, hubPath.Child("tokenSecretRef"))...)
+	}
+	if torrent.Spec.UpdatePolicy != nil && torrent.Spec.Hub == nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("updatePolicy"), "updatePolicy only works with hub"))
+	}
+
+	if torrent.Spec.TTLSecondsAfterReady != nil && *torrent.Spec.TTLSecondsAfterReady < time.Duration(0) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("ttlSecondsAfterReady"), *torrent.Spec.TTLSecondsAfterReady, "must be greater than or equal to 0"))
 	}
 
-	if !(torrent.Spec.TTLSecondsAfterReady == nil || *torrent.Spec.TTLSecondsAfterReady == time.Duration(0)) {
-		allErrs = append(allErrs, field.Forbidden(specPath.Child("ttlSecondsAfterReady"), "only support nil and 0 right now"))
+	if _, _, errs := util.ParseNodeAffinity(torrent.Spec.NodeAffinity, specPath.Child("nodeAffinity")); len(errs) > 0 {
+		allErrs = append(allErrs, errs...)
 	}
+	allErrs = append(allErrs, util.ValidateTolerations(torrent.Spec.Tolerations, specPath.Child("tolerations"))...)
 
 	return allErrs
 }
@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -40,9 +41,8 @@ import (
 	api "github.com/inftyai/manta/api/v1alpha1"
 	controller "github.com/inftyai/manta/pkg/controller"
 	"github.com/inftyai/manta/pkg/dispatcher"
-	"github.com/inftyai/manta/pkg/dispatcher/framework"
-	"github.com/inftyai/manta/pkg/dispatcher/plugins/diskaware"
-	"github.com/inftyai/manta/pkg/dispatcher/plugins/nodeselector"
+	"github.com/inftyai/manta/pkg/dispatcher/config"
+	"github.com/inftyai/manta/pkg/dispatcher/plugins"
 )
 
 // These tests use Ginkgo (BDD-style Go testing framework). Refer to
@@ -105,14 +105,14 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).ToNot(HaveOccurred())
 
-	dispatcher, err := dispatcher.NewDispatcher([]framework.RegisterFunc{nodeselector.New, diskaware.New})
+	dispatcher, err := dispatcher.NewDispatcher(plugins.NewInTreeRegistry(), config.Default())
 	Expect(err).ToNot(HaveOccurred())
 
-	torrentController := controller.NewTorrentReconciler(mgr.GetClient(), mgr.GetScheme(), dispatcher)
+	torrentController := controller.NewTorrentReconciler(mgr.GetClient(), mgr.GetScheme(), dispatcher, 0)
 	Expect(torrentController.SetupWithManager(mgr)).NotTo(HaveOccurred())
-	replicationController := controller.NewReplicationReconciler(mgr.GetClient(), mgr.GetScheme())
+	replicationController := controller.NewReplicationReconciler(mgr.GetClient(), mgr.GetScheme(), dispatcher, 10*time.Minute)
 	Expect(replicationController.SetupWithManager(mgr)).NotTo(HaveOccurred())
-	nodeTrackerController := controller.NewNodeTrackerReconciler(mgr.GetClient(), mgr.GetScheme(), dispatcher)
+	nodeTrackerController := controller.NewNodeTrackerReconciler(mgr.GetClient(), mgr.GetScheme(), dispatcher, 0.9, 0.8, 3*time.Minute)
 	Expect(nodeTrackerController.SetupWithManager(mgr)).NotTo(HaveOccurred())
 
 	go func() {
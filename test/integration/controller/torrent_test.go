@@ -543,5 +543,44 @@ var _ = ginkgo.Describe("Torrent controller test", func() {
 				},
 			},
 		}),
+		ginkgo.Entry("Torrent with ttl is greater than zero", &testValidatingCase{
+			precondition: func() error {
+				nodeTracker := wrapper.MakeNodeTracker("node1").Obj()
+				return k8sClient.Create(ctx, nodeTracker)
+			},
+			makeTorrent: func() *api.Torrent {
+				return wrapper.MakeTorrent("qwen2-7b").Preheat(true).TTL(3).Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj()
+			},
+			updates: []*update{
+				{
+					updateFunc: func(torrent *api.Torrent) {
+						gomega.Expect(k8sClient.Create(ctx, torrent)).To(gomega.Succeed())
+					},
+				},
+				{
+					updateFunc: func(torrent *api.Torrent) {
+						util.UpdateReplicationsCondition(ctx, k8sClient, torrent, api.ReplicateConditionType)
+					},
+					checkFunc: func(ctx context.Context, k8sClient client.Client, torrent *api.Torrent) {
+						validation.ValidateTorrentStatusEqualTo(ctx, k8sClient, torrent, api.ReplicateConditionType, "Replicating", metav1.ConditionTrue, nil)
+					},
+				},
+				{
+					updateFunc: func(torrent *api.Torrent) {
+						util.UpdateReplicationsCondition(ctx, k8sClient, torrent, api.ReadyConditionType)
+					},
+					checkFunc: func(ctx context.Context, k8sClient client.Client, torrent *api.Torrent) {
+						// The expire time is set once ready, and Torrent will be deleted after that.
+						gomega.Eventually(func() bool {
+							if err := k8sClient.Get(ctx, types.NamespacedName{Name: torrent.Name}, torrent); err != nil {
+								return false
+							}
+							return torrent.Status.ExpireTime != nil
+						}, util.Timeout, util.Interval).Should(gomega.BeTrue())
+						validation.ValidateTorrentNotExist(ctx, k8sClient, torrent.Name, nil)
+					},
+				},
+			},
+		}),
 	)
 })
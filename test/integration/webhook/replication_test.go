@@ -76,5 +76,17 @@ var _ = ginkgo.Describe("Replication default and validation", func() {
 			},
 			failed: true,
 		}),
+		ginkgo.Entry("replication with remote source and peers", &testValidatingCase{
+			replication: func() *api.Replication {
+				return wrapper.MakeReplication("fake-replication").SourceOfURI("remote://node1@/workspace/models/Qwen--Qwen2-7B-Instruct/blobs/chunk").PeerNodeNames("node2", "node3").DestinationOfURI("localhost:///workspace/models/Qwen--Qwen2-7B-Instruct/snapshots/main/file").Obj()
+			},
+			failed: false,
+		}),
+		ginkgo.Entry("peerNodeNames only works with remote source", &testValidatingCase{
+			replication: func() *api.Replication {
+				return wrapper.MakeReplication("fake-replication").SourceOfHub("Huggingface", "Qwen/Qwen2-7B-Instruct", "", "").PeerNodeNames("node2").DestinationOfURI("localhost:///workspace/models/Qwen--Qwen2-7B-Instruct/blobs/chunk").Obj()
+			},
+			failed: true,
+		}),
 	)
 })
@@ -19,6 +19,7 @@ package webhook
 import (
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
 
@@ -79,10 +80,118 @@ var _ = ginkgo.Describe("Torrent default and validation", func() {
 			},
 			createFailed: true,
 		}),
-		ginkgo.Entry("unknown hub not supported", &testValidatingCase{
+		ginkgo.Entry("modelScope hub set", &testValidatingCase{
 			creationFunc: func() *api.Torrent {
 				return wrapper.MakeTorrent("download-qwen").Hub("ModelScope", "Qwen/Qwen2-7B-Instruct", "").Obj()
 			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("unknown hub not supported", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Unknown", "Qwen/Qwen2-7B-Instruct", "").Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("torrent s3 uri set", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").URI("s3://models/Qwen/Qwen2-7B-Instruct").CredentialSecretRef("manta-system", "s3-credentials").Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("credentialSecretRef set without namespace", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").URI("s3://models/Qwen/Qwen2-7B-Instruct").CredentialSecretRef("", "s3-credentials").Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("credentialSecretRef set in other namespace", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").URI("s3://models/Qwen/Qwen2-7B-Instruct").CredentialSecretRef("default", "s3-credentials").Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("torrent oss uri set", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").URI("oss://models.oss-cn-hangzhou.aliyuncs.com/Qwen/Qwen2-7B-Instruct").Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("torrent image uri set", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").URI("img://registry.example.com/models/qwen2:v1").CredentialSecretRef("manta-system", "registry-credentials").Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("unsupported uri", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").URI("gs://models/Qwen/Qwen2-7B-Instruct").Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("hub and uri both set", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").URI("s3://models/Qwen/Qwen2-7B-Instruct").Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("credentialSecretRef set without uri", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").CredentialSecretRef("default", "s3-credentials").Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("tokenSecretRef set", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").TokenSecretRef("manta-system", "hf-token").Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("tokenSecretRef set without namespace", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").TokenSecretRef("", "hf-token").Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("tokenSecretRef set in other namespace", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").TokenSecretRef("default", "hf-token").Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("patterns set", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").AllowPatterns("*.json", "*.safetensors").IgnorePatterns("onnx/").Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("patterns set with filename", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-0.5B-Instruct-GGUF", "qwen2-0_5b-instruct-q5_k_m.gguf").AllowPatterns("*.gguf").Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("invalid pattern", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").IgnorePatterns("model-[9-0].bin").Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("updatePolicy set with hub", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").UpdatePolicy(3600, true).Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("updatePolicy set without hub", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").URI("s3://models/Qwen/Qwen2-7B-Instruct").UpdatePolicy(3600, false).Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("updatePolicy interval too short", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").UpdatePolicy(10, false).Obj()
+			},
 			createFailed: true,
 		}),
 		ginkgo.Entry("preheat from false to true should be succeeded", &testValidatingCase{
@@ -113,9 +222,58 @@ var _ = ginkgo.Describe("Torrent default and validation", func() {
 			},
 			createFailed: false,
 		}),
-		ginkgo.Entry("ttlSecondsAfterReady not nil or 0", &testValidatingCase{
+		ginkgo.Entry("ttlSecondsAfterReady is greater than 0", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Preheat(true).TTL(3600).Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("ttlSecondsAfterReady is negative", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Preheat(true).TTL(-1).Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("nodeAffinity is valid", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").
+					RequiredNodeAffinity("gpu", corev1.NodeSelectorOpIn, "A100", "H100").
+					RequiredNodeAffinity("pool", corev1.NodeSelectorOpNotIn, "spot").
+					PreferredNodeAffinity(10, "gpu", corev1.NodeSelectorOpIn, "H100").
+					Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("nodeAffinity with empty values", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").
+					RequiredNodeAffinity("gpu", corev1.NodeSelectorOpIn).
+					Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("nodeAffinity with non-integer value for Gt", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").
+					PreferredNodeAffinity(10, "gpu-memory", corev1.NodeSelectorOpGt, "large").
+					Obj()
+			},
+			createFailed: true,
+		}),
+		ginkgo.Entry("tolerations are valid", &testValidatingCase{
+			creationFunc: func() *api.Torrent {
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").
+					Toleration("nvidia.com/gpu", corev1.TolerationOpExists, "", corev1.TaintEffectNoSchedule).
+					Toleration("pool", corev1.TolerationOpEqual, "spot", "").
+					Obj()
+			},
+			createFailed: false,
+		}),
+		ginkgo.Entry("toleration with value for Exists", &testValidatingCase{
 			creationFunc: func() *api.Torrent {
-				return wrapper.MakeTorrent("download-qwen").Preheat(true).TTL(1).Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").Obj()
+				return wrapper.MakeTorrent("download-qwen").Hub("Huggingface", "Qwen/Qwen2-7B-Instruct", "").
+					Toleration("pool", corev1.TolerationOpExists, "spot", "").
+					Obj()
 			},
 			createFailed: true,
 		}),
@@ -17,6 +17,7 @@ limitations under the License.
 package wrapper
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	api "github.com/inftyai/manta/api/v1alpha1"
@@ -60,3 +61,28 @@ func (w *NodeTrackerWrpper) Label(k, v string) *NodeTrackerWrpper {
 	w.Labels[k] = v
 	return w
 }
+
+// BandwidthLimits sets the bandwidth limits, empty value means not set.
+func (w *NodeTrackerWrpper) BandwidthLimits(hubDownload, peerDownload, peerUpload string) *NodeTrackerWrpper {
+	w.Spec.BandwidthLimits = &api.BandwidthLimits{}
+	if hubDownload != "" {
+		w.Spec.BandwidthLimits.HubDownload = &hubDownload
+	}
+	if peerDownload != "" {
+		w.Spec.BandwidthLimits.PeerDownload = &peerDownload
+	}
+	if peerUpload != "" {
+		w.Spec.BandwidthLimits.PeerUpload = &peerUpload
+	}
+	return w
+}
+
+// NodeState sets the state mirrored from the Node.
+func (w *NodeTrackerWrpper) NodeState(ready, unschedulable bool, taints ...corev1.Taint) *NodeTrackerWrpper {
+	w.Status.Node = &api.NodeState{
+		Ready:         ready,
+		Unschedulable: unschedulable,
+		Taints:        taints,
+	}
+	return w
+}
@@ -56,6 +56,11 @@ func (w *ReplicationWrapper) SizeBytes(size int64) *ReplicationWrapper {
 	return w
 }
 
+func (w *ReplicationWrapper) ObjectDigest(digest string) *ReplicationWrapper {
+	w.Spec.ObjectDigest = digest
+	return w
+}
+
 func (w *ReplicationWrapper) SourceOfURI(uri string) *ReplicationWrapper {
 	w.Spec.Source = api.Target{
 		URI: ptr.To[string](uri),
@@ -83,6 +88,12 @@ func (w *ReplicationWrapper) SourceOfHub(name, repoID, revision, filename string
 	return w
 }
 
+// PeerNodeNames should be called after the source is set.
+func (w *ReplicationWrapper) PeerNodeNames(names ...string) *ReplicationWrapper {
+	w.Spec.Source.PeerNodeNames = names
+	return w
+}
+
 func (w *ReplicationWrapper) DestinationOfURI(uri string) *ReplicationWrapper {
 	destination := api.Target{
 		URI: &uri,
@@ -19,7 +19,9 @@ package wrapper
 import (
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 
 	api "github.com/inftyai/manta/api/v1alpha1"
 )
@@ -53,6 +55,42 @@ func (w *TorrentWrapper) Hub(name string, repoID string, filename string) *Torre
 	if filename != "" {
 		w.Spec.Hub.Filename = &filename
 	}
+	// Defaulted by the webhook.
+	if w.Spec.Hub.Revision == nil {
+		revision := api.HUGGINGFACE_DEFAULT_REVISION
+		if name == api.MODELSCOPE_MODEL_HUB {
+			revision = api.MODELSCOPE_DEFAULT_REVISION
+		}
+		w.Spec.Hub.Revision = &revision
+	}
+	return w
+}
+
+// AllowPatterns should be called after the hub is set.
+func (w *TorrentWrapper) AllowPatterns(patterns ...string) *TorrentWrapper {
+	w.Spec.Hub.AllowPatterns = patterns
+	return w
+}
+
+// IgnorePatterns should be called after the hub is set.
+func (w *TorrentWrapper) IgnorePatterns(patterns ...string) *TorrentWrapper {
+	w.Spec.Hub.IgnorePatterns = patterns
+	return w
+}
+
+// TokenSecretRef should be called after the hub is set.
+func (w *TorrentWrapper) TokenSecretRef(namespace, name string) *TorrentWrapper {
+	w.Spec.Hub.TokenSecretRef = &corev1.SecretReference{Namespace: namespace, Name: name}
+	return w
+}
+
+func (w *TorrentWrapper) URI(uri string) *TorrentWrapper {
+	w.Spec.URI = ptr.To(api.URIProtocol(uri))
+	return w
+}
+
+func (w *TorrentWrapper) CredentialSecretRef(namespace, name string) *TorrentWrapper {
+	w.Spec.CredentialSecretRef = &corev1.SecretReference{Namespace: namespace, Name: name}
 	return w
 }
 
@@ -74,13 +112,56 @@ func (w *TorrentWrapper) NodeSelector(k, v string) *TorrentWrapper {
 	return w
 }
 
+// RequiredNodeAffinity adds the expression to the first required node selector term.
+func (w *TorrentWrapper) RequiredNodeAffinity(key string, op corev1.NodeSelectorOperator, values ...string) *TorrentWrapper {
+	if w.Spec.NodeAffinity == nil {
+		w.Spec.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	if w.Spec.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		w.Spec.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{}},
+		}
+	}
+	term := &w.Spec.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0]
+	term.MatchExpressions = append(term.MatchExpressions, corev1.NodeSelectorRequirement{Key: key, Operator: op, Values: values})
+	return w
+}
+
+// PreferredNodeAffinity adds a preferred term with the only expression.
+func (w *TorrentWrapper) PreferredNodeAffinity(weight int32, key string, op corev1.NodeSelectorOperator, values ...string) *TorrentWrapper {
+	if w.Spec.NodeAffinity == nil {
+		w.Spec.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	w.Spec.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(w.Spec.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, corev1.PreferredSchedulingTerm{
+		Weight: weight,
+		Preference: corev1.NodeSelectorTerm{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{Key: key, Operator: op, Values: values}},
+		},
+	})
+	return w
+}
+
+func (w *TorrentWrapper) Toleration(key string, op corev1.TolerationOperator, value string, effect corev1.TaintEffect) *TorrentWrapper {
+	w.Spec.Tolerations = append(w.Spec.Tolerations, corev1.Toleration{Key: key, Operator: op, Value: value, Effect: effect})
+	return w
+}
+
+func (w *TorrentWrapper) UpdatePolicy(intervalSeconds int32, autoRollout bool) *TorrentWrapper {
+	w.Spec.UpdatePolicy = &api.UpdatePolicy{
+		IntervalSeconds: &intervalSeconds,
+		AutoRollout:     &autoRollout,
+	}
+	return w
+}
+
 func (w *TorrentWrapper) Preheat(yesOrNo bool) *TorrentWrapper {
 	w.Spec.Preheat = &yesOrNo
 	return w
 }
 
 func (w *TorrentWrapper) TTL(number int32) *TorrentWrapper {
-	ttl := time.Duration(number) * time.Second
+	// The same as the spec decoded from ttlSecondsAfterReady: <number>.
+	ttl := time.Duration(number)
 	w.Spec.TTLSecondsAfterReady = &ttl
 	return w
 }